@@ -5,8 +5,11 @@ import (
     "fmt"
     "io/ioutil"
     "net/http"
+    "net/url"
     "os"
+    "strconv"
     "strings"
+    "time"
 
     "github.com/olekukonko/tablewriter"
     "github.com/sirupsen/logrus"
@@ -16,142 +19,280 @@ import (
 var filterCmd = &cobra.Command{
     Use:   "filter",
     Short: "Filter trails based on criteria",
-    Long:  `Filter trails by various criteria such as restrooms, fishing, bike, horse, fee, recycle bin, grills, bike rack, and dog tube, with pagination support.`,
+    Long: `Filter trails by various criteria such as restrooms, picnic, fishing, type, difficulty, access type, leash rules, bike, horse, fee, recycle bin, grills, bike rack, and dog tube, with pagination support.
+
+Use --near=latitude,longitude to list the trailheads closest to a point first, with their distance in the
+table, and --radius to only show those within that many km.`,
     Run:   filterTrails,
 }
 
+// filterFlags maps each filter flag to the /trails query parameter it sets
+var filterFlags = []struct {
+    flag, param, usage string
+}{
+    {"restrooms", "restrooms", "Filter by restrooms (Yes/No)"},
+    {"picnic", "picnic", "Filter by picnic area (Yes/No)"},
+    {"fishing", "fishing", "Filter by fishing (Yes/No)"},
+    {"type", "type", "Filter by trailhead type"},
+    {"difficulty", "difficulty", "Filter by difficulty"},
+    {"access_type", "access_type", "Filter by access type"},
+    {"th_leash", "th_leash", "Filter by trailhead leash requirement (Yes/No)"},
+    {"bike", "bike_trail", "Filter by bike trail (Yes/No)"},
+    {"horse", "horse_trail", "Filter by horse trail (Possible/Not Recommended/Designated/Not Allowed/Pull Through/NA)"},
+    {"fee", "fee", "Filter by fee (Yes/No)"},
+    {"recycle_bin", "recycle_bin", "Filter by recycle bin (Yes/No)"},
+    {"grills", "grills", "Filter by grills (Yes/No)"},
+    {"bike_rack", "bike_rack", "Filter by bike rack (Yes/No)"},
+    {"dog_tube", "dog_tube", "Filter by number of dog tubes"},
+}
+
 func init() {
-    filterCmd.Flags().String("restrooms", "", "Filter by restrooms (Yes/No)")
-    filterCmd.Flags().String("fishing", "", "Filter by fishing (Yes/No)")
-    filterCmd.Flags().String("bike", "", "Filter by bike trail (Yes/No)")
-    filterCmd.Flags().String("horse", "", "Filter by horse trail (Possible/Not Recommended/Designated/NA)")
-    filterCmd.Flags().String("fee", "", "Filter by fee (Yes/No)")
-    filterCmd.Flags().String("recycle_bin", "", "Filter by recycle bin (Yes/No)")
-    filterCmd.Flags().String("grills", "", "Filter by grills (Yes/No)")
-    filterCmd.Flags().String("bike_rack", "", "Filter by bike rack (Yes/No)")
-    filterCmd.Flags().String("dog_tube", "", "Filter by dog tube (Yes/No)")
+    for _, f := range filterFlags {
+        filterCmd.Flags().String(f.flag, "", f.usage)
+    }
+    filterCmd.Flags().String("sort", "", "Comma-separated columns to sort by, prefixed with - for descending (e.g. name,-park_spaces)")
     filterCmd.Flags().Int("page", 1, "Page number for pagination")
     filterCmd.Flags().Int("limit", 10, "Number of results per page for pagination")
+    filterCmd.Flags().Bool("wide", false, "Show every trailhead field in the table")
+    filterCmd.Flags().String("near", "", "Measure distances from this latitude,longitude and sort by distance unless --sort is given")
+    filterCmd.Flags().Float64("radius", 0, "With --near, only show trailheads within this many km")
 
     rootCmd.AddCommand(filterCmd)
 }
 
 func filterTrails(cmd *cobra.Command, args []string) {
-    // Get filter flags
-    restrooms, _ := cmd.Flags().GetString("restrooms")
-    fishing, _ := cmd.Flags().GetString("fishing")
-    bikeTrail, _ := cmd.Flags().GetString("bike")
-    horseTrail, _ := cmd.Flags().GetString("horse")
-    fee, _ := cmd.Flags().GetString("fee")
-    recycleBin, _ := cmd.Flags().GetString("recycle_bin")
-    grills, _ := cmd.Flags().GetString("grills")
-    bikeRack, _ := cmd.Flags().GetString("bike_rack")
-    dogTube, _ := cmd.Flags().GetString("dog_tube")
     page, _ := cmd.Flags().GetInt("page")
     limit, _ := cmd.Flags().GetInt("limit")
+    wide, _ := cmd.Flags().GetBool("wide")
 
-    // Convert filters to lowercase for consistency
-    filters := []string{}
-    if restrooms != "" {
-        filters = append(filters, "restrooms="+strings.ToLower(restrooms))
+    // Send every filter flag that was given; the server normalizes the values
+    query := url.Values{}
+    for _, f := range filterFlags {
+        if value, _ := cmd.Flags().GetString(f.flag); value != "" {
+            query.Set(f.param, value)
+        }
     }
-    if fishing != "" {
-        filters = append(filters, "fishing="+strings.ToLower(fishing))
+
+    near, _ := cmd.Flags().GetString("near")
+    radius, _ := cmd.Flags().GetFloat64("radius")
+    if radius > 0 && near == "" {
+        logrus.Error("--radius needs --near")
+        return
     }
-    if bikeTrail != "" {
-        filters = append(filters, "bike_trail="+strings.ToLower(bikeTrail))
+    if near != "" {
+        query.Set("near", near)
+        query.Set("sort", "distance")
+        if radius > 0 {
+            query.Set("radius_km", strconv.FormatFloat(radius, 'f', -1, 64))
+        }
     }
-    if horseTrail != "" {
-        filters = append(filters, "horse_trail="+strings.ToLower(horseTrail))
+
+    if sortBy, _ := cmd.Flags().GetString("sort"); sortBy != "" {
+        query.Set("sort", sortBy)
     }
-    if fee != "" {
-        filters = append(filters, "fee="+strings.ToLower(fee))
+
+    // Add pagination parameters
+    query.Set("page", strconv.Itoa(page))
+    query.Set("limit", strconv.Itoa(limit))
+
+    // Fetch and parse the filtered trails
+    var response struct {
+        Page    int             `json:"page"`
+        Limit   int             `json:"limit"`
+        Total   int             `json:"total"`
+        Results []TrailResponse `json:"results"`
     }
-    if recycleBin != "" {
-        filters = append(filters, "recycle_bin="+strings.ToLower(recycleBin))
+    if err := fetchTrails(query, &response); err != nil {
+        logrus.Error(err)
+        return
+    }
+
+    // Display the filtered results in a table format
+    if len(response.Results) == 0 {
+        logrus.Info("No trails found for the given criteria.")
+        return
     }
-    if grills != "" {
-        filters = append(filters, "grills="+strings.ToLower(grills))
+
+    table := tablewriter.NewWriter(os.Stdout)
+    header := []string{"Name", "Address", "Restrooms", "Picnic", "Fishing", "Difficulty", "Access Type", "TH Leash", "Bike Trail", "Horse Trail", "Fee", "Recycle Bin", "Grills", "Bike Rack", "Dog Tube", "Park Spaces"}
+    if wide {
+        header = append(header, "AKA", "Access ID", "Type", "Trash Cans", "ADA Surface", "ADA Toilet", "ADA Fishing", "ADA Camping", "ADA Picnic", "ADA Parking", "ADA Facility", "ADA Facility Name", "Date From", "Date To", "Dog Compost", "Latitude", "Longitude")
     }
-    if bikeRack != "" {
-        filters = append(filters, "bike_rack="+strings.ToLower(bikeRack))
+    if near != "" {
+        header = append([]string{"Distance (km)"}, header...)
     }
-    if dogTube != "" {
-        filters = append(filters, "dog_tube="+strings.ToLower(dogTube))
+    table.SetHeader(header)
+
+    for _, trail := range response.Results {
+        row := []string{
+            trail.Name, trail.Address, formatYesNo(trail.Restrooms), formatYesNo(trail.Picnic), formatYesNo(trail.Fishing), trail.Difficulty, trail.AccessType,
+            formatYesNo(trail.THLeash), formatYesNo(trail.BikeTrail), formatCounted(trail.HorseTrail, trail.HorseTrailCount), formatYesNo(trail.Fee), formatYesNo(trail.RecycleBin),
+            formatYesNo(trail.Grills), formatYesNo(trail.BikeRack), formatCount(trail.DogTube), formatCount(trail.ParkSpaces),
+        }
+        if wide {
+            row = append(row, trail.AKA, trail.AccessID, trail.Type, formatCount(trail.TrashCans), trail.ADASurface, formatYesNo(trail.ADAToilet),
+                formatYesNo(trail.ADAFishing), formatYesNo(trail.ADACamping), formatYesNo(trail.ADAPicnic), formatCounted(trail.ADAParking, trail.ADAParkingCount), formatYesNo(trail.ADAFacility),
+                trail.ADAFacilityName, formatDate(trail.DateFrom), formatDate(trail.DateTo), formatYesNo(trail.DogCompost),
+                formatCoordinate(trail.Latitude), formatCoordinate(trail.Longitude))
+        }
+        if near != "" {
+            row = append([]string{formatDistance(trail.Distance)}, row...)
+        }
+        table.Append(row)
     }
 
-    // Add pagination parameters
-    filters = append(filters, fmt.Sprintf("page=%d", page))
-    filters = append(filters, fmt.Sprintf("limit=%d", limit))
+    logrus.Infof("Showing page %d with %d results per page, %d trails in total:", response.Page, response.Limit, response.Total)
+    table.Render()
+}
 
-    apiURL := "http://localhost:8080/trails?" + strings.Join(filters, "&")
+// serverURL is the address of the local trail-finder server
+const serverURL = "http://localhost:8080"
 
+// fetchTrails requests /trails with the given query parameters and decodes the JSON response into out
+func fetchTrails(query url.Values, out interface{}) error {
+    if err := fetchJSON("/trails", query, out); err != nil {
+        return fmt.Errorf("failed to fetch trails: %w", err)
+    }
+    return nil
+}
+
+// fetchJSON requests a server endpoint with the given query parameters and decodes the JSON response into out
+func fetchJSON(path string, query url.Values, out interface{}) error {
     // Make the API request
-    resp, err := http.Get(apiURL)
+    target := serverURL + path
+    if len(query) > 0 {
+        target += "?" + query.Encode()
+    }
+    resp, err := http.Get(target)
     if err != nil {
-        logrus.Errorf("Error fetching trails: %v", err)
-        return
+        return fmt.Errorf("error requesting %s: %w", path, err)
     }
     defer resp.Body.Close()
 
-    if resp.StatusCode != http.StatusOK {
-        logrus.Errorf("Failed to fetch trails: %s", resp.Status)
-        return
-    }
-
     // Read the response body
     body, err := ioutil.ReadAll(resp.Body)
     if err != nil {
-        logrus.Errorf("Error reading response: %v", err)
-        return
+        return fmt.Errorf("error reading response: %w", err)
+    }
+
+    // The server explains rejected requests in the response body
+    if resp.StatusCode != http.StatusOK {
+        return fmt.Errorf("%s: %s", resp.Status, errorMessage(body))
     }
 
     // Parse the JSON response
-    var response struct {
-        Page    int             `json:"page"`
-        Limit   int             `json:"limit"`
-        Results []TrailResponse `json:"results"`
+    if err := json.Unmarshal(body, out); err != nil {
+        return fmt.Errorf("error parsing JSON response: %w", err)
     }
-    err = json.Unmarshal(body, &response)
-    if err != nil {
-        logrus.Errorf("Error parsing JSON response: %v", err)
-        return
+    return nil
+}
+
+// errorMessage extracts the message of a JSON error envelope, falling back to the raw body
+func errorMessage(body []byte) string {
+    var envelope struct {
+        Error struct {
+            Message string `json:"message"`
+            Details []struct {
+                Field   string `json:"field"`
+                Message string `json:"message"`
+            } `json:"details"`
+        } `json:"error"`
+    }
+    if err := json.Unmarshal(body, &envelope); err != nil || envelope.Error.Message == "" {
+        return strings.TrimSpace(string(body))
     }
 
-    // Display the filtered results in a table format
-    if len(response.Results) == 0 {
-        logrus.Info("No trails found for the given criteria.")
-        return
+    message := envelope.Error.Message
+    for _, detail := range envelope.Error.Details {
+        message += fmt.Sprintf("; %s %s", detail.Field, detail.Message)
     }
+    return message
+}
 
-    table := tablewriter.NewWriter(os.Stdout)
-    table.SetHeader([]string{"Name", "Restrooms", "Picnic", "Fishing", "Difficulty", "Access Type", "TH Leash", "Bike Trail", "Horse Trail", "Fee", "Recycle Bin", "Grills", "Bike Rack", "Dog Tube"})
+// TrailResponse represents the structure of a trail in the response, excluding FID
+type TrailResponse struct {
+    Name            string     `json:"name"`
+    Restrooms       bool       `json:"restrooms"`
+    Picnic          bool       `json:"picnic"`
+    Fishing         bool       `json:"fishing"`
+    Type            string     `json:"type"`
+    Difficulty      string     `json:"difficulty"`
+    AccessType      string     `json:"access_type"`
+    THLeash         bool       `json:"th_leash"`
+    BikeTrail       bool       `json:"bike_trail"`
+    HorseTrail      string     `json:"horse_trail"`
+    HorseTrailCount *int       `json:"horse_trail_count"`
+    Fee             bool       `json:"fee"`
+    RecycleBin      bool       `json:"recycle_bin"`
+    Grills          bool       `json:"grills"`
+    BikeRack        bool       `json:"bike_rack"`
+    DogTube         *int       `json:"dog_tube"`
+    AKA             string     `json:"aka"`
+    Address         string     `json:"address"`
+    AccessID        string     `json:"access_id"`
+    TrashCans       *int       `json:"trash_cans"`
+    ParkSpaces      *int       `json:"park_spaces"`
+    ADASurface      string     `json:"ada_surface"`
+    ADAToilet       bool       `json:"ada_toilet"`
+    ADAFishing      bool       `json:"ada_fishing"`
+    ADACamping      bool       `json:"ada_camping"`
+    ADAPicnic       bool       `json:"ada_picnic"`
+    ADAParking      string     `json:"ada_parking"`
+    ADAParkingCount *int       `json:"ada_parking_count"`
+    ADAFacility     bool       `json:"ada_facility"`
+    ADAFacilityName string     `json:"ada_facility_name"`
+    DateFrom        *time.Time `json:"date_from"`
+    DateTo          *time.Time `json:"date_to"`
+    DogCompost      bool       `json:"dog_compost"`
+    Latitude        *float64   `json:"latitude"`
+    Longitude       *float64   `json:"longitude"`
+    Distance        *float64   `json:"distance_km"`
+}
 
-    for _, trail := range response.Results {
-        table.Append([]string{
-            trail.Name, trail.Restrooms, trail.Picnic, trail.Fishing, trail.Difficulty, trail.AccessType,
-            trail.THLeash, trail.BikeTrail, trail.HorseTrail, trail.Fee, trail.RecycleBin, trail.Grills, trail.BikeRack, trail.DogTube,
-        })
+// formatYesNo renders a boolean amenity for the table
+func formatYesNo(b bool) string {
+    if b {
+        return "Yes"
     }
+    return "No"
+}
 
-    logrus.Infof("Showing page %d with %d results per page:", response.Page, response.Limit)
-    table.Render()
+// formatCount renders an optional count for the table, leaving unknown values blank
+func formatCount(n *int) string {
+    if n == nil {
+        return ""
+    }
+    return strconv.Itoa(*n)
 }
 
-// TrailResponse represents the structure of a trail in the response, excluding FID
-type TrailResponse struct {
-    Name        string `json:"name"`
-    Restrooms   string `json:"restrooms"`
-    Picnic      string `json:"picnic"`
-    Fishing     string `json:"fishing"`
-    Difficulty  string `json:"difficulty"`
-    AccessType  string `json:"access_type"`
-    THLeash     string `json:"th_leash"`
-    BikeTrail   string `json:"bike_trail"`
-    HorseTrail  string `json:"horse_trail"`
-    Fee         string `json:"fee"`
-    RecycleBin  string `json:"recycle_bin"`
-    Grills      string `json:"grills"`
-    BikeRack    string `json:"bike_rack"`
-    DogTube     string `json:"dog_tube"`
+// formatCounted renders a counted amenity such as a designated horse trail, with its count when the source gave one
+func formatCounted(value string, n *int) string {
+    if n == nil {
+        return value
+    }
+    return value + " x " + strconv.Itoa(*n)
+}
+
+// formatCoordinate renders an optional latitude or longitude for the table, leaving unknown values blank
+func formatCoordinate(f *float64) string {
+    if f == nil {
+        return ""
+    }
+    return strconv.FormatFloat(*f, 'f', 5, 64)
+}
+
+// formatDistance renders a distance in km for the table, leaving trailheads without coordinates blank
+func formatDistance(km *float64) string {
+    if km == nil {
+        return ""
+    }
+    return strconv.FormatFloat(*km, 'f', 1, 64)
+}
+
+// formatDate renders an optional date for the table, leaving unknown values blank
+func formatDate(t *time.Time) string {
+    if t == nil {
+        return ""
+    }
+    return t.Format("2006-01-02")
 }
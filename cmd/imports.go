@@ -0,0 +1,144 @@
+package cmd
+
+import (
+    "fmt"
+    "net/url"
+    "os"
+    "strconv"
+    "time"
+    "trail-finder/models"
+
+    "github.com/olekukonko/tablewriter"
+    "github.com/sirupsen/logrus"
+    "github.com/spf13/cobra"
+)
+
+var importsCmd = &cobra.Command{
+    Use:   "imports",
+    Short: "Inspect CSV import jobs",
+    Long:  `Inspect the history of CSV imports queued through the server's /load endpoint.`,
+}
+
+var importsListCmd = &cobra.Command{
+    Use:   "list",
+    Short: "List recent import jobs",
+    Long:  `List the most recent import jobs, newest first, with their state and row counts.`,
+    Args:  cobra.NoArgs,
+    RunE:  listImports,
+}
+
+var importsShowCmd = &cobra.Command{
+    Use:   "show <id>",
+    Short: "Show an import job",
+    Long:  `Show the state, row counts, timing and rejected rows of an import job.`,
+    Args:  cobra.ExactArgs(1),
+    RunE:  showImport,
+}
+
+func init() {
+    importsListCmd.Flags().Int("limit", 20, "Number of import jobs to list")
+    importsShowCmd.Flags().Int("rejected-offset", 0, "Number of rejected row entries to skip")
+    importsShowCmd.Flags().Int("rejected-limit", 100, "Number of rejected row entries to show")
+
+    importsCmd.AddCommand(importsListCmd, importsShowCmd)
+    rootCmd.AddCommand(importsCmd)
+}
+
+func listImports(cmd *cobra.Command, args []string) error {
+    // Arguments are valid at this point, so failures are reported without the usage text
+    cmd.SilenceUsage = true
+    limit, _ := cmd.Flags().GetInt("limit")
+
+    var response struct {
+        Results []models.ImportJob `json:"results"`
+    }
+    if err := fetchJSON("/imports", url.Values{"limit": {strconv.Itoa(limit)}}, &response); err != nil {
+        return fmt.Errorf("failed to list imports: %w", err)
+    }
+    if len(response.Results) == 0 {
+        logrus.Info("No imports found.")
+        return nil
+    }
+
+    table := tablewriter.NewWriter(os.Stdout)
+    table.SetHeader([]string{"ID", "State", "Source", "Mode", "Read", "Imported", "Rejected", "Created", "Duration"})
+    for _, job := range response.Results {
+        table.Append([]string{
+            job.ID, string(job.State), job.Source, string(job.Mode), strconv.Itoa(job.RowsRead), strconv.Itoa(job.RowsImported),
+            strconv.Itoa(job.RowsRejected), formatTime(&job.CreatedAt), formatDuration(job.StartedAt, job.FinishedAt),
+        })
+    }
+    table.Render()
+    return nil
+}
+
+func showImport(cmd *cobra.Command, args []string) error {
+    cmd.SilenceUsage = true
+    offset, _ := cmd.Flags().GetInt("rejected-offset")
+    limit, _ := cmd.Flags().GetInt("rejected-limit")
+
+    var job models.ImportJobPage
+    params := url.Values{"rejected_offset": {strconv.Itoa(offset)}, "rejected_limit": {strconv.Itoa(limit)}}
+    if err := fetchJSON("/imports/"+url.PathEscape(args[0]), params, &job); err != nil {
+        return fmt.Errorf("failed to show import %s: %w", args[0], err)
+    }
+
+    details := tablewriter.NewWriter(os.Stdout)
+    details.AppendBulk([][]string{
+        {"ID", job.ID},
+        {"State", string(job.State)},
+        {"Source", job.Source},
+        {"Mode", string(job.Mode)},
+        {"Version", formatVersionID(job.Version)},
+        {"Rows read", strconv.Itoa(job.RowsRead)},
+        {"Rows imported", strconv.Itoa(job.RowsImported)},
+        {"Rows rejected", strconv.Itoa(job.RowsRejected)},
+        {"Created", formatTime(&job.CreatedAt)},
+        {"Started", formatTime(job.StartedAt)},
+        {"Finished", formatTime(job.FinishedAt)},
+        {"Duration", formatDuration(job.StartedAt, job.FinishedAt)},
+    })
+    if job.Mode == models.ImportUpsert {
+        details.AppendBulk([][]string{
+            {"Trails inserted", strconv.Itoa(job.Inserted)},
+            {"Trails updated", strconv.Itoa(job.Updated)},
+            {"Trails unchanged", strconv.Itoa(job.Unchanged)},
+            {"Trails deleted", strconv.Itoa(job.Deleted)},
+        })
+    }
+    if job.Error != "" {
+        details.Append([]string{"Error", job.Error})
+    }
+    details.Render()
+
+    if len(job.Rejected) == 0 {
+        return nil
+    }
+    if job.Truncated {
+        logrus.Infof("Showing problems %d to %d of %d (use --rejected-offset for the others):",
+            job.RejectedOffset+1, job.RejectedOffset+len(job.Rejected), job.RejectedTotal)
+    }
+    rejected := tablewriter.NewWriter(os.Stdout)
+    rejected.SetHeader([]string{"Line", "Column", "Value", "Reason"})
+    for _, row := range job.Rejected {
+        rejected.Append([]string{strconv.Itoa(row.Line), row.Column, row.Value, row.Reason})
+    }
+    rejected.Render()
+    return nil
+}
+
+// formatTime renders an optional timestamp in local time, leaving unset times blank
+func formatTime(t *time.Time) string {
+    if t == nil || t.IsZero() {
+        return ""
+    }
+    return t.Local().Format("2006-01-02 15:04:05")
+}
+
+// formatDuration renders how long a job ran, leaving jobs that have not finished blank
+func formatDuration(started, finished *time.Time) string {
+    if started == nil || finished == nil {
+        return ""
+    }
+    return finished.Sub(*started).Round(time.Millisecond).String()
+}
@@ -1,27 +1,61 @@
 package cmd
 
 import (
+    "encoding/json"
+    "fmt"
     "os"
+    "strconv"
+    "time"
     "trail-finder/handlers"
-	"trail-finder/db"
+    "trail-finder/models"
+    "trail-finder/store"
     "github.com/joho/godotenv"
+    "github.com/olekukonko/tablewriter"
     "github.com/sirupsen/logrus"
     "github.com/spf13/cobra"
 )
 
 var loadCmd = &cobra.Command{
     Use:   "load",
-    Short: "Load CSV data into the database",
-    Long:  `Load the specified CSV file into the database, replacing existing data.`,
-    Run:   loadCSV,
+    Short: "Load CSV or GeoJSON data into the database",
+    Long: `Load the specified CSV file into the database, replacing existing data.
+
+A GeoJSON FeatureCollection is detected from the file's content and loaded the same way: the
+properties of each feature go through the column mapping like CSV headers, and its Point geometry
+sets the trail's latitude and longitude.
+
+Rows that cannot be imported are skipped and logged. Use --report to write every rejected row,
+with its column, raw value and reason, to a JSON file, and --strict to abort the load, leaving the
+stored trails untouched and exiting with a non-zero status, if any row is rejected.
+
+With --mode=upsert the CSV is merged into the stored trails instead: new FIDs are inserted, changed
+trails updated and the others kept, or soft-deleted with --soft-delete.
+
+Use --dry-run to preview the load: nothing is stored, and the trails that would be added, removed
+or changed (with each changed field) are printed as a table, or as JSON with --output=json.`,
+    RunE:  loadCSV,
 }
 
+// openStore connects to the trail store named by the connection string and returns a function that closes it.
+// Tests replace it to load into an in-memory store.
+var openStore = store.Open
+
 func init() {
-    loadCmd.Flags().StringP("file", "f", "", "Path to the CSV file")
+    loadCmd.Flags().StringP("file", "f", "", "Path to the CSV or GeoJSON file")
+    loadCmd.Flags().StringP("mapping", "m", "", "Path to a JSON column mapping file (defaults to the Boulder County layout)")
+    loadCmd.Flags().String("report", "", "Write the validation report of the CSV rows to this JSON file")
+    loadCmd.Flags().Bool("strict", false, "Abort the load if any row is rejected")
+    loadCmd.Flags().String("mode", "replace", "How to apply the CSV: replace the stored trails or upsert into them")
+    loadCmd.Flags().Bool("soft-delete", false, "With --mode=upsert, soft-delete stored trails missing from the CSV")
+    loadCmd.Flags().Bool("dry-run", false, "Print what the load would change instead of storing it")
+    loadCmd.Flags().StringP("output", "o", "table", "Dry run output format: table or json")
     rootCmd.AddCommand(loadCmd)
 }
 
-func loadCSV(cmd *cobra.Command, args []string) {
+func loadCSV(cmd *cobra.Command, args []string) error {
+    // Arguments are valid at this point, so failures are reported without the usage text
+    cmd.SilenceUsage = true
+
     // Initialize the logger
     handlers.InitLogger()
 
@@ -30,38 +64,143 @@ func loadCSV(cmd *cobra.Command, args []string) {
         logrus.Warn("Error loading .env file")
     }
 
-    // Get the database connection string from environment variables
-    dbConnString := os.Getenv("DB_CONN_STRING")
-    if dbConnString == "" {
-        logrus.Error("DB_CONN_STRING environment variable is not set")
-        return
+    dryRun, _ := cmd.Flags().GetBool("dry-run")
+    output, _ := cmd.Flags().GetString("output")
+    if output != "table" && output != "json" {
+        return fmt.Errorf("invalid output format %q: must be table or json", output)
     }
-
-    // Initialize the database connection
-    if err := db.InitDB(dbConnString); err != nil {
-        logrus.Fatalf("Failed to initialize database: %v", err)
+    modeFlag, _ := cmd.Flags().GetString("mode")
+    mode, err := models.ParseImportMode(modeFlag)
+    if err != nil {
+        return err
+    }
+    softDelete, _ := cmd.Flags().GetBool("soft-delete")
+    if softDelete && mode != models.ImportUpsert {
+        return fmt.Errorf("--soft-delete requires --mode=upsert")
     }
-    defer db.CloseDB() // Ensure the database connection is closed after the operation
 
     // Get the file flag
     file, _ := cmd.Flags().GetString("file")
     if file == "" {
-        logrus.Error("CSV file path must be provided using the --file flag")
-        return
+        return fmt.Errorf("file path must be provided using the --file flag")
     }
 
     // Check if the file exists
     if _, err := os.Stat(file); os.IsNotExist(err) {
-        logrus.Errorf("Specified file does not exist: %s", file)
-        return
+        return fmt.Errorf("specified file does not exist: %s", file)
+    }
+
+    // Use the default column mapping unless an alternate one was supplied
+    mapping := models.DefaultMapping()
+    if mappingFile, _ := cmd.Flags().GetString("mapping"); mappingFile != "" {
+        mapping, err = models.LoadMapping(mappingFile)
+        if err != nil {
+            return fmt.Errorf("failed to load column mapping: %w", err)
+        }
     }
 
-    // Load the CSV file into the database
-    err := handlers.LoadTrails(file)
+    // Get the database connection string from the --db flag or environment variables
+    connString, err := dbConnString(cmd)
     if err != nil {
-        logrus.Errorf("Error loading CSV file: %v", err)
-        return
+        return err
     }
 
-    logrus.Infof("CSV data loaded successfully from: %s", file)
+    // Initialize the database connection
+    trailStore, closeStore, err := openStore(connString)
+    if err != nil {
+        return fmt.Errorf("failed to initialize database: %w", err)
+    }
+    defer closeStore() // Ensure the database connection is closed after the operation
+
+    // Load the file into the database, writing the report even when the load fails
+    strict, _ := cmd.Flags().GetBool("strict")
+    opts := handlers.LoadOptions{Mode: mode, SoftDelete: softDelete, Strict: strict, DryRun: dryRun}
+    report, loadErr := handlers.LoadTrailsFile(trailStore, file, mapping, opts)
+    var reportErr error
+    if reportFile, _ := cmd.Flags().GetString("report"); reportFile != "" {
+        if reportErr = writeReport(reportFile, report); reportErr != nil {
+            reportErr = fmt.Errorf("failed to write report: %w", reportErr)
+        } else {
+            logrus.Infof("Validation report written to: %s", reportFile)
+        }
+    }
+    if loadErr != nil {
+        if reportErr != nil {
+            logrus.Error(reportErr)
+        }
+        return fmt.Errorf("failed to load %s: %w", file, loadErr)
+    }
+    if reportErr != nil {
+        return reportErr
+    }
+
+    if report.RowsRejected > 0 {
+        logrus.Warnf("Rejected %d of %d rows from: %s", report.RowsRejected, report.RowsRead, file)
+    }
+    if report.Diff != nil {
+        if err := printDiff(*report.Diff, output); err != nil {
+            return fmt.Errorf("failed to print diff: %w", err)
+        }
+        return nil
+    }
+    if mode == models.ImportUpsert {
+        logrus.Infof("Trails inserted: %d, updated: %d, unchanged: %d, deleted: %d",
+            report.Inserted, report.Updated, report.Unchanged, report.Deleted)
+    }
+    logrus.Infof("Trails loaded successfully from: %s", file)
+    return nil
+}
+
+// printDiff prints the changes a dry run found, as a table with one row per added or removed trail
+// and per changed field, or as JSON
+func printDiff(diff models.DatasetDiff, output string) error {
+    if output == "json" {
+        data, err := json.MarshalIndent(diff, "", "  ")
+        if err != nil {
+            return err
+        }
+        fmt.Println(string(data))
+        return nil
+    }
+
+    if !diff.Empty() {
+        table := tablewriter.NewWriter(os.Stdout)
+        table.SetHeader([]string{"Change", "FID", "Name", "Field", "Old", "New"})
+        for _, t := range diff.Added {
+            table.Append([]string{"added", strconv.Itoa(t.FID), t.Name, "", "", ""})
+        }
+        for _, t := range diff.Removed {
+            table.Append([]string{"removed", strconv.Itoa(t.FID), t.Name, "", "", ""})
+        }
+        for _, t := range diff.Changed {
+            for _, f := range t.Fields {
+                table.Append([]string{"changed", strconv.Itoa(t.FID), t.Name, f.Field, formatValue(f.Old), formatValue(f.New)})
+            }
+        }
+        table.Render()
+    }
+    logrus.Infof("Dry run: %d added, %d removed, %d changed, %d unchanged. Nothing was stored.",
+        len(diff.Added), len(diff.Removed), len(diff.Changed), diff.Unchanged)
+    return nil
+}
+
+// formatValue renders a trail field value for a table cell, leaving unset values blank
+func formatValue(value interface{}) string {
+    switch v := value.(type) {
+    case nil:
+        return ""
+    case time.Time:
+        return v.Format("2006-01-02")
+    default:
+        return fmt.Sprint(v)
+    }
+}
+
+// writeReport saves the validation report of a load as indented JSON
+func writeReport(path string, report models.ImportReport) error {
+    data, err := json.MarshalIndent(report, "", "  ")
+    if err != nil {
+        return err
+    }
+    return os.WriteFile(path, append(data, '\n'), 0o644)
 }
@@ -1,74 +1,345 @@
 package cmd
 
 import (
-    "database/sql"
+    "errors"
+    "fmt"
+    "io/fs"
     "os"
+    "path/filepath"
+    "regexp"
+    "sort"
+    "strconv"
+    "strings"
+    "trail-finder/db"
+
     "github.com/golang-migrate/migrate/v4"
-    "github.com/golang-migrate/migrate/v4/database/postgres"
-    _ "github.com/golang-migrate/migrate/v4/source/file"
     "github.com/joho/godotenv"
+    "github.com/olekukonko/tablewriter"
     "github.com/sirupsen/logrus"
     "github.com/spf13/cobra"
-
-    _ "github.com/jackc/pgx/v4/stdlib"
 )
 
 var migrateCmd = &cobra.Command{
     Use:   "migrate",
     Short: "Run database migrations",
-    Long:  "Run database migrations to set up or update the database schema",
-    Run:   runMigrations,
+    Long:  "Run database migrations to set up or update the database schema. Without a subcommand, all pending migrations are applied.",
+    Args:  cobra.NoArgs,
+    PersistentPreRun: func(cmd *cobra.Command, args []string) {
+        // Arguments are valid at this point, so failures are reported without the usage text
+        cmd.SilenceUsage = true
+    },
+    RunE: func(cmd *cobra.Command, args []string) error {
+        return migrateUp(cmd, nil)
+    },
+}
+
+var migrateUpCmd = &cobra.Command{
+    Use:   "up [N]",
+    Short: "Apply all or N pending migrations",
+    Args:  cobra.MaximumNArgs(1),
+    RunE:  migrateUp,
+}
+
+var migrateDownCmd = &cobra.Command{
+    Use:   "down [N]",
+    Short: "Roll back N migrations (default 1), or all of them with --all",
+    Args:  cobra.MaximumNArgs(1),
+    RunE:  migrateDown,
+}
+
+var migrateGotoCmd = &cobra.Command{
+    Use:   "goto V",
+    Short: "Migrate up or down to version V",
+    Args:  cobra.ExactArgs(1),
+    RunE:  migrateGoto,
+}
+
+var migrateStatusCmd = &cobra.Command{
+    Use:   "status",
+    Short: "Show the current schema version and the available migrations",
+    Args:  cobra.NoArgs,
+    RunE:  migrateStatus,
+}
+
+var migrateForceCmd = &cobra.Command{
+    Use:   "force V",
+    Short: "Set the schema version to V without running migrations, clearing the dirty flag",
+    Args:  cobra.ExactArgs(1),
+    RunE:  migrateForce,
 }
 
+var migrateCreateCmd = &cobra.Command{
+    Use:   "create NAME",
+    Short: "Create a new pair of up/down migration files",
+    Args:  cobra.ExactArgs(1),
+    RunE:  migrateCreate,
+}
+
+// migrationFilePattern matches golang-migrate file names such as 0001_create_trails_table.up.sql
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
 func init() {
+    migrateDownCmd.Flags().Bool("all", false, "Roll back every applied migration")
+    migrateCreateCmd.Flags().String("dir", "migrations", "Directory to write the migration files to")
+
+    migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateGotoCmd, migrateStatusCmd, migrateForceCmd, migrateCreateCmd)
     rootCmd.AddCommand(migrateCmd)
 }
 
-func runMigrations(cmd *cobra.Command, args []string) {
+// newMigrate creates a migrate instance for the embedded migrations using the --db flag or DB_CONN_STRING.
+// open is db.NewMigrateUp for applying migrations, and db.NewMigrate for every command that must not adopt
+// a legacy schema.
+func newMigrate(cmd *cobra.Command, open func(string) (*migrate.Migrate, error)) (*migrate.Migrate, error) {
     // Load environment variables from .env file
     if err := godotenv.Load(); err != nil {
         logrus.Warn("Warning: .env file not found or failed to load.")
     }
 
-    // Load the database connection string from environment variables
-    dbConnString := os.Getenv("DB_CONN_STRING")
-    if dbConnString == "" {
-        logrus.Error("DB_CONN_STRING environment variable is not set")
-        return
+    connString, err := dbConnString(cmd)
+    if err != nil {
+        return nil, err
+    }
+
+    return open(connString)
+}
+
+// parseCount parses an optional positive step count argument
+func parseCount(args []string) (int, error) {
+    if len(args) == 0 {
+        return 0, nil
     }
+    n, err := strconv.Atoi(args[0])
+    if err != nil || n < 1 {
+        return 0, fmt.Errorf("N must be a positive integer, got %q", args[0])
+    }
+    return n, nil
+}
 
-    // Create a new *sql.DB connection using the pgx driver
-    db, err := sql.Open("pgx", dbConnString)
+// parseVersion parses a migration version argument
+func parseVersion(arg string) (uint, error) {
+    v, err := strconv.ParseUint(arg, 10, 32)
     if err != nil {
-        logrus.Fatalf("Failed to create database connection: %v", err)
+        return 0, fmt.Errorf("V must be a migration version number, got %q", arg)
     }
-    defer db.Close()
+    return uint(v), nil
+}
 
-    // Verify the connection is valid
-    if err := db.Ping(); err != nil {
-        logrus.Fatalf("Failed to connect to the database: %v", err)
+// ignoreNoChange treats "no change" from the migrate library as success
+func ignoreNoChange(err error) error {
+    if errors.Is(err, migrate.ErrNoChange) {
+        logrus.Info("No migrations to apply.")
+        return nil
     }
+    return err
+}
 
-    // Use the created *sql.DB connection for the migrate library
-    driver, err := postgres.WithInstance(db, &postgres.Config{})
+func migrateUp(cmd *cobra.Command, args []string) error {
+    n, err := parseCount(args)
     if err != nil {
-        logrus.Fatalf("Failed to create database driver: %v", err)
+        return err
     }
 
-    // Initialize the migration
-    m, err := migrate.NewWithDatabaseInstance(
-        "file://migrations", // Path to the migrations folder
-        "postgres",          // Database name
-        driver,
-    )
+    m, err := newMigrate(cmd, db.NewMigrateUp)
     if err != nil {
-        logrus.Fatalf("Failed to initialize migrations: %v", err)
+        return err
     }
+    defer m.Close()
 
-    // Run the migrations
-    if err := m.Up(); err != nil && err != migrate.ErrNoChange {
-        logrus.Fatalf("Failed to apply migrations: %v", err)
+    if n == 0 {
+        err = m.Up()
+    } else {
+        err = m.Steps(n)
+    }
+    if err := ignoreNoChange(err); err != nil {
+        return fmt.Errorf("failed to apply migrations: %w", err)
     }
 
     logrus.Info("Migrations applied successfully!")
+    return nil
+}
+
+func migrateDown(cmd *cobra.Command, args []string) error {
+    n, err := parseCount(args)
+    if err != nil {
+        return err
+    }
+    all, _ := cmd.Flags().GetBool("all")
+    if all && n > 0 {
+        return fmt.Errorf("N and --all cannot be used together")
+    }
+    if n == 0 {
+        n = 1
+    }
+
+    m, err := newMigrate(cmd, db.NewMigrate)
+    if err != nil {
+        return err
+    }
+    defer m.Close()
+
+    if all {
+        err = m.Down()
+    } else {
+        err = m.Steps(-n)
+    }
+    if err := ignoreNoChange(err); err != nil {
+        return fmt.Errorf("failed to roll back migrations: %w", err)
+    }
+
+    logrus.Info("Migrations rolled back successfully!")
+    return nil
+}
+
+func migrateGoto(cmd *cobra.Command, args []string) error {
+    version, err := parseVersion(args[0])
+    if err != nil {
+        return err
+    }
+
+    m, err := newMigrate(cmd, db.NewMigrate)
+    if err != nil {
+        return err
+    }
+    defer m.Close()
+
+    if err := ignoreNoChange(m.Migrate(version)); err != nil {
+        return fmt.Errorf("failed to migrate to version %d: %w", version, err)
+    }
+
+    logrus.Infof("Database migrated to version %d", version)
+    return nil
+}
+
+func migrateForce(cmd *cobra.Command, args []string) error {
+    version, err := parseVersion(args[0])
+    if err != nil {
+        return err
+    }
+
+    m, err := newMigrate(cmd, db.NewMigrate)
+    if err != nil {
+        return err
+    }
+    defer m.Close()
+
+    if err := m.Force(int(version)); err != nil {
+        return fmt.Errorf("failed to force version %d: %w", version, err)
+    }
+
+    logrus.Infof("Database version forced to %d", version)
+    return nil
+}
+
+func migrateStatus(cmd *cobra.Command, args []string) error {
+    m, err := newMigrate(cmd, db.NewMigrate)
+    if err != nil {
+        return err
+    }
+    defer m.Close()
+
+    current, dirty, versionErr := m.Version()
+    if versionErr != nil && !errors.Is(versionErr, migrate.ErrNilVersion) {
+        return fmt.Errorf("failed to read schema version: %w", versionErr)
+    }
+
+    connString, _ := dbConnString(cmd)
+    available, err := embeddedMigrations(connString)
+    if err != nil {
+        return err
+    }
+
+    table := tablewriter.NewWriter(os.Stdout)
+    table.SetHeader([]string{"Version", "Name", "Status"})
+    for _, migration := range available {
+        status := "pending"
+        if migration.version <= current {
+            status = "applied"
+        }
+        if migration.version == current && dirty {
+            status = "dirty"
+        }
+        table.Append([]string{strconv.FormatUint(uint64(migration.version), 10), migration.name, status})
+    }
+
+    if errors.Is(versionErr, migrate.ErrNilVersion) {
+        fmt.Println("Current version: none")
+    } else {
+        fmt.Printf("Current version: %d (dirty: %t)\n", current, dirty)
+    }
+    table.Render()
+    return nil
+}
+
+func migrateCreate(cmd *cobra.Command, args []string) error {
+    dir, _ := cmd.Flags().GetString("dir")
+    name := strings.Trim(regexp.MustCompile(`[^a-z0-9]+`).ReplaceAllString(strings.ToLower(args[0]), "_"), "_")
+    if name == "" {
+        return fmt.Errorf("migration name %q must contain letters or digits", args[0])
+    }
+
+    existing, err := listMigrations(os.DirFS(dir))
+    if err != nil {
+        return fmt.Errorf("failed to read migrations directory: %w", err)
+    }
+    var next uint = 1
+    if len(existing) > 0 {
+        next = existing[len(existing)-1].version + 1
+    }
+
+    base := fmt.Sprintf("%04d_%s", next, name)
+    for _, direction := range []string{"up", "down"} {
+        path := filepath.Join(dir, base+"."+direction+".sql")
+        file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+        if err != nil {
+            return fmt.Errorf("failed to create migration file: %w", err)
+        }
+        file.Close()
+        fmt.Println(path)
+    }
+    return nil
+}
+
+// migrationInfo describes one migration version found in a migrations directory
+type migrationInfo struct {
+    version uint
+    name    string
+}
+
+// embeddedMigrations lists the migrations compiled into the binary for the connection string's backend
+func embeddedMigrations(connString string) ([]migrationInfo, error) {
+    driver, err := db.DriverFor(connString)
+    if err != nil {
+        return nil, err
+    }
+    files, err := db.MigrationFiles(driver)
+    if err != nil {
+        return nil, err
+    }
+    return listMigrations(files)
+}
+
+// listMigrations lists the migration versions in a directory, sorted by version
+func listMigrations(fsys fs.FS) ([]migrationInfo, error) {
+    entries, err := fs.ReadDir(fsys, ".")
+    if err != nil {
+        return nil, err
+    }
+
+    seen := map[uint]bool{}
+    var result []migrationInfo
+    for _, entry := range entries {
+        match := migrationFilePattern.FindStringSubmatch(entry.Name())
+        if match == nil {
+            continue
+        }
+        version, err := strconv.ParseUint(match[1], 10, 32)
+        if err != nil || seen[uint(version)] {
+            continue
+        }
+        seen[uint(version)] = true
+        result = append(result, migrationInfo{version: uint(version), name: match[2]})
+    }
+
+    sort.Slice(result, func(i, j int) bool { return result[i].version < result[j].version })
+    return result, nil
 }
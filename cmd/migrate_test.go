@@ -0,0 +1,41 @@
+package cmd
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestEmbeddedMigrations(t *testing.T) {
+    available, err := embeddedMigrations("postgresql://localhost/trails")
+    assert.Nil(t, err, "Expected embedded migrations to be readable")
+    assert.NotEmpty(t, available, "Expected migrations to be embedded in the binary")
+
+    // Versions are sorted and contiguous from 1
+    for i, migration := range available {
+        assert.Equal(t, uint(i+1), migration.version)
+    }
+
+    // SQLite migrations share the PostgreSQL version numbers and never run ahead of them
+    sqliteAvailable, err := embeddedMigrations("sqlite://trails.db")
+    assert.Nil(t, err, "Expected embedded SQLite migrations to be readable")
+    if assert.NotEmpty(t, sqliteAvailable, "Expected SQLite migrations to be embedded in the binary") {
+        assert.LessOrEqual(t, sqliteAvailable[len(sqliteAvailable)-1].version, available[len(available)-1].version)
+    }
+}
+
+func TestMigrateCreate(t *testing.T) {
+    dir := t.TempDir()
+    assert.Nil(t, os.WriteFile(filepath.Join(dir, "0007_existing.up.sql"), nil, 0o644))
+
+    err := migrateCreateCmd.Flags().Set("dir", dir)
+    assert.Nil(t, err)
+
+    err = migrateCreate(migrateCreateCmd, []string{"Add Trail Notes"})
+    assert.Nil(t, err, "Expected migration files to be created")
+
+    assert.FileExists(t, filepath.Join(dir, "0008_add_trail_notes.up.sql"))
+    assert.FileExists(t, filepath.Join(dir, "0008_add_trail_notes.down.sql"))
+}
@@ -1,6 +1,9 @@
 package cmd
 
 import (
+    "fmt"
+    "os"
+
     "github.com/spf13/cobra"
     "github.com/sirupsen/logrus"
 )
@@ -9,11 +12,30 @@ var rootCmd = &cobra.Command{
     Use:   "trail-cli",
     Short: "Trail Finder CLI",
     Long:  "CLI application for managing trail data",
+    // Errors are logged by Execute, which exits with a non-zero status
+    SilenceErrors: true,
+}
+
+func init() {
+    rootCmd.PersistentFlags().String("db", "", "Database connection string: postgres://... or sqlite://path/to/trails.db (defaults to DB_CONN_STRING)")
+}
+
+// dbConnString returns the --db flag, falling back to the DB_CONN_STRING environment variable
+func dbConnString(cmd *cobra.Command) (string, error) {
+    connString, _ := cmd.Flags().GetString("db")
+    if connString == "" {
+        connString = os.Getenv("DB_CONN_STRING")
+    }
+    if connString == "" {
+        return "", fmt.Errorf("DB_CONN_STRING environment variable is not set")
+    }
+    return connString, nil
 }
 
-// Execute runs the root command
+// Execute runs the root command and exits with a non-zero status if it fails
 func Execute() {
     if err := rootCmd.Execute(); err != nil {
-        logrus.Fatalf("Error executing command: %v", err)
+        logrus.Errorf("Error executing command: %v", err)
+        os.Exit(1)
     }
 }
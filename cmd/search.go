@@ -0,0 +1,82 @@
+package cmd
+
+import (
+    "fmt"
+    "net/url"
+    "os"
+    "strconv"
+    "strings"
+
+    "github.com/olekukonko/tablewriter"
+    "github.com/sirupsen/logrus"
+    "github.com/spf13/cobra"
+)
+
+var searchCmd = &cobra.Command{
+    Use:   "search <text>",
+    Short: "Search trailheads by name, AKA or address",
+    Long:  `Search trailhead names, AKAs and addresses, tolerating partial words and misspellings. Results are ranked best match first, with the matching words shown in [brackets].`,
+    Args:  cobra.MinimumNArgs(1),
+    Run:   searchTrails,
+}
+
+func init() {
+    searchCmd.Flags().Int("page", 1, "Page number for pagination")
+    searchCmd.Flags().Int("limit", 10, "Number of results per page for pagination")
+
+    rootCmd.AddCommand(searchCmd)
+}
+
+// SearchResponse is a trail in a search response, with its rank and highlighted fields
+type SearchResponse struct {
+    TrailResponse
+    Rank       float64           `json:"rank"`
+    Highlights map[string]string `json:"highlights"`
+}
+
+// highlightMarkers converts the server's highlight tags into brackets for the terminal
+var highlightMarkers = strings.NewReplacer("<mark>", "[", "</mark>", "]")
+
+func searchTrails(cmd *cobra.Command, args []string) {
+    page, _ := cmd.Flags().GetInt("page")
+    limit, _ := cmd.Flags().GetInt("limit")
+
+    query := url.Values{}
+    query.Set("q", strings.Join(args, " "))
+    query.Set("page", strconv.Itoa(page))
+    query.Set("limit", strconv.Itoa(limit))
+
+    var response struct {
+        Page    int              `json:"page"`
+        Limit   int              `json:"limit"`
+        Results []SearchResponse `json:"results"`
+    }
+    if err := fetchTrails(query, &response); err != nil {
+        logrus.Error(err)
+        return
+    }
+
+    if len(response.Results) == 0 {
+        logrus.Info("No trails found for the given search.")
+        return
+    }
+
+    table := tablewriter.NewWriter(os.Stdout)
+    table.SetHeader([]string{"Rank", "Name", "AKA", "Address", "Difficulty"})
+    for _, result := range response.Results {
+        // Show the highlighted version of each searched field that matched
+        field := func(name, value string) string {
+            if highlighted, ok := result.Highlights[name]; ok {
+                return highlightMarkers.Replace(highlighted)
+            }
+            return value
+        }
+        table.Append([]string{
+            fmt.Sprintf("%.2f", result.Rank), field("name", result.Name), field("aka", result.AKA),
+            field("address", result.Address), result.Difficulty,
+        })
+    }
+
+    logrus.Infof("Showing page %d with %d results per page:", response.Page, response.Limit)
+    table.Render()
+}
@@ -0,0 +1,139 @@
+package cmd
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "strconv"
+    "trail-finder/handlers"
+    "trail-finder/store"
+
+    "github.com/joho/godotenv"
+    "github.com/olekukonko/tablewriter"
+    "github.com/sirupsen/logrus"
+    "github.com/spf13/cobra"
+)
+
+var versionsCmd = &cobra.Command{
+    Use:   "versions",
+    Short: "List and restore dataset versions",
+    Long: `Every successful load records a dataset version: a snapshot of the trails it left, with the
+SHA-256 hash of the loaded file. Versions can be listed, queried through GET /trails?as_of=<id>,
+and restored with rollback.`,
+}
+
+var versionsListCmd = &cobra.Command{
+    Use:   "list",
+    Short: "List recent dataset versions",
+    Long:  `List the most recent dataset versions, newest first, with their source file, hash and trail count.`,
+    Args:  cobra.NoArgs,
+    RunE:  listVersions,
+}
+
+var versionsRollbackCmd = &cobra.Command{
+    Use:   "rollback <id>",
+    Short: "Restore the trails of a dataset version",
+    Long: `Replace the stored trails with the snapshot of a dataset version. The rollback is itself recorded
+as a new version, so it can be undone by rolling back to the version before it.
+
+Single-trail edits made through POST, PUT, PATCH or DELETE /trails do not record a version, so edits
+made since the last load or rollback are discarded by a rollback and cannot be restored.`,
+    Args: cobra.ExactArgs(1),
+    RunE: rollbackVersion,
+}
+
+func init() {
+    versionsListCmd.Flags().Int("limit", 20, "Number of versions to list")
+
+    versionsCmd.AddCommand(versionsListCmd, versionsRollbackCmd)
+    rootCmd.AddCommand(versionsCmd)
+}
+
+// openVersionStore connects to the trail store for a versions command
+func openVersionStore(cmd *cobra.Command) (store.TrailStore, func(), error) {
+    // Arguments are valid at this point, so failures are reported without the usage text
+    cmd.SilenceUsage = true
+
+    handlers.InitLogger()
+    if err := godotenv.Load(); err != nil {
+        logrus.Warn("Error loading .env file")
+    }
+
+    connString, err := dbConnString(cmd)
+    if err != nil {
+        return nil, nil, err
+    }
+    trailStore, closeStore, err := openStore(connString)
+    if err != nil {
+        return nil, nil, fmt.Errorf("failed to initialize database: %w", err)
+    }
+    return trailStore, closeStore, nil
+}
+
+func listVersions(cmd *cobra.Command, args []string) error {
+    limit, _ := cmd.Flags().GetInt("limit")
+    trailStore, closeStore, err := openVersionStore(cmd)
+    if err != nil {
+        return err
+    }
+    defer closeStore()
+
+    versions, err := trailStore.ListVersions(context.Background(), limit)
+    if err != nil {
+        return fmt.Errorf("failed to list versions: %w", err)
+    }
+    if len(versions) == 0 {
+        logrus.Info("No versions found.")
+        return nil
+    }
+
+    table := tablewriter.NewWriter(os.Stdout)
+    table.SetHeader([]string{"ID", "Source", "SHA-256", "Trails", "Rollback Of", "Created"})
+    for _, v := range versions {
+        table.Append([]string{
+            strconv.Itoa(v.ID), v.Source, shortHash(v.SHA256), strconv.Itoa(v.TrailCount),
+            formatVersionID(v.RollbackOf), formatTime(&v.CreatedAt),
+        })
+    }
+    table.Render()
+    return nil
+}
+
+func rollbackVersion(cmd *cobra.Command, args []string) error {
+    id, err := strconv.Atoi(args[0])
+    if err != nil || id <= 0 {
+        return fmt.Errorf("invalid version ID %q", args[0])
+    }
+    trailStore, closeStore, err := openVersionStore(cmd)
+    if err != nil {
+        return err
+    }
+    defer closeStore()
+
+    version, err := trailStore.Rollback(context.Background(), id)
+    if errors.Is(err, store.ErrNotFound) {
+        return fmt.Errorf("version %d not found", id)
+    }
+    if err != nil {
+        return fmt.Errorf("failed to roll back to version %d: %w", id, err)
+    }
+    logrus.Infof("Restored the %d trails of version %d, recorded as version %d", version.TrailCount, id, version.ID)
+    return nil
+}
+
+// shortHash abbreviates a SHA-256 hash for display, like a git commit ID
+func shortHash(hash string) string {
+    if len(hash) > 12 {
+        return hash[:12]
+    }
+    return hash
+}
+
+// formatVersionID renders an optional version reference, leaving 0 blank
+func formatVersionID(id int) string {
+    if id == 0 {
+        return ""
+    }
+    return strconv.Itoa(id)
+}
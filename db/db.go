@@ -3,75 +3,46 @@ package db
 import (
     "context"
     "fmt"
-
-    "github.com/jackc/pgx/v4"
 )
 
-var DbConn *pgx.Conn
+// Pool is the connection pool shared by the server's request handlers
+var Pool *Store
 
-// InitDB initializes the connection to PostgreSQL
+// InitDB initializes the PostgreSQL connection pool and brings the schema up to date.
+// Pool settings are read from the environment (see PoolConfigFromEnv).
 func InitDB(connString string) error {
-    var err error
-    DbConn, err = pgx.Connect(context.Background(), connString)
+    config, err := PoolConfigFromEnv()
     if err != nil {
-        return fmt.Errorf("failed to connect to database: %w", err)
+        return fmt.Errorf("invalid connection pool settings: %w", err)
     }
 
-    // Create the trails table if it doesn't exist
-    if err := CreateTable(); err != nil {
-        return fmt.Errorf("failed to create trails table: %w", err)
+    Pool, err = NewStore(context.Background(), connString, config)
+    if err != nil {
+        return fmt.Errorf("failed to connect to database: %w", err)
     }
 
-    fmt.Println("Database initialized successfully.")
-    return nil
-}
-
-// CreateTable creates the trails table if it doesn't exist
-func CreateTable() error {
-    createTableQuery := `
-        CREATE TABLE IF NOT EXISTS trails (
-            fid SERIAL PRIMARY KEY,
-            name TEXT,
-            restrooms TEXT,
-            picnic TEXT,
-            fishing TEXT,
-			type TEXT,
-            bike_trail TEXT,
-            horse_trail TEXT,
-            difficulty TEXT,
-            fee TEXT,
-            recycle_bin TEXT,
-            grills TEXT,
-            bike_rack TEXT,
-            dog_tube TEXT
-        )
-    `
-
-    _, err := DbConn.Exec(context.Background(), createTableQuery)
-    if err != nil {
-        return fmt.Errorf("failed to create trails table: %w", err)
+    // Apply any pending schema migrations
+    if err := Migrate(connString); err != nil {
+        return fmt.Errorf("failed to migrate database: %w", err)
     }
 
-    fmt.Println("Trails table created or already exists.")
+    fmt.Println("Database initialized successfully.")
     return nil
 }
 
-// CloseDB closes the PostgreSQL connection
+// CloseDB closes the PostgreSQL connection pool
 func CloseDB() {
-    if DbConn != nil {
-        err := DbConn.Close(context.Background())
-        if err != nil {
-            fmt.Println("Error closing database connection:", err)
-        } else {
-            fmt.Println("Database connection closed.")
-        }
+    if Pool != nil {
+        Pool.Close()
+        Pool = nil
+        fmt.Println("Database connection closed.")
     }
 }
 
 // IsTableEmpty checks if the trails table is empty
 func IsTableEmpty() (bool, error) {
     var count int
-    err := DbConn.QueryRow(context.Background(), "SELECT COUNT(*) FROM trails").Scan(&count)
+    err := Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM trails WHERE deleted_at IS NULL").Scan(&count)
     if err != nil {
         return false, fmt.Errorf("failed to count trails: %w", err)
     }
@@ -19,7 +19,7 @@ func setupTestDB(t *testing.T) {
     }
 
     // Clear the trails table before each test to prevent duplicates
-    _, err = DbConn.Exec(context.Background(), "TRUNCATE TABLE trails RESTART IDENTITY CASCADE")
+    _, err = Pool.Exec(context.Background(), "TRUNCATE TABLE trails RESTART IDENTITY CASCADE")
     if err != nil {
         t.Fatalf("Failed to clear trails table: %v", err)
     }
@@ -27,12 +27,7 @@ func setupTestDB(t *testing.T) {
 
 func tearDownTestDB(t *testing.T) {
     // Clean up the database after tests
-    if DbConn != nil {
-        err := DbConn.Close(context.Background())
-        if err != nil {
-            t.Fatalf("Failed to close test database connection: %v", err)
-        }
-    }
+    CloseDB()
 }
 
 func TestDatabaseConnection(t *testing.T) {
@@ -40,7 +35,7 @@ func TestDatabaseConnection(t *testing.T) {
     defer tearDownTestDB(t)
 
     // Check if the connection is established
-    assert.NotNil(t, DbConn, "Expected DbConn to be initialized")
+    assert.NotNil(t, Pool, "Expected Pool to be initialized")
 }
 
 func TestCreateTable(t *testing.T) {
@@ -49,7 +44,7 @@ func TestCreateTable(t *testing.T) {
 
     // Verify that the table is created
     var tableName string
-    err := DbConn.QueryRow(context.Background(), "SELECT table_name FROM information_schema.tables WHERE table_name='trails'").Scan(&tableName)
+    err := Pool.QueryRow(context.Background(), "SELECT table_name FROM information_schema.tables WHERE table_name='trails'").Scan(&tableName)
     assert.Nil(t, err, "Expected table 'trails' to exist")
     assert.Equal(t, "trails", tableName, "Expected table name to be 'trails'")
 }
@@ -59,11 +54,11 @@ func TestInsertTrail(t *testing.T) {
     defer tearDownTestDB(t)
 
     // Insert a sample trail into the database
-    _, err := DbConn.Exec(context.Background(), `
+    _, err := Pool.Exec(context.Background(), `
     INSERT INTO trails 
     (fid, name, restrooms, picnic, fishing, type, difficulty, access_type, th_leash, bike_trail, horse_trail, fee, recycle_bin, grills, bike_rack, dog_tube) 
     VALUES 
-    (1, 'Test Trail', 'yes', 'yes', 'no', 'hiking', 'easy', 'TH', 'Yes', 'yes', 'possible', 'no', 'yes', '2', 'yes', '1')
+    (1, 'Test Trail', 'yes', 'yes', 'no', 'hiking', 'easy', 'TH', 'Yes', 'yes', 'possible', 'no', 'yes', 'yes', 'yes', '1')
 `)
 if err != nil {
     t.Fatalf("Failed to insert mock data: %v", err)
@@ -72,7 +67,7 @@ if err != nil {
 
     // Verify that the trail is inserted
     var count int
-    err = DbConn.QueryRow(context.Background(), "SELECT COUNT(*) FROM trails WHERE name='Test Trail'").Scan(&count)
+    err = Pool.QueryRow(context.Background(), "SELECT COUNT(*) FROM trails WHERE name='Test Trail'").Scan(&count)
     assert.Nil(t, err, "Failed to count trails in the database")
     assert.Equal(t, 1, count, "Expected 1 row in the trails table")
 }
@@ -82,6 +77,6 @@ func TestCleanUpTrails(t *testing.T) {
     defer tearDownTestDB(t)
 
     // Clean up the trails table after testing
-    _, err := DbConn.Exec(context.Background(), "DELETE FROM trails")
+    _, err := Pool.Exec(context.Background(), "DELETE FROM trails")
     assert.Nil(t, err, "Failed to clean up the trails table")
 }
@@ -0,0 +1,52 @@
+package db
+
+import (
+    "fmt"
+    "strings"
+)
+
+// Supported storage backends, selected by the scheme of the connection string
+const (
+    DriverPostgres = "postgres"
+    DriverSQLite   = "sqlite"
+)
+
+// DriverFor returns the backend named by the connection string's scheme.
+// postgres:// and postgresql:// URLs, and key=value connection strings, select PostgreSQL;
+// sqlite://, sqlite3:// and file: select SQLite.
+func DriverFor(connString string) (string, error) {
+    if connString == "" {
+        return "", fmt.Errorf("connection string is empty")
+    }
+
+    scheme, _, ok := strings.Cut(connString, ":")
+    if !ok || strings.ContainsAny(scheme, " =") {
+        // libpq key=value strings such as "host=localhost dbname=trails" have no scheme
+        return DriverPostgres, nil
+    }
+
+    switch strings.ToLower(scheme) {
+    case "postgres", "postgresql":
+        return DriverPostgres, nil
+    case "sqlite", "sqlite3", "file":
+        return DriverSQLite, nil
+    default:
+        return "", fmt.Errorf("unsupported database scheme %q (use postgres:// or sqlite://)", scheme)
+    }
+}
+
+// sqliteDSN converts a sqlite:// connection string into the file name and options understood by the
+// SQLite driver. sqlite://trails.db is relative to the working directory, sqlite:///var/lib/trails.db
+// is absolute and sqlite://:memory: is an in-memory database. file: URIs are passed through unchanged.
+func sqliteDSN(connString string) (string, error) {
+    for _, prefix := range []string{"sqlite://", "sqlite3://"} {
+        if len(connString) >= len(prefix) && strings.EqualFold(connString[:len(prefix)], prefix) {
+            connString = connString[len(prefix):]
+            break
+        }
+    }
+    if strings.HasPrefix(connString, "?") || connString == "" {
+        return "", fmt.Errorf("sqlite connection string must name a database file")
+    }
+    return connString, nil
+}
@@ -0,0 +1,182 @@
+package db
+
+import (
+    "database/sql"
+    "errors"
+    "fmt"
+    "io/fs"
+    "trail-finder/migrations"
+
+    "github.com/golang-migrate/migrate/v4"
+    "github.com/golang-migrate/migrate/v4/database"
+    "github.com/golang-migrate/migrate/v4/database/postgres"
+    "github.com/golang-migrate/migrate/v4/database/sqlite"
+    "github.com/golang-migrate/migrate/v4/source/iofs"
+
+    _ "github.com/jackc/pgx/v4/stdlib"
+)
+
+// MigrationFiles returns the embedded migrations for a backend returned by DriverFor
+func MigrationFiles(driver string) (fs.FS, error) {
+    switch driver {
+    case DriverPostgres:
+        return migrations.FS, nil
+    case DriverSQLite:
+        return fs.Sub(migrations.SQLiteFS, "sqlite")
+    default:
+        return nil, fmt.Errorf("no migrations for database driver %q", driver)
+    }
+}
+
+// NewMigrate creates a migrate instance that applies the embedded migrations to the given database.
+// The backend is chosen by the connection string's scheme. The caller is responsible for closing it.
+// Creating it does not change the database, so it can be used to inspect the schema version.
+func NewMigrate(connString string) (*migrate.Migrate, error) {
+    m, _, _, err := openMigrate(connString)
+    return m, err
+}
+
+// NewMigrateUp creates a migrate instance like NewMigrate, for applying migrations. A PostgreSQL trails table
+// created by application code before the migrations defined the schema is first adopted, so they apply to it.
+func NewMigrateUp(connString string) (*migrate.Migrate, error) {
+    m, sqlDB, driverName, err := openMigrate(connString)
+    if err != nil {
+        return nil, err
+    }
+    if driverName == DriverPostgres {
+        if err := adoptLegacySchema(m, sqlDB); err != nil {
+            m.Close()
+            return nil, err
+        }
+    }
+    return m, nil
+}
+
+// openMigrate creates a migrate instance for the connection string, returning the handle it owns and the backend
+func openMigrate(connString string) (*migrate.Migrate, *sql.DB, string, error) {
+    driverName, err := DriverFor(connString)
+    if err != nil {
+        return nil, nil, "", err
+    }
+
+    var sqlDB *sql.DB
+    switch driverName {
+    case DriverSQLite:
+        dsn, err := sqliteDSN(connString)
+        if err != nil {
+            return nil, nil, "", err
+        }
+        sqlDB, err = sql.Open("sqlite", dsn)
+        if err != nil {
+            return nil, nil, "", fmt.Errorf("failed to open database for migrations: %w", err)
+        }
+    default:
+        sqlDB, err = sql.Open("pgx", connString)
+        if err != nil {
+            return nil, nil, "", fmt.Errorf("failed to open database for migrations: %w", err)
+        }
+    }
+
+    m, err := newMigrateWithInstance(driverName, sqlDB)
+    if err != nil {
+        sqlDB.Close()
+        return nil, nil, "", err
+    }
+    return m, sqlDB, driverName, nil
+}
+
+// adoptLegacySchema prepares a trails table created by application code, before the migrations defined the
+// schema, so the migrations apply to it. Such tables have no migration version, or version 1 or 2 when the
+// migrations were run after the application created the table, since versions 1 and 2 do not recreate it.
+// Databases past version 2, with a dirty version, or without a trails table, are left alone. The first such
+// tables had text amenity columns but no th_leash or access_type column, which version 3 converts, so those are
+// added as text. Later ones were created with the typed columns of version 3, so the database is marked as at
+// version 3 and version 4 adds the columns that are still missing.
+func adoptLegacySchema(m *migrate.Migrate, sqlDB *sql.DB) error {
+    version, dirty, err := m.Version()
+    if err != nil && !errors.Is(err, migrate.ErrNilVersion) {
+        return fmt.Errorf("failed to read the migration version: %w", err)
+    }
+    if err == nil && (dirty || version >= 3) {
+        return nil
+    }
+
+    var restrooms sql.NullString
+    err = sqlDB.QueryRow("SELECT data_type FROM information_schema.columns " +
+        "WHERE table_schema = current_schema() AND table_name = 'trails' AND column_name = 'restrooms'").Scan(&restrooms)
+    if errors.Is(err, sql.ErrNoRows) {
+        return nil
+    }
+    if err != nil {
+        return fmt.Errorf("failed to inspect the trails table: %w", err)
+    }
+
+    if restrooms.String == "boolean" {
+        if err := m.Force(3); err != nil {
+            return fmt.Errorf("failed to mark the typed trails table as migrated: %w", err)
+        }
+        return nil
+    }
+    if _, err := sqlDB.Exec("ALTER TABLE trails ADD COLUMN IF NOT EXISTS th_leash TEXT, ADD COLUMN IF NOT EXISTS access_type TEXT"); err != nil {
+        return fmt.Errorf("failed to add the missing trails columns: %w", err)
+    }
+    return nil
+}
+
+// newMigrateWithInstance creates a migrate instance that runs the backend's embedded migrations on sqlDB.
+// Closing the returned instance also closes sqlDB.
+func newMigrateWithInstance(driverName string, sqlDB *sql.DB) (*migrate.Migrate, error) {
+    files, err := MigrationFiles(driverName)
+    if err != nil {
+        return nil, err
+    }
+    source, err := iofs.New(files, ".")
+    if err != nil {
+        return nil, fmt.Errorf("failed to load embedded migrations: %w", err)
+    }
+
+    var driver database.Driver
+    switch driverName {
+    case DriverSQLite:
+        driver, err = sqlite.WithInstance(sqlDB, &sqlite.Config{})
+    default:
+        driver, err = postgres.WithInstance(sqlDB, &postgres.Config{})
+    }
+    if err != nil {
+        return nil, fmt.Errorf("failed to create migration driver: %w", err)
+    }
+
+    m, err := migrate.NewWithInstance("iofs", source, driverName, driver)
+    if err != nil {
+        return nil, fmt.Errorf("failed to initialize migrations: %w", err)
+    }
+    return m, nil
+}
+
+// Migrate applies all pending embedded migrations to the database
+func Migrate(connString string) error {
+    m, err := NewMigrateUp(connString)
+    if err != nil {
+        return err
+    }
+    defer m.Close()
+
+    if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+        return fmt.Errorf("failed to apply migrations: %w", err)
+    }
+    return nil
+}
+
+// migrateSQLite applies the pending SQLite migrations on an open handle. The migrate instance is not
+// closed, since that would close the handle, which the caller keeps using.
+func migrateSQLite(sqlDB *sql.DB) error {
+    m, err := newMigrateWithInstance(DriverSQLite, sqlDB)
+    if err != nil {
+        return err
+    }
+
+    if err := m.Up(); err != nil && !errors.Is(err, migrate.ErrNoChange) {
+        return fmt.Errorf("failed to apply migrations: %w", err)
+    }
+    return nil
+}
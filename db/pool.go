@@ -0,0 +1,244 @@
+package db
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "os"
+    "strconv"
+    "time"
+
+    "github.com/jackc/pgconn"
+    "github.com/jackc/pgx/v4"
+    "github.com/jackc/pgx/v4/pgxpool"
+)
+
+// ErrAcquireTimeout is returned when no pooled connection becomes free within the acquire timeout
+var ErrAcquireTimeout = errors.New("timed out waiting for a database connection")
+
+// PoolConfig controls the size and health checking of the connection pool
+type PoolConfig struct {
+    MinConns          int32         // Connections kept open even when idle
+    MaxConns          int32         // Upper bound on open connections
+    HealthCheckPeriod time.Duration // How often idle connections are checked and replaced
+    MaxConnLifetime   time.Duration // Connections older than this are closed and replaced
+    MaxConnIdleTime   time.Duration // Idle connections older than this are closed
+    AcquireTimeout    time.Duration // How long a request waits for a free connection
+}
+
+// DefaultPoolConfig returns the pool settings used when nothing is configured
+func DefaultPoolConfig() PoolConfig {
+    return PoolConfig{
+        MinConns:          2,
+        MaxConns:          10,
+        HealthCheckPeriod: time.Minute,
+        MaxConnLifetime:   time.Hour,
+        MaxConnIdleTime:   30 * time.Minute,
+        AcquireTimeout:    5 * time.Second,
+    }
+}
+
+// PoolConfigFromEnv reads pool settings from DB_MIN_CONNS, DB_MAX_CONNS, DB_HEALTH_CHECK_PERIOD,
+// DB_MAX_CONN_LIFETIME, DB_MAX_CONN_IDLE_TIME and DB_ACQUIRE_TIMEOUT, falling back to the defaults
+func PoolConfigFromEnv() (PoolConfig, error) {
+    config := DefaultPoolConfig()
+
+    ints := map[string]*int32{
+        "DB_MIN_CONNS": &config.MinConns,
+        "DB_MAX_CONNS": &config.MaxConns,
+    }
+    for name, target := range ints {
+        if value, ok := os.LookupEnv(name); ok && value != "" {
+            n, err := strconv.ParseInt(value, 10, 32)
+            if err != nil || n < 0 {
+                return config, fmt.Errorf("invalid %s: %q", name, value)
+            }
+            *target = int32(n)
+        }
+    }
+
+    durations := map[string]*time.Duration{
+        "DB_HEALTH_CHECK_PERIOD": &config.HealthCheckPeriod,
+        "DB_MAX_CONN_LIFETIME":   &config.MaxConnLifetime,
+        "DB_MAX_CONN_IDLE_TIME":  &config.MaxConnIdleTime,
+        "DB_ACQUIRE_TIMEOUT":     &config.AcquireTimeout,
+    }
+    for name, target := range durations {
+        if value, ok := os.LookupEnv(name); ok && value != "" {
+            d, err := time.ParseDuration(value)
+            if err != nil || d <= 0 {
+                return config, fmt.Errorf("invalid %s: %q", name, value)
+            }
+            *target = d
+        }
+    }
+
+    if config.MaxConns < 1 {
+        return config, fmt.Errorf("DB_MAX_CONNS must be at least 1")
+    }
+    if config.MinConns > config.MaxConns {
+        return config, fmt.Errorf("DB_MIN_CONNS (%d) cannot exceed DB_MAX_CONNS (%d)", config.MinConns, config.MaxConns)
+    }
+    return config, nil
+}
+
+// Store is a pgxpool-backed connection pool that is safe for concurrent use by request handlers.
+// The pool is not exposed: every query goes through a connection taken within the acquire timeout,
+// so an exhausted pool fails requests with ErrAcquireTimeout instead of blocking them.
+type Store struct {
+    pool   *pgxpool.Pool
+    config PoolConfig
+}
+
+// NewStore opens a connection pool with the given settings and verifies that the database is reachable
+func NewStore(ctx context.Context, connString string, config PoolConfig) (*Store, error) {
+    poolConfig, err := pgxpool.ParseConfig(connString)
+    if err != nil {
+        return nil, fmt.Errorf("invalid connection string: %w", err)
+    }
+
+    poolConfig.MinConns = config.MinConns
+    poolConfig.MaxConns = config.MaxConns
+    poolConfig.HealthCheckPeriod = config.HealthCheckPeriod
+    poolConfig.MaxConnLifetime = config.MaxConnLifetime
+    poolConfig.MaxConnIdleTime = config.MaxConnIdleTime
+
+    pool, err := pgxpool.ConnectConfig(ctx, poolConfig)
+    if err != nil {
+        return nil, fmt.Errorf("failed to create connection pool: %w", err)
+    }
+
+    store := &Store{pool: pool, config: config}
+    if err := store.Ping(ctx); err != nil {
+        pool.Close()
+        return nil, err
+    }
+    return store, nil
+}
+
+// Acquire takes a connection from the pool, waiting at most the configured acquire timeout.
+// The caller must Release the connection when done.
+func (s *Store) Acquire(ctx context.Context) (*pgxpool.Conn, error) {
+    acquireCtx, cancel := context.WithTimeout(ctx, s.config.AcquireTimeout)
+    defer cancel()
+
+    conn, err := s.pool.Acquire(acquireCtx)
+    if err != nil {
+        if errors.Is(acquireCtx.Err(), context.DeadlineExceeded) && ctx.Err() == nil {
+            return nil, ErrAcquireTimeout
+        }
+        return nil, fmt.Errorf("failed to acquire database connection: %w", err)
+    }
+    return conn, nil
+}
+
+// QueryRow runs a query returning at most one row on a connection acquired within the acquire timeout.
+// Errors, including failing to acquire a connection, are returned by Scan, which releases the connection.
+func (s *Store) QueryRow(ctx context.Context, sql string, args ...interface{}) pgx.Row {
+    conn, err := s.Acquire(ctx)
+    if err != nil {
+        return errRow{err}
+    }
+    return &connRow{row: conn.QueryRow(ctx, sql, args...), conn: conn}
+}
+
+// Exec runs a statement on a connection acquired within the acquire timeout
+func (s *Store) Exec(ctx context.Context, sql string, args ...interface{}) (pgconn.CommandTag, error) {
+    conn, err := s.Acquire(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Release()
+    return conn.Exec(ctx, sql, args...)
+}
+
+// Begin starts a transaction on a connection acquired within the acquire timeout.
+// The connection is released when the transaction is committed or rolled back.
+func (s *Store) Begin(ctx context.Context) (pgx.Tx, error) {
+    conn, err := s.Acquire(ctx)
+    if err != nil {
+        return nil, err
+    }
+    tx, err := conn.Begin(ctx)
+    if err != nil {
+        conn.Release()
+        return nil, err
+    }
+    return &connTx{Tx: tx, conn: conn}, nil
+}
+
+// Close closes every connection of the pool
+func (s *Store) Close() {
+    s.pool.Close()
+}
+
+// Ping checks that a connection can be acquired and the database responds
+func (s *Store) Ping(ctx context.Context) error {
+    conn, err := s.Acquire(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Release()
+
+    if err := conn.Conn().Ping(ctx); err != nil {
+        return fmt.Errorf("database ping failed: %w", err)
+    }
+    return nil
+}
+
+// Stats reports the current pool usage, for health and diagnostic output
+func (s *Store) Stats() map[string]int32 {
+    stat := s.pool.Stat()
+    return map[string]int32{
+        "total_conns":    stat.TotalConns(),
+        "idle_conns":     stat.IdleConns(),
+        "acquired_conns": stat.AcquiredConns(),
+        "max_conns":      stat.MaxConns(),
+    }
+}
+
+// errRow is the row of a query that could not run, returning the error from Scan
+type errRow struct {
+    err error
+}
+
+func (r errRow) Scan(dest ...interface{}) error {
+    return r.err
+}
+
+// connRow is a row read on an acquired connection, released once the row is scanned
+type connRow struct {
+    row  pgx.Row
+    conn *pgxpool.Conn
+}
+
+func (r *connRow) Scan(dest ...interface{}) error {
+    defer r.conn.Release()
+    return r.row.Scan(dest...)
+}
+
+// connTx is a transaction on an acquired connection, released when the transaction ends
+type connTx struct {
+    pgx.Tx
+    conn *pgxpool.Conn
+}
+
+func (t *connTx) Commit(ctx context.Context) error {
+    err := t.Tx.Commit(ctx)
+    t.release()
+    return err
+}
+
+func (t *connTx) Rollback(ctx context.Context) error {
+    err := t.Tx.Rollback(ctx)
+    t.release()
+    return err
+}
+
+// release returns the connection to the pool the first time the transaction ends
+func (t *connTx) release() {
+    if t.conn != nil {
+        t.conn.Release()
+        t.conn = nil
+    }
+}
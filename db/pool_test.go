@@ -0,0 +1,33 @@
+package db
+
+import (
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestPoolConfigFromEnv(t *testing.T) {
+    t.Setenv("DB_MIN_CONNS", "1")
+    t.Setenv("DB_MAX_CONNS", "20")
+    t.Setenv("DB_ACQUIRE_TIMEOUT", "250ms")
+
+    config, err := PoolConfigFromEnv()
+    assert.Nil(t, err, "Expected pool settings to parse")
+    assert.Equal(t, int32(1), config.MinConns)
+    assert.Equal(t, int32(20), config.MaxConns)
+    assert.Equal(t, 250*time.Millisecond, config.AcquireTimeout)
+    assert.Equal(t, DefaultPoolConfig().HealthCheckPeriod, config.HealthCheckPeriod, "Expected unset values to keep their defaults")
+}
+
+func TestPoolConfigFromEnvInvalid(t *testing.T) {
+    t.Setenv("DB_MIN_CONNS", "5")
+    t.Setenv("DB_MAX_CONNS", "2")
+    _, err := PoolConfigFromEnv()
+    assert.NotNil(t, err, "Expected min conns above max conns to be rejected")
+
+    t.Setenv("DB_MAX_CONNS", "10")
+    t.Setenv("DB_ACQUIRE_TIMEOUT", "soon")
+    _, err = PoolConfigFromEnv()
+    assert.NotNil(t, err, "Expected an invalid duration to be rejected")
+}
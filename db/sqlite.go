@@ -0,0 +1,46 @@
+package db
+
+import (
+    "context"
+    "database/sql"
+    "fmt"
+
+    _ "modernc.org/sqlite" // Pure-Go SQLite driver, so the binaries build with CGO_ENABLED=0
+)
+
+// OpenSQLite opens the SQLite database named by a sqlite:// connection string and brings its schema up to date.
+// SQLite allows a single writer, so the handle is limited to one connection; this also keeps every
+// request on the same database when it is :memory:.
+func OpenSQLite(connString string) (*sql.DB, error) {
+    dsn, err := sqliteDSN(connString)
+    if err != nil {
+        return nil, err
+    }
+
+    sqlDB, err := sql.Open("sqlite", dsn)
+    if err != nil {
+        return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+    }
+    sqlDB.SetMaxOpenConns(1)
+    // Keep the connection open so an in-memory database is not discarded between requests
+    sqlDB.SetConnMaxIdleTime(0)
+    sqlDB.SetConnMaxLifetime(0)
+
+    if err := sqlDB.PingContext(context.Background()); err != nil {
+        sqlDB.Close()
+        return nil, fmt.Errorf("failed to connect to sqlite database: %w", err)
+    }
+    if _, err := sqlDB.Exec("PRAGMA busy_timeout = 5000"); err != nil {
+        sqlDB.Close()
+        return nil, fmt.Errorf("failed to configure sqlite database: %w", err)
+    }
+
+    // Apply any pending schema migrations on this handle
+    if err := migrateSQLite(sqlDB); err != nil {
+        sqlDB.Close()
+        return nil, fmt.Errorf("failed to migrate database: %w", err)
+    }
+
+    fmt.Println("Database initialized successfully.")
+    return sqlDB, nil
+}
@@ -0,0 +1,325 @@
+package handlers
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "trail-finder/models"
+
+    "github.com/sirupsen/logrus"
+)
+
+// maxTrailBodyBytes caps the size of a single trail request body
+const maxTrailBodyBytes = 1 << 20
+
+// errTrailChanged aborts a change whose If-Match header names another version of the trail
+var errTrailChanged = errors.New("trail changed since it was read")
+
+// errResponded aborts a change whose request was rejected, after the error response was written
+var errResponded = errors.New("request rejected")
+
+// GetTrail handles GET /trails/{fid}
+func (h *TrailHandler) GetTrail(w http.ResponseWriter, r *http.Request) {
+    fid, ok := pathFID(w, r)
+    if !ok {
+        return
+    }
+
+    trail, err := h.Store.Get(r.Context(), fid)
+    if err != nil {
+        writeStoreError(w, err, "get trail")
+        return
+    }
+    w.Header().Set("ETag", trailETag(&trail))
+    writeJSON(w, http.StatusOK, trail)
+}
+
+// CreateTrail handles POST /trails, adding a trail with a fid that is not taken yet
+func (h *TrailHandler) CreateTrail(w http.ResponseWriter, r *http.Request) {
+    fields, ok := readTrailFields(w, r)
+    if !ok {
+        return
+    }
+    var missing []models.FieldError
+    if _, ok := fields["fid"]; !ok {
+        missing = append(missing, models.FieldError{Field: "fid", Message: "is required"})
+    }
+    trail, ok := trailFromFields(w, fields, missing...)
+    if !ok {
+        return
+    }
+
+    if err := h.Store.Insert(r.Context(), trail); err != nil {
+        writeStoreError(w, err, "create trail")
+        return
+    }
+
+    logrus.Infof("Created trail %d", trail.FID)
+    w.Header().Set("Location", fmt.Sprintf("/trails/%d", trail.FID))
+    w.Header().Set("ETag", trailETag(&trail))
+    writeJSON(w, http.StatusCreated, trail)
+}
+
+// ReplaceTrail handles PUT /trails/{fid}, replacing every field of an existing trail.
+// Fields left out of the body are reset to their defaults. With If-Match, the trail is only replaced
+// if it still has one of the given entity tags.
+func (h *TrailHandler) ReplaceTrail(w http.ResponseWriter, r *http.Request) {
+    fid, ok := pathFID(w, r)
+    if !ok {
+        return
+    }
+    fields, ok := readTrailFields(w, r)
+    if !ok {
+        return
+    }
+    if !setPathFID(w, fields, fid) {
+        return
+    }
+    replacement, ok := trailFromFields(w, fields)
+    if !ok {
+        return
+    }
+
+    trail, err := h.Store.Modify(r.Context(), fid, func(existing models.Trail) (models.Trail, error) {
+        if !ifMatch(r, trailETag(&existing)) {
+            return models.Trail{}, errTrailChanged
+        }
+        return replacement, nil
+    })
+    if !writeModifyError(w, err) {
+        return
+    }
+
+    logrus.Infof("Replaced trail %d", trail.FID)
+    w.Header().Set("ETag", trailETag(&trail))
+    writeJSON(w, http.StatusOK, trail)
+}
+
+// PatchTrail handles PATCH /trails/{fid} with a JSON merge patch (RFC 7396): the given fields are changed,
+// null resets a field to its default, and the other fields keep their values. With If-Match, the patch is
+// only applied if the trail still has one of the given entity tags.
+func (h *TrailHandler) PatchTrail(w http.ResponseWriter, r *http.Request) {
+    fid, ok := pathFID(w, r)
+    if !ok {
+        return
+    }
+    patch, ok := readTrailFields(w, r)
+    if !ok {
+        return
+    }
+    if !setPathFID(w, patch, fid) {
+        return
+    }
+
+    // Overlay the patch on the stored fields while the trail is locked, so concurrent patches are not lost
+    trail, err := h.Store.Modify(r.Context(), fid, func(existing models.Trail) (models.Trail, error) {
+        if !ifMatch(r, trailETag(&existing)) {
+            return models.Trail{}, errTrailChanged
+        }
+        fields, err := trailFields(&existing)
+        if err != nil {
+            return models.Trail{}, err
+        }
+        for field, value := range patch {
+            if string(value) == "null" {
+                delete(fields, field)
+            } else {
+                fields[field] = value
+            }
+        }
+        trail, ok := trailFromFields(w, fields)
+        if !ok {
+            return models.Trail{}, errResponded
+        }
+        return trail, nil
+    })
+    if !writeModifyError(w, err) {
+        return
+    }
+
+    logrus.Infof("Patched trail %d", trail.FID)
+    w.Header().Set("ETag", trailETag(&trail))
+    writeJSON(w, http.StatusOK, trail)
+}
+
+// DeleteTrail handles DELETE /trails/{fid}
+func (h *TrailHandler) DeleteTrail(w http.ResponseWriter, r *http.Request) {
+    fid, ok := pathFID(w, r)
+    if !ok {
+        return
+    }
+
+    if err := h.Store.Delete(r.Context(), fid); err != nil {
+        writeStoreError(w, err, "delete trail")
+        return
+    }
+
+    logrus.Infof("Deleted trail %d", fid)
+    w.WriteHeader(http.StatusNoContent)
+}
+
+// writeModifyError responds to a failed Modify of a trail, returning false when there was an error.
+// A trail whose entity tag no longer matches If-Match is a 412.
+func writeModifyError(w http.ResponseWriter, err error) bool {
+    switch {
+    case err == nil:
+        return true
+    case errors.Is(err, errResponded):
+    case errors.Is(err, errTrailChanged):
+        writeError(w, http.StatusPreconditionFailed, "Trail has changed since it was read, fetch it again and retry")
+    default:
+        writeStoreError(w, err, "update trail")
+    }
+    return false
+}
+
+// trailETag returns the entity tag of a trail, a hash of its JSON representation that changes with any field
+func trailETag(trail *models.Trail) string {
+    data, _ := json.Marshal(trail)
+    sum := sha256.Sum256(data)
+    return `"` + hex.EncodeToString(sum[:8]) + `"`
+}
+
+// ifMatch reports whether the If-Match header of the request allows changing a trail with the given entity tag:
+// it is missing, is *, or lists the tag
+func ifMatch(r *http.Request, etag string) bool {
+    header := r.Header.Get("If-Match")
+    if header == "" {
+        return true
+    }
+    for _, tag := range strings.Split(header, ",") {
+        if tag = strings.TrimSpace(tag); tag == "*" || tag == etag {
+            return true
+        }
+    }
+    return false
+}
+
+// pathFID parses the {fid} path segment, responding with 400 when it is not an integer
+func pathFID(w http.ResponseWriter, r *http.Request) (int, bool) {
+    fid, err := strconv.Atoi(r.PathValue("fid"))
+    if err != nil {
+        writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid trail fid %q", r.PathValue("fid")))
+        return 0, false
+    }
+    return fid, true
+}
+
+// readTrailFields decodes a request body holding a JSON object of trail fields.
+// Malformed JSON is a 400; fields that are not trails columns are a 422 naming each of them.
+func readTrailFields(w http.ResponseWriter, r *http.Request) (map[string]json.RawMessage, bool) {
+    var fields map[string]json.RawMessage
+    decoder := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxTrailBodyBytes))
+    if err := decoder.Decode(&fields); err != nil || decoder.More() {
+        var tooLarge *http.MaxBytesError
+        if errors.As(err, &tooLarge) {
+            writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("request body must not exceed %d bytes", tooLarge.Limit))
+            return nil, false
+        }
+        writeError(w, http.StatusBadRequest, "request body must be a single JSON object")
+        return nil, false
+    }
+    if fields == nil {
+        writeError(w, http.StatusBadRequest, "request body must be a single JSON object")
+        return nil, false
+    }
+
+    var unknown []models.FieldError
+    for field := range fields {
+        if _, ok := filterableFields[field]; !ok {
+            unknown = append(unknown, models.FieldError{Field: field, Message: "is not a trail field"})
+        }
+    }
+    if len(unknown) > 0 {
+        sort.Slice(unknown, func(i, j int) bool { return unknown[i].Field < unknown[j].Field })
+        writeError(w, http.StatusUnprocessableEntity, "Invalid trail", unknown...)
+        return nil, false
+    }
+    return fields, true
+}
+
+// setPathFID makes the trail fields carry the fid of the request path, rejecting a body naming a different trail
+func setPathFID(w http.ResponseWriter, fields map[string]json.RawMessage, fid int) bool {
+    path := json.RawMessage(strconv.Itoa(fid))
+    if raw, ok := fields["fid"]; ok {
+        var bodyFID int
+        if err := json.Unmarshal(raw, &bodyFID); err != nil || bodyFID != fid {
+            writeError(w, http.StatusUnprocessableEntity, "Invalid trail",
+                models.FieldError{Field: "fid", Message: fmt.Sprintf("must match the fid %d in the path", fid)})
+            return false
+        }
+    }
+    fields["fid"] = path
+    return true
+}
+
+// trailFromFields builds a normalized trail from its JSON fields, responding with 422 and every
+// invalid field when a value has the wrong type or breaks the schema constraints. invalid holds problems
+// the caller already found in the body, which are reported with the others.
+func trailFromFields(w http.ResponseWriter, fields map[string]json.RawMessage, invalid ...models.FieldError) (models.Trail, bool) {
+    var trail models.Trail
+    typeErrors := false
+    for field, raw := range fields {
+        // Decode each field on its own, so every type error is reported rather than the first
+        single, _ := json.Marshal(map[string]json.RawMessage{field: raw})
+        if err := json.Unmarshal(single, &trail); err != nil {
+            invalid = append(invalid, models.FieldError{Field: field, Message: "must be " + jsonTypeName(field)})
+            typeErrors = true
+        }
+    }
+
+    // Constraints are only checked once every value has the right type
+    if !typeErrors {
+        trail.Normalize()
+        if err := trail.Validate(); err != nil {
+            var validationErr *models.ValidationError
+            if !errors.As(err, &validationErr) {
+                writeError(w, http.StatusUnprocessableEntity, err.Error())
+                return models.Trail{}, false
+            }
+            invalid = append(invalid, validationErr.Fields...)
+        }
+    }
+    if len(invalid) > 0 {
+        sort.SliceStable(invalid, func(i, j int) bool { return filterableFields[invalid[i].Field] < filterableFields[invalid[j].Field] })
+        writeError(w, http.StatusUnprocessableEntity, "Invalid trail", invalid...)
+        return models.Trail{}, false
+    }
+    return trail, true
+}
+
+// trailFields returns the JSON fields of a stored trail, the base a merge patch is applied to
+func trailFields(trail *models.Trail) (map[string]json.RawMessage, error) {
+    data, err := json.Marshal(trail)
+    if err != nil {
+        return nil, err
+    }
+    var fields map[string]json.RawMessage
+    err = json.Unmarshal(data, &fields)
+    return fields, err
+}
+
+// jsonTypeName describes the JSON value a trail field expects
+func jsonTypeName(field string) string {
+    switch models.FieldKinds[field] {
+    case models.KindBool:
+        return "a boolean"
+    case models.KindCount:
+        if field == "fid" {
+            return "an integer"
+        }
+        return "an integer or null"
+    case models.KindDate:
+        return "an RFC 3339 date or null"
+    case models.KindCoordinate:
+        return "a number or null"
+    default:
+        return "a string"
+    }
+}
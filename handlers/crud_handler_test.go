@@ -0,0 +1,185 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "trail-finder/models"
+    "trail-finder/store"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// trailMux routes the trail endpoints the way main registers them, so path values are set
+func trailMux(h *TrailHandler) *http.ServeMux {
+    mux := http.NewServeMux()
+    mux.HandleFunc("GET /trails", h.GetTrails)
+    mux.HandleFunc("POST /trails", h.CreateTrail)
+    mux.HandleFunc("GET /trails/facets", h.GetFacets)
+    mux.HandleFunc("GET /trails/{fid}", h.GetTrail)
+    mux.HandleFunc("PUT /trails/{fid}", h.ReplaceTrail)
+    mux.HandleFunc("PATCH /trails/{fid}", h.PatchTrail)
+    mux.HandleFunc("DELETE /trails/{fid}", h.DeleteTrail)
+    return mux
+}
+
+// serve sends a request through the mux and returns the recorded response
+func serve(mux http.Handler, method, target, body string) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(method, target, strings.NewReader(body))
+    w := httptest.NewRecorder()
+    mux.ServeHTTP(w, req)
+    return w
+}
+
+// decodeError decodes the JSON error envelope of a response
+func decodeError(t *testing.T, w *httptest.ResponseRecorder) errorDetail {
+    var body errorBody
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body), "expected a JSON error envelope: %s", w.Body.String())
+    return body.Error
+}
+
+func TestTrailCRUD(t *testing.T) {
+    s := setupTestStore(t)
+    mux := trailMux(NewTrailHandler(s))
+
+    // Create a trail; text is normalized like the CSV loader
+    w := serve(mux, http.MethodPost, "/trails", `{"fid": 7, "name": " Heil Valley Ranch ", "restrooms": true, "park_spaces": 40}`)
+    require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+    assert.Equal(t, "/trails/7", w.Header().Get("Location"))
+
+    w = serve(mux, http.MethodGet, "/trails/7", "")
+    require.Equal(t, http.StatusOK, w.Code)
+    var trail models.Trail
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &trail))
+    assert.Equal(t, "heil valley ranch", trail.Name)
+    assert.Equal(t, models.HorseTrailNA, trail.HorseTrail)
+    require.NotNil(t, trail.ParkSpaces)
+    assert.Equal(t, 40, *trail.ParkSpaces)
+
+    // Creating the same fid again conflicts
+    w = serve(mux, http.MethodPost, "/trails", `{"fid": 7, "name": "duplicate"}`)
+    assert.Equal(t, http.StatusConflict, w.Code)
+    assert.Equal(t, "conflict", decodeError(t, w).Code)
+
+    // PATCH changes the given fields, resets null ones and keeps the rest
+    w = serve(mux, http.MethodPatch, "/trails/7", `{"fee": true, "park_spaces": null}`)
+    require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    trail, err := s.Get(context.Background(), 7)
+    require.NoError(t, err)
+    assert.True(t, trail.Fee)
+    assert.True(t, trail.Restrooms)
+    assert.Nil(t, trail.ParkSpaces)
+
+    // With If-Match, changes only apply to the version of the trail the client read
+    w = serve(mux, http.MethodGet, "/trails/7", "")
+    etag := w.Header().Get("ETag")
+    require.NotEmpty(t, etag)
+    conditional := func(method, match, body string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(method, "/trails/7", strings.NewReader(body))
+        req.Header.Set("If-Match", match)
+        w := httptest.NewRecorder()
+        mux.ServeHTTP(w, req)
+        return w
+    }
+    w = conditional(http.MethodPatch, etag, `{"grills": true}`)
+    require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    assert.NotEqual(t, etag, w.Header().Get("ETag"))
+    w = conditional(http.MethodPatch, etag, `{"grills": false}`)
+    assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+    assert.Equal(t, "precondition_failed", decodeError(t, w).Code)
+    w = conditional(http.MethodPut, etag, `{"name": "stale"}`)
+    assert.Equal(t, http.StatusPreconditionFailed, w.Code)
+    trail, err = s.Get(context.Background(), 7)
+    require.NoError(t, err)
+    assert.True(t, trail.Grills)
+    assert.Equal(t, "heil valley ranch", trail.Name)
+
+    // PUT replaces every field
+    w = serve(mux, http.MethodPut, "/trails/7", `{"name": "Heil Ranch", "horse_trail": "designated"}`)
+    require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    trail, err = s.Get(context.Background(), 7)
+    require.NoError(t, err)
+    assert.Equal(t, "heil ranch", trail.Name)
+    assert.Equal(t, models.HorseTrailDesignated, trail.HorseTrail)
+    assert.False(t, trail.Restrooms)
+
+    // DELETE removes it, and later requests for it are not found
+    w = serve(mux, http.MethodDelete, "/trails/7", "")
+    assert.Equal(t, http.StatusNoContent, w.Code)
+    for _, method := range []string{http.MethodGet, http.MethodDelete} {
+        w = serve(mux, method, "/trails/7", "")
+        assert.Equal(t, http.StatusNotFound, w.Code, method)
+        assert.Equal(t, "not_found", decodeError(t, w).Code)
+    }
+    w = serve(mux, http.MethodPut, "/trails/7", `{"name": "missing"}`)
+    assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestTrailCRUDValidation(t *testing.T) {
+    s := setupTestStore(t)
+    require.NoError(t, s.Upsert(context.Background(), mockTrail()))
+    mux := trailMux(NewTrailHandler(s))
+
+    tests := []struct {
+        name, method, target, body string
+        status                     int
+        details                    []models.FieldError
+    }{
+        {"malformed JSON", http.MethodPost, "/trails", `{"fid": 2,`, http.StatusBadRequest, nil},
+        {"not an object", http.MethodPost, "/trails", `[1]`, http.StatusBadRequest, nil},
+        {"invalid fid in path", http.MethodGet, "/trails/abc", "", http.StatusBadRequest, nil},
+        {"missing fid", http.MethodPost, "/trails", `{"name": "no fid"}`, http.StatusUnprocessableEntity,
+            []models.FieldError{{Field: "fid", Message: "is required"}}},
+        {"missing fid and name", http.MethodPost, "/trails", `{"name": "", "dog_tube": -1}`, http.StatusUnprocessableEntity,
+            []models.FieldError{
+                {Field: "fid", Message: "is required"},
+                {Field: "name", Message: "is required"},
+                {Field: "dog_tube", Message: "must not be negative"},
+            }},
+        {"unknown field", http.MethodPost, "/trails", `{"fid": 2, "name": "a", "bike": true}`, http.StatusUnprocessableEntity,
+            []models.FieldError{{Field: "bike", Message: "is not a trail field"}}},
+        {"wrong types", http.MethodPost, "/trails", `{"fid": 2, "name": "a", "fee": "yes", "park_spaces": "many"}`, http.StatusUnprocessableEntity,
+            []models.FieldError{{Field: "fee", Message: "must be a boolean"}, {Field: "park_spaces", Message: "must be an integer or null"}}},
+        {"schema constraints", http.MethodPost, "/trails", `{"fid": 2, "horse_trail": "sometimes", "dog_tube": -1}`, http.StatusUnprocessableEntity,
+            []models.FieldError{
+                {Field: "name", Message: "is required"},
+                {Field: "horse_trail", Message: "must be one of possible, not_recommended, designated, not_allowed, pull_through, na"},
+                {Field: "dog_tube", Message: "must not be negative"},
+            }},
+        {"fid mismatch", http.MethodPut, "/trails/1", `{"fid": 2, "name": "a"}`, http.StatusUnprocessableEntity,
+            []models.FieldError{{Field: "fid", Message: "must match the fid 1 in the path"}}},
+        {"patch breaks a constraint", http.MethodPatch, "/trails/1", `{"name": null}`, http.StatusUnprocessableEntity,
+            []models.FieldError{{Field: "name", Message: "is required"}}},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            w := serve(mux, tt.method, tt.target, tt.body)
+            assert.Equal(t, tt.status, w.Code, w.Body.String())
+            assert.Equal(t, "application/json", w.Header().Get("Content-Type"))
+            assert.Equal(t, tt.details, decodeError(t, w).Details)
+        })
+    }
+
+    // Rejected requests leave the stored trail untouched
+    trail, err := s.Get(context.Background(), 1)
+    require.NoError(t, err)
+    assert.Equal(t, "Test Trail", trail.Name)
+    count, err := s.Count(context.Background(), []store.Filter{})
+    require.NoError(t, err)
+    assert.Equal(t, 1, count)
+}
+
+func TestErrorEnvelope(t *testing.T) {
+    mux := trailMux(NewTrailHandler(setupTestStore(t)))
+
+    w := serve(mux, http.MethodGet, "/trails?bike=yes", "")
+    assert.Equal(t, http.StatusBadRequest, w.Code)
+    detail := decodeError(t, w)
+    assert.Equal(t, "bad_request", detail.Code)
+    assert.Equal(t, "unknown query parameters: bike", detail.Message)
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+    "encoding/csv"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "mime"
+    "net/http"
+    "sort"
+    "strconv"
+    "strings"
+    "time"
+    "trail-finder/models"
+)
+
+// errNotAcceptable is returned when the Accept header names none of the formats trails can be listed in
+var errNotAcceptable = errors.New("not acceptable")
+
+// exportFormat is a representation of a trails listing other than the paged JSON response. Exports hold every
+// matching trail, written one at a time as the store reads them.
+type exportFormat struct {
+    name        string // Value of the format parameter
+    contentType string
+    newWriter   func(w io.Writer, fields []string, distances bool) trailWriter // fields is nil for every column
+}
+
+// trailWriter writes the trails of an export. Close finishes the document once every trail was written.
+type trailWriter interface {
+    Write(trail *models.Trail) error
+    Close() error
+}
+
+// exportFormats are the export formats by media type
+var exportFormats = map[string]*exportFormat{
+    "application/geo+json": {name: "geojson", contentType: "application/geo+json", newWriter: newGeoJSONWriter},
+    "text/csv":             {name: "csv", contentType: "text/csv; charset=utf-8", newWriter: newCSVWriter},
+    "application/x-ndjson": {name: "ndjson", contentType: "application/x-ndjson", newWriter: newNDJSONWriter},
+}
+
+// parseFormat returns the export format requested with the format parameter or, without it, the Accept header.
+// A nil format means the paged JSON response, which is also what a missing or wildcard Accept header gets.
+// When several acceptable types are listed, the one with the highest quality wins, ties going to the first.
+func parseFormat(r *http.Request) (*exportFormat, error) {
+    if name := r.URL.Query().Get("format"); name != "" {
+        if name == "json" {
+            return nil, nil
+        }
+        for _, format := range exportFormats {
+            if format.name == name {
+                return format, nil
+            }
+        }
+        return nil, fmt.Errorf("invalid format %q: must be json, geojson, csv or ndjson", name)
+    }
+
+    accept := r.Header.Get("Accept")
+    if strings.TrimSpace(accept) == "" {
+        return nil, nil
+    }
+    type mediaRange struct {
+        mediaType string
+        quality   float64
+    }
+    var ranges []mediaRange
+    for _, part := range strings.Split(accept, ",") {
+        mediaType, params, err := mime.ParseMediaType(part)
+        if err != nil {
+            continue
+        }
+        quality := 1.0
+        if q, ok := params["q"]; ok {
+            if quality, err = strconv.ParseFloat(q, 64); err != nil {
+                continue
+            }
+        }
+        if quality > 0 {
+            ranges = append(ranges, mediaRange{mediaType, quality})
+        }
+    }
+    sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].quality > ranges[j].quality })
+
+    for _, accepted := range ranges {
+        switch accepted.mediaType {
+        case "application/json", "application/*", "*/*":
+            return nil, nil
+        case "text/*":
+            return exportFormats["text/csv"], nil
+        }
+        if format, ok := exportFormats[accepted.mediaType]; ok {
+            return format, nil
+        }
+    }
+    return nil, fmt.Errorf("%w: trails are available as application/json, application/geo+json, text/csv or application/x-ndjson", errNotAcceptable)
+}
+
+// exportColumns returns the columns of a CSV export: the requested fields or every column, followed by the
+// distance from the near point when one was given
+func exportColumns(fields []string, distances bool) []string {
+    columns := fields
+    if columns == nil {
+        columns = models.TrailColumns
+    }
+    if distances {
+        columns = append(columns[:len(columns):len(columns)], "distance_km")
+    }
+    return columns
+}
+
+// exportProperties returns the JSON form of a trail in an export, like a result of the paged response:
+// the whole trail, or only the requested fields, with its distance when one was measured
+func exportProperties(trail *models.Trail, fields []string) interface{} {
+    if fields == nil {
+        return trail
+    }
+    return trail.Project(fields)
+}
+
+// ndjsonWriter writes one JSON trail per line
+type ndjsonWriter struct {
+    encoder *json.Encoder
+    fields  []string
+}
+
+func newNDJSONWriter(w io.Writer, fields []string, distances bool) trailWriter {
+    return &ndjsonWriter{encoder: json.NewEncoder(w), fields: fields}
+}
+
+func (n *ndjsonWriter) Write(trail *models.Trail) error {
+    return n.encoder.Encode(exportProperties(trail, n.fields))
+}
+
+func (n *ndjsonWriter) Close() error {
+    return nil
+}
+
+// csvWriter writes a header row of column names, then one row per trail. Unset values are left blank,
+// booleans are true or false, and dates are RFC 3339 timestamps.
+type csvWriter struct {
+    writer  *csv.Writer
+    columns []string
+    header  bool // Whether the header row was written
+}
+
+func newCSVWriter(w io.Writer, fields []string, distances bool) trailWriter {
+    return &csvWriter{writer: csv.NewWriter(w), columns: exportColumns(fields, distances)}
+}
+
+func (c *csvWriter) Write(trail *models.Trail) error {
+    if err := c.writeHeader(); err != nil {
+        return err
+    }
+    row := make([]string, len(c.columns))
+    for i, column := range c.columns {
+        if column == "distance_km" {
+            if trail.Distance != nil {
+                row[i] = csvValue(*trail.Distance)
+            }
+            continue
+        }
+        value, _ := trail.FieldValue(column)
+        row[i] = csvValue(value)
+    }
+    return c.writer.Write(row)
+}
+
+func (c *csvWriter) Close() error {
+    if err := c.writeHeader(); err != nil {
+        return err
+    }
+    c.writer.Flush()
+    return c.writer.Error()
+}
+
+// writeHeader writes the header row before the first trail, or alone when there are none
+func (c *csvWriter) writeHeader() error {
+    if c.header {
+        return nil
+    }
+    c.header = true
+    return c.writer.Write(c.columns)
+}
+
+// csvValue renders a column value as CSV text
+func csvValue(value interface{}) string {
+    switch v := value.(type) {
+    case nil:
+        return ""
+    case bool:
+        return strconv.FormatBool(v)
+    case int:
+        return strconv.Itoa(v)
+    case float64:
+        return strconv.FormatFloat(v, 'f', -1, 64)
+    case time.Time:
+        return v.Format(time.RFC3339)
+    default:
+        return fmt.Sprint(v)
+    }
+}
+
+// geoJSONWriter writes a FeatureCollection with a feature for each trail, written as the trails arrive.
+// Features are identified by FID and have a Point geometry, or none for trails without coordinates.
+type geoJSONWriter struct {
+    w       io.Writer
+    fields  []string
+    written int // Features written so far
+}
+
+// exportFeature is a feature of an exported collection
+type exportFeature struct {
+    Type       string       `json:"type"`
+    ID         int          `json:"id"`
+    Geometry   *exportPoint `json:"geometry"`
+    Properties interface{}  `json:"properties"`
+}
+
+// exportPoint is the Point geometry of a trail, with its position as [longitude, latitude]
+type exportPoint struct {
+    Type        string     `json:"type"`
+    Coordinates [2]float64 `json:"coordinates"`
+}
+
+func newGeoJSONWriter(w io.Writer, fields []string, distances bool) trailWriter {
+    return &geoJSONWriter{w: w, fields: fields}
+}
+
+func (g *geoJSONWriter) Write(trail *models.Trail) error {
+    feature := exportFeature{Type: "Feature", ID: trail.FID, Properties: exportProperties(trail, g.fields)}
+    if trail.Latitude != nil && trail.Longitude != nil {
+        feature.Geometry = &exportPoint{Type: "Point", Coordinates: [2]float64{*trail.Longitude, *trail.Latitude}}
+    }
+    data, err := json.Marshal(feature)
+    if err != nil {
+        return err
+    }
+
+    prefix := ",\n"
+    if g.written == 0 {
+        prefix = `{"type":"FeatureCollection","features":[` + "\n"
+    }
+    g.written++
+    _, err = io.WriteString(g.w, prefix+string(data))
+    return err
+}
+
+func (g *geoJSONWriter) Close() error {
+    suffix := "\n]}\n"
+    if g.written == 0 {
+        suffix = `{"type":"FeatureCollection","features":[]}` + "\n"
+    }
+    _, err := io.WriteString(g.w, suffix)
+    return err
+}
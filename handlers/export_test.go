@@ -0,0 +1,153 @@
+package handlers
+
+import (
+    "bufio"
+    "context"
+    "encoding/csv"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "strings"
+    "testing"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestParseFormat(t *testing.T) {
+    for _, tc := range []struct {
+        target, accept string
+        want           string // Name of the export format, json for the paged response or error
+    }{
+        {"/trails", "", "json"},
+        {"/trails", "*/*", "json"},
+        {"/trails", "text/csv", "csv"},
+        {"/trails", "application/geo+json, application/json;q=0.9", "geojson"},
+        {"/trails", "application/json;q=0.5, application/x-ndjson", "ndjson"},
+        {"/trails", "text/html, text/*;q=0.8", "csv"},
+        {"/trails", "text/csv;q=0, */*", "json"},
+        {"/trails", "image/png", "error"},
+        {"/trails?format=geojson", "text/csv", "geojson"},
+        {"/trails?format=json", "text/csv", "json"},
+        {"/trails?format=xml", "", "error"},
+    } {
+        req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+        req.Header.Set("Accept", tc.accept)
+
+        format, err := parseFormat(req)
+        switch {
+        case tc.want == "error":
+            assert.Error(t, err, "%s with Accept %q", tc.target, tc.accept)
+        case tc.want == "json":
+            assert.NoError(t, err)
+            assert.Nil(t, format, "%s with Accept %q", tc.target, tc.accept)
+        default:
+            require.NoError(t, err)
+            if assert.NotNil(t, format, "%s with Accept %q", tc.target, tc.accept) {
+                assert.Equal(t, tc.want, format.name, "%s with Accept %q", tc.target, tc.accept)
+            }
+        }
+    }
+}
+
+// Test GetTrails exports every matching trail in the negotiated format
+func TestGetTrailsExport(t *testing.T) {
+    s := setupTestStore(t)
+    h := NewTrailHandler(s)
+
+    lat, lon := 39.9986, -105.2816
+    for fid, name := range []string{"chautauqua", "mesa", "sanitas"} {
+        trail := mockTrail()
+        trail.FID, trail.Name = fid+1, name
+        if fid == 0 {
+            trail.Latitude, trail.Longitude = &lat, &lon
+        }
+        if err := s.Upsert(context.Background(), trail); err != nil {
+            t.Fatalf("Failed to insert mock data: %v", err)
+        }
+    }
+
+    get := func(target, accept string) *httptest.ResponseRecorder {
+        req := httptest.NewRequest(http.MethodGet, target, nil)
+        req.Header.Set("Accept", accept)
+        w := httptest.NewRecorder()
+        h.GetTrails(w, req)
+        return w
+    }
+
+    // CSV has a header row of the requested columns, with the distance when near is given
+    w := get("/trails?fields=fid,name,dog_tube&near=40.015,-105.2705&sort=distance", "text/csv")
+    assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    assert.Equal(t, "text/csv; charset=utf-8", w.Header().Get("Content-Type"))
+    assert.Equal(t, "Accept", w.Header().Get("Vary"))
+    rows, err := csv.NewReader(w.Body).ReadAll()
+    require.NoError(t, err)
+    require.Len(t, rows, 4)
+    assert.Equal(t, []string{"fid", "name", "dog_tube", "distance_km"}, rows[0])
+    assert.Equal(t, []string{"1", "chautauqua", "1"}, rows[1][:3])
+    assert.NotEmpty(t, rows[1][3])
+    assert.Equal(t, []string{"2", "mesa", "1", ""}, rows[2])
+
+    // NDJSON has one trail per line, like the results of the JSON response
+    w = get("/trails?format=ndjson&restrooms=yes", "")
+    assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    assert.Equal(t, "application/x-ndjson", w.Header().Get("Content-Type"))
+    var names []string
+    scanner := bufio.NewScanner(w.Body)
+    for scanner.Scan() {
+        var trail models.Trail
+        require.NoError(t, json.Unmarshal(scanner.Bytes(), &trail))
+        names = append(names, trail.Name)
+    }
+    assert.Equal(t, []string{"chautauqua", "mesa", "sanitas"}, names)
+
+    // GeoJSON has a feature per trail, with a Point geometry when the trail has coordinates
+    w = get("/trails?fields=name", "application/geo+json")
+    assert.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    assert.Equal(t, "application/geo+json", w.Header().Get("Content-Type"))
+    var collection struct {
+        Type     string `json:"type"`
+        Features []struct {
+            Type     string `json:"type"`
+            ID       int    `json:"id"`
+            Geometry *struct {
+                Type        string    `json:"type"`
+                Coordinates []float64 `json:"coordinates"`
+            } `json:"geometry"`
+            Properties map[string]interface{} `json:"properties"`
+        } `json:"features"`
+    }
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &collection))
+    assert.Equal(t, "FeatureCollection", collection.Type)
+    require.Len(t, collection.Features, 3)
+    first := collection.Features[0]
+    assert.Equal(t, 1, first.ID)
+    if assert.NotNil(t, first.Geometry) {
+        assert.Equal(t, "Point", first.Geometry.Type)
+        assert.Equal(t, []float64{lon, lat}, first.Geometry.Coordinates)
+    }
+    assert.Equal(t, map[string]interface{}{"name": "chautauqua"}, first.Properties)
+    assert.Nil(t, collection.Features[1].Geometry)
+
+    // Empty exports are still complete documents
+    w = get("/trails?format=geojson&fid=99", "")
+    assert.Equal(t, http.StatusOK, w.Code)
+    assert.JSONEq(t, `{"type": "FeatureCollection", "features": []}`, w.Body.String())
+    w = get("/trails?format=csv&fid=99&fields=fid,name", "")
+    assert.Equal(t, "fid,name\n", w.Body.String())
+
+    // Exports are not paged, and unknown formats and versions are rejected
+    for target, status := range map[string]int{
+        "/trails?format=csv&limit=5":     http.StatusBadRequest,
+        "/trails?format=ndjson&q=mesa":   http.StatusBadRequest,
+        "/trails?format=xml":             http.StatusBadRequest,
+        "/trails?format=geojson&as_of=9": http.StatusNotFound,
+    } {
+        w := get(target, "")
+        assert.Equal(t, status, w.Code, target)
+        assert.True(t, strings.HasPrefix(w.Header().Get("Content-Type"), "application/json"), target)
+    }
+    w = get("/trails", "image/png")
+    assert.Equal(t, http.StatusNotAcceptable, w.Code)
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+    "fmt"
+    "net/http"
+    "net/url"
+    "trail-finder/models"
+
+    "github.com/sirupsen/logrus"
+)
+
+// identifyingFields are the columns that identify, describe or locate a single trailhead. Nearly every trail has
+// its own value, so they make no useful drop-down and are only counted when requested with fields.
+var identifyingFields = map[string]bool{
+    "fid":               true,
+    "name":              true,
+    "aka":               true,
+    "address":           true,
+    "access_id":         true,
+    "ada_facility_name": true,
+    "latitude":          true,
+    "longitude":         true,
+}
+
+// defaultFacetFields are the columns counted when no fields parameter is given, in column order
+var defaultFacetFields = func() []string {
+    var fields []string
+    for _, column := range models.TrailColumns {
+        if !identifyingFields[column] {
+            fields = append(fields, column)
+        }
+    }
+    return fields
+}()
+
+// facetUnsupportedParams are the listing parameters that do not apply to facet counts
+var facetUnsupportedParams = []string{"page", "limit", "cursor", "q", "sort", "as_of", "format"}
+
+// GetFacets handles GET /trails/facets, returning the distinct values of each column and how many of the
+// matching trails have each. It accepts the same filters as GetTrails, so counts narrow down with them;
+// fields selects the columns to count, and defaults to every column except the identifying ones.
+func (h *TrailHandler) GetFacets(w http.ResponseWriter, r *http.Request) {
+    query := r.URL.Query()
+    if err := rejectFacetParams(query); err != nil {
+        logrus.Warnf("Invalid facet request: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    filters, err := ParseFilters(query)
+    if err != nil {
+        logrus.Warnf("Invalid trail filters: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    fields, err := ParseFields(query.Get("fields"))
+    if err != nil {
+        logrus.Warnf("Invalid facet fields: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    if fields == nil {
+        fields = defaultFacetFields
+    }
+
+    facets, err := h.Store.Facets(r.Context(), fields, filters)
+    if err != nil {
+        writeStoreError(w, err, "count trail facets")
+        return
+    }
+    total, err := h.Store.Count(r.Context(), filters)
+    if err != nil {
+        writeStoreError(w, err, "count trails")
+        return
+    }
+
+    writeJSON(w, http.StatusOK, map[string]interface{}{
+        "total":  total,
+        "facets": facets,
+    })
+}
+
+// rejectFacetParams reports the paging, search and ordering parameters, which facets do not support
+func rejectFacetParams(query url.Values) error {
+    for _, param := range facetUnsupportedParams {
+        if _, ok := query[param]; ok {
+            return fmt.Errorf("%s is not supported by /trails/facets", param)
+        }
+    }
+    return nil
+}
@@ -0,0 +1,63 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "testing"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestGetFacets(t *testing.T) {
+    s := setupTestStore(t)
+    moderate := mockTrail()
+    moderate.FID, moderate.Name, moderate.Difficulty, moderate.Restrooms = 2, "Moderate Trail", "moderate", false
+    hard := mockTrail()
+    hard.FID, hard.Name, hard.Difficulty, hard.DogTube = 3, "Hard Trail", "hard", nil
+    for _, trail := range []models.Trail{mockTrail(), moderate, hard} {
+        require.NoError(t, s.Upsert(context.Background(), trail))
+    }
+    mux := trailMux(NewTrailHandler(s))
+
+    type facets struct {
+        Total  int `json:"total"`
+        Facets map[string][]struct {
+            Value interface{} `json:"value"`
+            Count int         `json:"count"`
+        } `json:"facets"`
+    }
+
+    // Every column but the identifying ones is counted by default
+    w := serve(mux, http.MethodGet, "/trails/facets", "")
+    require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    var all facets
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &all))
+    assert.Equal(t, 3, all.Total)
+    assert.Len(t, all.Facets, len(defaultFacetFields))
+    assert.NotContains(t, all.Facets, "name")
+    require.Len(t, all.Facets["dog_tube"], 2)
+    assert.Equal(t, float64(1), all.Facets["dog_tube"][0].Value)
+    assert.Equal(t, 2, all.Facets["dog_tube"][0].Count)
+    assert.Nil(t, all.Facets["dog_tube"][1].Value, "expected unset counts to be counted last")
+
+    // Filters narrow the counts, and fields selects the columns
+    w = serve(mux, http.MethodGet, "/trails/facets?restrooms=yes&fields=difficulty,name", "")
+    require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    var narrowed facets
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &narrowed))
+    assert.Equal(t, 2, narrowed.Total)
+    assert.Len(t, narrowed.Facets, 2)
+    require.Len(t, narrowed.Facets["difficulty"], 2)
+    assert.Equal(t, "easy", narrowed.Facets["difficulty"][0].Value)
+    assert.Equal(t, "hard", narrowed.Facets["difficulty"][1].Value)
+
+    // Paging and unknown parameters are rejected
+    for _, target := range []string{"/trails/facets?page=2", "/trails/facets?bike=yes", "/trails/facets?fields=nope"} {
+        w = serve(mux, http.MethodGet, target, "")
+        assert.Equal(t, http.StatusBadRequest, w.Code, target)
+        assert.Equal(t, "bad_request", decodeError(t, w).Code)
+    }
+}
@@ -0,0 +1,263 @@
+package handlers
+
+import (
+    "fmt"
+    "math"
+    "net/url"
+    "sort"
+    "strconv"
+    "strings"
+    "trail-finder/models"
+    "trail-finder/store"
+)
+
+// listingParams are the GetTrails query parameters that control paging, search, ordering, the dataset version,
+// the returned fields and their format rather than filter trails
+var listingParams = map[string]bool{
+    "as_of":  true,
+    "page":   true,
+    "limit":  true,
+    "cursor": true,
+    "q":      true,
+    "sort":   true,
+    "fields": true,
+    "format": true,
+}
+
+// nearParams are the GetTrails query parameters of a near query: the latitude,longitude point distances are
+// measured from and the radius in km that trails must be within
+var nearParams = map[string]bool{
+    "near":      true,
+    "radius_km": true,
+}
+
+// orParam is the query parameter holding an OR group of conditions, separated by orSeparator
+const (
+    orParam     = "or"
+    orSeparator = "|"
+)
+
+// filterableFields is the whitelist of query parameters that filter trails: every trails column,
+// mapped to its position so filters are built in column order
+var filterableFields = func() map[string]int {
+    fields := make(map[string]int, len(models.TrailColumns))
+    for i, column := range models.TrailColumns {
+        fields[column] = i
+    }
+    return fields
+}()
+
+// bracketOperators are the operators accepted as field[op]=value
+var bracketOperators = map[string]store.Op{
+    "eq":  store.OpEq,
+    "ne":  store.OpNe,
+    "in":  store.OpIn,
+    "nin": store.OpNotIn,
+    "gt":  store.OpGt,
+    "gte": store.OpGte,
+    "lt":  store.OpLt,
+    "lte": store.OpLte,
+}
+
+// ParseFilters converts the query parameters of a trails listing into store filters.
+// Every trails column can be filtered on; values are normalized to the column's type, and blank values are ignored.
+//
+//   - field=a matches a value; field=a,b matches any of the listed values
+//   - field!=a or field[ne]=a excludes values; field[in]=a,b and field[nin]=a,b are explicit list forms
+//   - field[gt|gte|lt|lte]=n compares counts and dates
+//   - or=fieldA=a|fieldB!=b matches when any of the conditions match; each or parameter is one group
+//   - near=lat,lon&radius_km=r matches the trails within r km of the point
+//
+// Parameters that are neither columns nor paging parameters are rejected, and all of them are named in the error.
+func ParseFilters(query url.Values) ([]store.Filter, error) {
+    var unknown []string
+    for param := range query {
+        if param == orParam || listingParams[param] || nearParams[param] {
+            continue
+        }
+        if _, _, err := parseFilterKey(param); err != nil {
+            unknown = append(unknown, param)
+        }
+    }
+    if len(unknown) > 0 {
+        sort.Strings(unknown)
+        return nil, fmt.Errorf("unknown query parameters: %s", strings.Join(unknown, ", "))
+    }
+
+    // Build the conditions in column order, so the same query always produces the same filters
+    params := make([]string, 0, len(query))
+    for param := range query {
+        if param != orParam && !listingParams[param] && !nearParams[param] {
+            params = append(params, param)
+        }
+    }
+    sort.Slice(params, func(i, j int) bool {
+        fi, _, _ := parseFilterKey(params[i])
+        fj, _, _ := parseFilterKey(params[j])
+        if fi != fj {
+            return filterableFields[fi] < filterableFields[fj]
+        }
+        return params[i] < params[j]
+    })
+
+    filters := []store.Filter{}
+    for _, param := range params {
+        values := query[param]
+        if len(values) > 1 {
+            return nil, fmt.Errorf("%s may only be given once", param)
+        }
+        filter, ok, err := parseCondition(param, values[0])
+        if err != nil {
+            return nil, err
+        }
+        if ok {
+            filters = append(filters, filter)
+        }
+    }
+
+    near, err := parseNearFilter(query)
+    if err != nil {
+        return nil, err
+    }
+    if near != nil {
+        filters = append(filters, *near)
+    }
+
+    for _, group := range query[orParam] {
+        filter, err := parseOrGroup(group)
+        if err != nil {
+            return nil, err
+        }
+        filters = append(filters, filter)
+    }
+    return filters, nil
+}
+
+// parseNear reads the near parameter, the latitude,longitude point a listing measures distances from.
+// It returns nil when the parameter is not given.
+func parseNear(query url.Values) (*models.Point, error) {
+    raw := query.Get("near")
+    if raw == "" {
+        return nil, nil
+    }
+    rawLat, rawLon, found := strings.Cut(raw, ",")
+    if !found {
+        return nil, fmt.Errorf("invalid near %q: expected latitude,longitude", raw)
+    }
+    lat, err := models.ParseCoordinate("latitude", rawLat)
+    if err != nil {
+        return nil, fmt.Errorf("invalid near latitude: %w", err)
+    }
+    lon, err := models.ParseCoordinate("longitude", rawLon)
+    if err != nil {
+        return nil, fmt.Errorf("invalid near longitude: %w", err)
+    }
+    return &models.Point{Latitude: lat, Longitude: lon}, nil
+}
+
+// parseNearFilter builds the filter of a near query with a radius. Without radius_km, near only measures
+// distances and does not filter, so the filter is nil.
+func parseNearFilter(query url.Values) (*store.Filter, error) {
+    point, err := parseNear(query)
+    if err != nil {
+        return nil, err
+    }
+    raw := query.Get("radius_km")
+    if raw == "" {
+        return nil, nil
+    }
+    if point == nil {
+        return nil, fmt.Errorf("radius_km needs near=latitude,longitude")
+    }
+    radius, err := strconv.ParseFloat(strings.TrimSpace(raw), 64)
+    if err != nil || !(radius > 0) || math.IsInf(radius, 0) {
+        return nil, fmt.Errorf("invalid radius_km %q: must be a positive number", raw)
+    }
+    return &store.Filter{Near: &store.Near{Point: *point, RadiusKm: radius}}, nil
+}
+
+// parseOrGroup converts one or parameter, such as bike_trail=yes|horse_trail=designated, into an OR group
+func parseOrGroup(group string) (store.Filter, error) {
+    var alternatives []store.Filter
+    for _, part := range strings.Split(group, orSeparator) {
+        key, raw, found := strings.Cut(part, "=")
+        if !found {
+            return store.Filter{}, fmt.Errorf("invalid or condition %q: expected field=value", part)
+        }
+        if _, _, err := parseFilterKey(key); err != nil {
+            return store.Filter{}, fmt.Errorf("invalid or condition %q: %w", part, err)
+        }
+        filter, ok, err := parseCondition(key, raw)
+        if err != nil {
+            return store.Filter{}, err
+        }
+        if !ok {
+            return store.Filter{}, fmt.Errorf("invalid or condition %q: a value is required", part)
+        }
+        alternatives = append(alternatives, filter)
+    }
+    return store.Filter{Any: alternatives}, nil
+}
+
+// parseCondition builds the filter for a validated field key and its raw value.
+// It reports false for a blank value, which does not filter.
+func parseCondition(key, raw string) (store.Filter, bool, error) {
+    field, op, _ := parseFilterKey(key)
+    if strings.TrimSpace(raw) == "" {
+        return store.Filter{}, false, nil
+    }
+
+    // Comma-separated values turn equality into a list match
+    parts := strings.Split(raw, ",")
+    switch op {
+    case store.OpEq:
+        if len(parts) > 1 {
+            op = store.OpIn
+        }
+    case store.OpNe:
+        if len(parts) > 1 {
+            op = store.OpNotIn
+        }
+    case store.OpIn, store.OpNotIn:
+    default:
+        kind := models.FieldKinds[field]
+        if kind != models.KindCount && kind != models.KindDate && kind != models.KindCoordinate {
+            return store.Filter{}, false, fmt.Errorf("%s cannot be used with %s: ranges apply to counts, dates and coordinates", op, field)
+        }
+        parts = []string{raw}
+    }
+
+    values := make([]interface{}, 0, len(parts))
+    for _, part := range parts {
+        value, err := models.NormalizeValue(field, part)
+        if err != nil {
+            return store.Filter{}, false, fmt.Errorf("invalid value for %s: %w", field, err)
+        }
+        values = append(values, value)
+    }
+
+    if op == store.OpIn || op == store.OpNotIn {
+        return store.Filter{Field: field, Op: op, Values: values}, true, nil
+    }
+    return store.Filter{Field: field, Op: op, Value: values[0]}, true, nil
+}
+
+// parseFilterKey splits a filter parameter name into its column and operator:
+// field, field! (as sent by field!=value) and field[op]
+func parseFilterKey(key string) (string, store.Op, error) {
+    field, op := key, store.OpEq
+    if strings.HasSuffix(key, "!") {
+        field, op = strings.TrimSuffix(key, "!"), store.OpNe
+    } else if open := strings.Index(key, "["); open >= 0 && strings.HasSuffix(key, "]") {
+        var ok bool
+        if op, ok = bracketOperators[key[open+1:len(key)-1]]; !ok {
+            return "", "", fmt.Errorf("unknown operator in %q", key)
+        }
+        field = key[:open]
+    }
+
+    if _, ok := filterableFields[field]; !ok {
+        return "", "", fmt.Errorf("unknown field %q", field)
+    }
+    return field, op, nil
+}
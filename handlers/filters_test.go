@@ -0,0 +1,125 @@
+package handlers
+
+import (
+    "net/url"
+    "testing"
+    "trail-finder/models"
+    "trail-finder/store"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestParseFiltersAcceptsEveryColumn(t *testing.T) {
+    query, err := url.ParseQuery("fee=Yes&recycle_bin=no&dog_tube=2&difficulty=Easy&horse_trail=Not+Recommended&page=2&limit=5")
+    require.NoError(t, err)
+
+    filters, err := ParseFilters(query)
+    require.NoError(t, err)
+    assert.Equal(t, []store.Filter{
+        {Field: "difficulty", Op: store.OpEq, Value: "easy"},
+        {Field: "horse_trail", Op: store.OpEq, Value: models.HorseTrailNotRecommended},
+        {Field: "fee", Op: store.OpEq, Value: true},
+        {Field: "recycle_bin", Op: store.OpEq, Value: false},
+        {Field: "dog_tube", Op: store.OpEq, Value: 2},
+    }, filters)
+}
+
+func TestParseFiltersRejectsUnknownParameters(t *testing.T) {
+    query, err := url.ParseQuery("restrooms=yes&bike=yes&colour=red")
+    require.NoError(t, err)
+
+    _, err = ParseFilters(query)
+    require.Error(t, err)
+    assert.Equal(t, "unknown query parameters: bike, colour", err.Error())
+}
+
+func TestParseFiltersIgnoresBlankValues(t *testing.T) {
+    filters, err := ParseFilters(url.Values{"fishing": {""}, "type": {" "}})
+    require.NoError(t, err)
+    assert.Empty(t, filters)
+}
+
+func TestParseFiltersRejectsRepeatedParameters(t *testing.T) {
+    _, err := ParseFilters(url.Values{"fee": {"yes", "no"}})
+    assert.Error(t, err)
+}
+
+func TestParseFiltersOperators(t *testing.T) {
+    query, err := url.ParseQuery("difficulty=easy,moderate&fee!=yes&grills[ne]=no&park_spaces[gte]=10&park_spaces[lt]=50&access_type[nin]=TH")
+    require.NoError(t, err)
+
+    filters, err := ParseFilters(query)
+    require.NoError(t, err)
+    assert.Equal(t, []store.Filter{
+        {Field: "difficulty", Op: store.OpIn, Values: []interface{}{"easy", "moderate"}},
+        {Field: "access_type", Op: store.OpNotIn, Values: []interface{}{"th"}},
+        {Field: "fee", Op: store.OpNe, Value: true},
+        {Field: "grills", Op: store.OpNe, Value: false},
+        {Field: "park_spaces", Op: store.OpGte, Value: 10},
+        {Field: "park_spaces", Op: store.OpLt, Value: 50},
+    }, filters)
+}
+
+func TestParseFiltersOrGroups(t *testing.T) {
+    query := url.Values{"or": {"bike_trail=yes|horse_trail=designated,possible", "fee!=yes|trash_cans[gt]=2"}}
+
+    filters, err := ParseFilters(query)
+    require.NoError(t, err)
+    assert.Equal(t, []store.Filter{
+        {Any: []store.Filter{
+            {Field: "bike_trail", Op: store.OpEq, Value: true},
+            {Field: "horse_trail", Op: store.OpIn, Values: []interface{}{models.HorseTrailDesignated, models.HorseTrailPossible}},
+        }},
+        {Any: []store.Filter{
+            {Field: "fee", Op: store.OpNe, Value: true},
+            {Field: "trash_cans", Op: store.OpGt, Value: 2},
+        }},
+    }, filters)
+}
+
+func TestParseFiltersRejectsInvalidOperators(t *testing.T) {
+    for _, raw := range []string{
+        "fee[like]=yes",            // unknown operator
+        "name[gt]=a",               // ranges only apply to counts and dates
+        "park_spaces[gte]=many",    // range values are normalized too
+        "or=bike_trail",            // conditions need a value
+        "or=bike_trail=yes|nope=1", // or conditions use the same whitelist
+    } {
+        query, err := url.ParseQuery(raw)
+        require.NoError(t, err)
+
+        _, err = ParseFilters(query)
+        assert.Error(t, err, raw)
+    }
+}
+
+func TestParseFiltersNear(t *testing.T) {
+    query := url.Values{"near": {"40.015, -105.2705"}, "radius_km": {"2.5"}, "fee": {"no"}}
+
+    filters, err := ParseFilters(query)
+    require.NoError(t, err)
+    assert.Equal(t, []store.Filter{
+        {Field: "fee", Op: store.OpEq, Value: false},
+        {Near: &store.Near{Point: models.Point{Latitude: 40.015, Longitude: -105.2705}, RadiusKm: 2.5}},
+    }, filters)
+
+    filters, err = ParseFilters(url.Values{"near": {"40,-105"}})
+    require.NoError(t, err)
+    assert.Empty(t, filters, "near without radius_km only measures distances")
+
+    for _, raw := range []string{
+        "near=40",                    // needs a latitude and longitude
+        "near=-105.27,40.01",         // latitude out of range
+        "near=north,west",            // not numbers
+        "radius_km=5",                // radius needs a point
+        "near=40,-105&radius_km=0",   // radius must be positive
+        "near=40,-105&radius_km=NaN", // and finite
+    } {
+        query, err := url.ParseQuery(raw)
+        require.NoError(t, err)
+
+        _, err = ParseFilters(query)
+        assert.Error(t, err, raw)
+    }
+}
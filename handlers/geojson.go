@@ -0,0 +1,233 @@
+package handlers
+
+import (
+    "bufio"
+    "bytes"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "io"
+    "sort"
+    "trail-finder/models"
+
+    "github.com/sirupsen/logrus"
+)
+
+// ErrInvalidGeoJSON is returned when GeoJSON cannot be parsed or is not a FeatureCollection
+var ErrInvalidGeoJSON = errors.New("invalid GeoJSON")
+
+// isGeoJSON reports whether the input starts with a JSON object rather than a CSV header
+func isGeoJSON(r *bufio.Reader) bool {
+    peeked, _ := r.Peek(512)
+    trimmed := bytes.TrimLeft(peeked, " \t\r\n")
+    return len(trimmed) > 0 && trimmed[0] == '{'
+}
+
+// geoJSONRows reads the features of a GeoJSON FeatureCollection one at a time, so large collections are
+// streamed like CSV. The properties of each feature are its header and values, resolved through the column
+// mapping, and a Point geometry gives the trail's coordinates.
+type geoJSONRows struct {
+    decoder  *json.Decoder
+    mapping  *models.ColumnMapping
+    source   string
+    typeName string // The collection's type member, when it came before its features
+    count    int    // Features read so far
+}
+
+// geoJSONFeature is a feature of the collection, with the members used by the loader
+type geoJSONFeature struct {
+    Type       string                 `json:"type"`
+    Properties map[string]interface{} `json:"properties"`
+    Geometry   *struct {
+        Type        string          `json:"type"`
+        Coordinates json.RawMessage `json:"coordinates"`
+    } `json:"geometry"`
+}
+
+// newGeoJSONRows reads the collection up to the start of its features array
+func newGeoJSONRows(r io.Reader, mapping *models.ColumnMapping, source string) (*geoJSONRows, error) {
+    decoder := json.NewDecoder(r)
+    decoder.UseNumber() // Keep property numbers such as FIDs in their source form
+    g := &geoJSONRows{decoder: decoder, mapping: mapping, source: source}
+
+    if err := g.expectDelim('{'); err != nil {
+        return nil, err
+    }
+    for decoder.More() {
+        key, err := g.readKey()
+        if err != nil {
+            return nil, err
+        }
+        if key == "features" {
+            if err := g.expectDelim('['); err != nil {
+                return nil, err
+            }
+            return g, nil
+        }
+        if err := g.readMember(key); err != nil {
+            return nil, err
+        }
+    }
+    return nil, g.invalid("no features array in %s", source)
+}
+
+func (g *geoJSONRows) Read() (sourceRow, error) {
+    if !g.decoder.More() {
+        return sourceRow{}, g.finish()
+    }
+
+    var feature geoJSONFeature
+    if err := g.decoder.Decode(&feature); err != nil {
+        return sourceRow{}, g.readError(err)
+    }
+    g.count++
+    row := sourceRow{line: g.count}
+    if feature.Type != "Feature" {
+        row.rejected = []models.RejectedRow{{Line: g.count, Column: "type", Value: feature.Type, Reason: "is not a Feature"}}
+        return row, nil
+    }
+
+    // The properties become a header row, in a stable order, and the values of a data row
+    for name := range feature.Properties {
+        row.header = append(row.header, name)
+    }
+    sort.Strings(row.header)
+    row.values = make([]string, len(row.header))
+    for i, name := range row.header {
+        row.values[i] = propertyValue(feature.Properties[name])
+    }
+
+    columns, err := g.mapping.Resolve(row.header)
+    if err != nil {
+        row.rejected = []models.RejectedRow{{Line: g.count, Column: "properties", Reason: err.Error()}}
+        return row, nil
+    }
+    row.columns = columns
+
+    if feature.Geometry != nil {
+        point, reason := geoJSONPoint(feature.Geometry.Type, feature.Geometry.Coordinates)
+        if reason != "" {
+            row.rejected = []models.RejectedRow{{Line: g.count, Column: "geometry", Value: feature.Geometry.Type, Reason: reason}}
+            return row, nil
+        }
+        row.point = point
+    }
+    return row, nil
+}
+
+// finish reads the rest of the collection after its features, checking it is a FeatureCollection
+// and that nothing follows it
+func (g *geoJSONRows) finish() error {
+    if err := g.expectDelim(']'); err != nil {
+        return err
+    }
+    for g.decoder.More() {
+        key, err := g.readKey()
+        if err != nil {
+            return err
+        }
+        if err := g.readMember(key); err != nil {
+            return err
+        }
+    }
+    if err := g.expectDelim('}'); err != nil {
+        return err
+    }
+    if g.typeName != "FeatureCollection" {
+        return g.invalid("%s is not a FeatureCollection", g.source)
+    }
+    if _, err := g.decoder.Token(); err != io.EOF {
+        return g.invalid("unexpected data after the FeatureCollection in %s", g.source)
+    }
+    return io.EOF
+}
+
+// readKey reads the name of the next member of an object
+func (g *geoJSONRows) readKey() (string, error) {
+    token, err := g.decoder.Token()
+    if err != nil {
+        return "", g.readError(err)
+    }
+    key, _ := token.(string)
+    return key, nil
+}
+
+// readMember reads the value of a collection member other than its features, keeping its type
+func (g *geoJSONRows) readMember(key string) error {
+    if key != "type" {
+        var skipped json.RawMessage
+        if err := g.decoder.Decode(&skipped); err != nil {
+            return g.readError(err)
+        }
+        return nil
+    }
+    if err := g.decoder.Decode(&g.typeName); err != nil {
+        return g.readError(err)
+    }
+    if g.typeName != "FeatureCollection" {
+        return g.invalid("%s is a %s, not a FeatureCollection", g.source, g.typeName)
+    }
+    return nil
+}
+
+// expectDelim reads the next token, which must be the given delimiter
+func (g *geoJSONRows) expectDelim(want json.Delim) error {
+    token, err := g.decoder.Token()
+    if err != nil {
+        return g.readError(err)
+    }
+    if delim, ok := token.(json.Delim); !ok || delim != want {
+        return g.invalid("expected %q in %s, got %v", want, g.source, token)
+    }
+    return nil
+}
+
+// invalid logs and returns an ErrInvalidGeoJSON with a formatted message
+func (g *geoJSONRows) invalid(format string, args ...interface{}) error {
+    message := fmt.Sprintf(format, args...)
+    logrus.Errorf("Invalid GeoJSON: %s", message)
+    return fmt.Errorf("%w: %s", ErrInvalidGeoJSON, message)
+}
+
+// readError wraps an error from reading GeoJSON, marking malformed JSON as ErrInvalidGeoJSON
+func (g *geoJSONRows) readError(err error) error {
+    var syntaxErr *json.SyntaxError
+    var typeErr *json.UnmarshalTypeError
+    if err == io.EOF || errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &syntaxErr) || errors.As(err, &typeErr) {
+        return g.invalid("could not read %s: %v", g.source, err)
+    }
+    logrus.Errorf("Could not read GeoJSON from %s: %v", g.source, err)
+    return fmt.Errorf("could not read GeoJSON: %w", err)
+}
+
+// propertyValue renders a feature property as the text a CSV would hold, so it is converted like CSV values
+func propertyValue(value interface{}) string {
+    switch v := value.(type) {
+    case nil:
+        return ""
+    case string:
+        return v
+    case json.Number:
+        return v.String()
+    case bool:
+        if v {
+            return "true"
+        }
+        return "false"
+    default:
+        data, _ := json.Marshal(v)
+        return string(data)
+    }
+}
+
+// geoJSONPoint returns the longitude and latitude of a Point geometry, or the reason the geometry is rejected
+func geoJSONPoint(geometryType string, coordinates json.RawMessage) (*[2]float64, string) {
+    if geometryType != "Point" {
+        return nil, "must be a Point"
+    }
+    var position []float64
+    if err := json.Unmarshal(coordinates, &position); err != nil || len(position) < 2 {
+        return nil, "coordinates must be [longitude, latitude]"
+    }
+    return &[2]float64{position[0], position[1]}, ""
+}
@@ -0,0 +1,37 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "time"
+
+    "github.com/sirupsen/logrus"
+)
+
+// poolStatser is implemented by stores that run on a connection pool
+type poolStatser interface {
+    Stats() map[string]int32
+}
+
+// HealthCheck handles GET /healthz, reporting whether the database is reachable and, for pooled stores, the pool usage.
+// It backs the Kubernetes readiness probe, so pods stop receiving traffic while the database is unavailable.
+func (h *TrailHandler) HealthCheck(w http.ResponseWriter, r *http.Request) {
+    w.Header().Set("Content-Type", "application/json")
+
+    ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+    defer cancel()
+
+    response := map[string]interface{}{"status": "ok"}
+    if pooled, ok := h.Store.(poolStatser); ok {
+        response["pool"] = pooled.Stats()
+    }
+
+    if err := h.Store.Ping(ctx); err != nil {
+        logrus.Warnf("Health check failed: %v", err)
+        response["status"] = "unavailable"
+        response["error"] = err.Error()
+        w.WriteHeader(http.StatusServiceUnavailable)
+    }
+    json.NewEncoder(w).Encode(response)
+}
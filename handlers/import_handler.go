@@ -0,0 +1,78 @@
+package handlers
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "trail-finder/models"
+    "trail-finder/store"
+)
+
+// DefaultImportsLimit is the number of import jobs GET /imports returns unless limit is given
+const DefaultImportsLimit = 20
+
+// DefaultRejectedLimit is the number of rejected row entries returned with an import unless rejected_limit is given
+const DefaultRejectedLimit = 100
+
+// MaxRejectedLimit caps the rejected_limit parameter
+const MaxRejectedLimit = 1000
+
+// ListImports handles GET /imports, returning the most recent import jobs, newest first,
+// each with the first page of its rejected rows
+func (h *TrailHandler) ListImports(w http.ResponseWriter, r *http.Request) {
+    limit := DefaultImportsLimit
+    if raw := r.URL.Query().Get("limit"); raw != "" {
+        n, err := strconv.Atoi(raw)
+        if err != nil || n <= 0 {
+            writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q: must be a positive number", raw))
+            return
+        }
+        limit = min(n, MaxPageSize)
+    }
+
+    jobs, err := h.Store.ListImports(r.Context(), limit)
+    if err != nil {
+        writeStoreError(w, err, "list imports")
+        return
+    }
+    pages := make([]models.ImportJobPage, len(jobs))
+    for i, job := range jobs {
+        pages[i] = job.PageRejected(0, DefaultRejectedLimit)
+    }
+    writeJSON(w, http.StatusOK, map[string]interface{}{"results": pages})
+}
+
+// GetImport handles GET /imports/{id}, reporting the state, row counts, rejected rows and timing of an import.
+// Rejected rows are paged with rejected_offset and rejected_limit, and the response reports how many there are.
+func (h *TrailHandler) GetImport(w http.ResponseWriter, r *http.Request) {
+    query := r.URL.Query()
+    offset, limit := 0, DefaultRejectedLimit
+    if raw := query.Get("rejected_offset"); raw != "" {
+        n, err := strconv.Atoi(raw)
+        if err != nil || n < 0 {
+            writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid rejected_offset %q: must be zero or a positive number", raw))
+            return
+        }
+        offset = n
+    }
+    if raw := query.Get("rejected_limit"); raw != "" {
+        n, err := strconv.Atoi(raw)
+        if err != nil || n <= 0 {
+            writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid rejected_limit %q: must be a positive number", raw))
+            return
+        }
+        limit = min(n, MaxRejectedLimit)
+    }
+
+    job, err := h.Store.GetImport(r.Context(), r.PathValue("id"))
+    if errors.Is(err, store.ErrNotFound) {
+        writeError(w, http.StatusNotFound, "Import not found")
+        return
+    }
+    if err != nil {
+        writeStoreError(w, err, "get import")
+        return
+    }
+    writeJSON(w, http.StatusOK, job.PageRejected(offset, limit))
+}
@@ -0,0 +1,181 @@
+package handlers
+
+import (
+    "context"
+    "encoding/json"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestImportEndpoints(t *testing.T) {
+    s := setupTestStore(t)
+    h := NewTrailHandler(s)
+    runImports(t, h)
+
+    mux := http.NewServeMux()
+    mux.HandleFunc("POST /load", h.LoadTrailsFromRequest)
+    mux.HandleFunc("GET /imports", h.ListImports)
+    mux.HandleFunc("GET /imports/{id}", h.GetImport)
+
+    var ids []string
+    for i := 0; i < 2; i++ {
+        req := httptest.NewRequest(http.MethodPost, "/load", strings.NewReader(uploadCSV))
+        req.Header.Set("Content-Type", "text/csv")
+        w := httptest.NewRecorder()
+        mux.ServeHTTP(w, req)
+        require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+        ids = append(ids, waitForImport(t, s, decodeImport(t, w).ID).ID)
+    }
+
+    // GET /imports/{id} reports the finished job
+    w := serve(mux, http.MethodGet, "/imports/"+ids[0], "")
+    require.Equal(t, http.StatusOK, w.Code)
+    job := decodeImport(t, w)
+    assert.Equal(t, models.ImportSucceeded, job.State)
+    assert.Equal(t, 2, job.RowsImported)
+    assert.Equal(t, 1, job.Version)
+
+    // GET /imports lists the newest first, up to limit
+    w = serve(mux, http.MethodGet, "/imports?limit=1", "")
+    require.Equal(t, http.StatusOK, w.Code)
+    var list struct {
+        Results []models.ImportJob `json:"results"`
+    }
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+    require.Len(t, list.Results, 1)
+    assert.Equal(t, ids[1], list.Results[0].ID)
+
+    w = serve(mux, http.MethodGet, "/imports/missing", "")
+    assert.Equal(t, http.StatusNotFound, w.Code)
+    assert.Equal(t, "Import not found", decodeError(t, w).Message)
+
+    w = serve(mux, http.MethodGet, "/imports?limit=zero", "")
+    assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+// Test GET /imports/{id} pages through every rejected row of a report
+func TestGetImportPagesRejectedRows(t *testing.T) {
+    s := setupTestStore(t)
+    h := NewTrailHandler(s)
+    mux := http.NewServeMux()
+    mux.HandleFunc("GET /imports/{id}", h.GetImport)
+
+    job := models.ImportJob{ID: "rejects", State: models.ImportSucceeded, CreatedAt: time.Now().UTC()}
+    for line := 2; line < 252; line++ {
+        job.Reject(models.RejectedRow{Line: line, Column: "FID", Value: "x", Reason: "is not an integer"})
+    }
+    require.NoError(t, s.CreateImport(context.Background(), job))
+
+    get := func(target string) models.ImportJobPage {
+        w := serve(mux, http.MethodGet, target, "")
+        require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+        var page models.ImportJobPage
+        require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+        return page
+    }
+
+    page := get("/imports/rejects")
+    assert.Len(t, page.Rejected, DefaultRejectedLimit)
+    assert.Equal(t, 250, page.RejectedTotal)
+    assert.Equal(t, 250, page.RowsRejected)
+    assert.True(t, page.Truncated)
+
+    page = get("/imports/rejects?rejected_offset=200&rejected_limit=100")
+    require.Len(t, page.Rejected, 50)
+    assert.Equal(t, 202, page.Rejected[0].Line)
+    assert.Equal(t, 200, page.RejectedOffset)
+    assert.True(t, page.Truncated)
+
+    page = get("/imports/rejects?rejected_limit=1000")
+    assert.Len(t, page.Rejected, 250)
+    assert.False(t, page.Truncated)
+
+    for _, target := range []string{"/imports/rejects?rejected_offset=-1", "/imports/rejects?rejected_limit=0"} {
+        w := serve(mux, http.MethodGet, target, "")
+        assert.Equal(t, http.StatusBadRequest, w.Code, target)
+    }
+}
+
+func TestImporterFailsInterruptedJobs(t *testing.T) {
+    s := setupTestStore(t)
+    ctx := context.Background()
+    now := time.Now().UTC()
+    beat := now.Add(-time.Hour)
+    stale := models.ImportJob{ID: "stale", State: models.ImportRunning, Owner: "gone", CreatedAt: now.Add(-2 * time.Hour), HeartbeatAt: &beat}
+    unowned := models.ImportJob{ID: "unowned", State: models.ImportQueued, CreatedAt: now.Add(-time.Hour)}
+    live := models.ImportJob{ID: "live", State: models.ImportRunning, Owner: "other", CreatedAt: now.Add(-time.Hour), HeartbeatAt: &now}
+    for _, job := range []models.ImportJob{stale, unowned, live} {
+        require.NoError(t, s.CreateImport(ctx, job))
+    }
+
+    im := NewImporter(s)
+    runCtx, cancel := context.WithCancel(ctx)
+    defer cancel()
+    go im.Run(runCtx)
+
+    for _, id := range []string{"stale", "unowned"} {
+        job := waitForImport(t, s, id)
+        assert.Equal(t, models.ImportFailed, job.State)
+        assert.Contains(t, job.Error, "interrupted")
+    }
+
+    // Jobs of another instance that is still sending heartbeats are left alone
+    job, err := s.GetImport(ctx, "live")
+    require.NoError(t, err)
+    assert.Equal(t, models.ImportRunning, job.State)
+}
+
+func TestImporterAbandonsQueuedJobsOnShutdown(t *testing.T) {
+    s := setupTestStore(t)
+    ctx := context.Background()
+    path := filepath.Join(t.TempDir(), "upload.csv")
+    require.NoError(t, os.WriteFile(path, []byte(uploadCSV), 0o600))
+
+    // Queued before the worker starts, with the worker stopped straight away
+    im := NewImporter(s)
+    job, err := im.Submit(ctx, "upload.csv", path, true, LoadOptions{})
+    require.NoError(t, err)
+    assert.NotEmpty(t, job.Owner)
+    runCtx, cancel := context.WithCancel(ctx)
+    cancel()
+    im.Run(runCtx)
+
+    job = waitForImport(t, s, job.ID)
+    assert.Equal(t, models.ImportFailed, job.State)
+    assert.Contains(t, job.Error, "shut down")
+    _, err = os.Stat(path)
+    assert.True(t, os.IsNotExist(err), "expected the uploaded file to be removed")
+}
+
+func TestImporterRefusesWhenQueueIsFull(t *testing.T) {
+    s := setupTestStore(t)
+    im := NewImporter(s)
+
+    // Without a running worker, the queue fills up
+    for i := 0; i < importQueueSize; i++ {
+        _, err := im.Submit(context.Background(), "queued.csv", "queued.csv", false, LoadOptions{})
+        require.NoError(t, err)
+    }
+    _, err := im.Submit(context.Background(), "overflow.csv", "overflow.csv", false, LoadOptions{})
+    assert.ErrorIs(t, err, ErrImportQueueFull)
+
+    jobs, err := s.ListImports(context.Background(), 0)
+    require.NoError(t, err)
+    failed := 0
+    for _, job := range jobs {
+        if job.State == models.ImportFailed {
+            failed++
+            assert.Equal(t, "overflow.csv", job.Source)
+        }
+    }
+    assert.Equal(t, 1, failed, "expected the refused import to be recorded as failed")
+}
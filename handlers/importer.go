@@ -0,0 +1,224 @@
+package handlers
+
+import (
+    "context"
+    "crypto/rand"
+    "encoding/hex"
+    "errors"
+    "fmt"
+    "os"
+    "strconv"
+    "time"
+    "trail-finder/models"
+    "trail-finder/store"
+
+    "github.com/sirupsen/logrus"
+)
+
+// importQueueSize is how many imports can wait for the worker before new ones are refused
+const importQueueSize = 16
+
+// importHeartbeat is how often the importer records that its unfinished jobs are still alive
+const importHeartbeat = 30 * time.Second
+
+// importStaleAfter is how long an unfinished job can go without a heartbeat before another instance fails it
+const importStaleAfter = 4 * importHeartbeat
+
+// ErrImportQueueFull is returned when too many imports are already waiting to run
+var ErrImportQueueFull = errors.New("too many imports are waiting, please retry later")
+
+// Importer runs CSV imports in a background worker, one at a time, recording each as an import job.
+// Jobs are owned by the importer that queued them; several server instances can share a store, and each
+// only fails the unfinished jobs of instances that stopped sending heartbeats.
+type Importer struct {
+    store store.TrailStore
+    queue chan importTask
+    owner string // Instance ID recorded on the jobs this importer queues
+}
+
+// importTask is a queued import: the job and the file it reads
+type importTask struct {
+    job    models.ImportJob
+    path   string // CSV file to import
+    remove bool   // Whether the file is a temporary copy of an upload, removed once imported
+    opts   LoadOptions
+}
+
+// NewImporter creates an importer that records jobs in and loads trails into the given store.
+// Imports only run once Run has been started.
+func NewImporter(s store.TrailStore) *Importer {
+    return &Importer{store: s, queue: make(chan importTask, importQueueSize), owner: newInstanceID()}
+}
+
+// Submit records a queued import job for the CSV at path and hands it to the worker, which loads it with opts.
+// When remove is set the file is deleted once it has been imported, or when it cannot be queued.
+func (im *Importer) Submit(ctx context.Context, source, path string, remove bool, opts LoadOptions) (models.ImportJob, error) {
+    task := importTask{path: path, remove: remove, opts: opts}
+    if err := task.opts.validate(); err != nil {
+        task.cleanup()
+        return models.ImportJob{}, err
+    }
+    id, err := newImportID()
+    if err != nil {
+        task.cleanup()
+        return models.ImportJob{}, err
+    }
+    created := time.Now().UTC()
+    task.job = models.ImportJob{
+        ID: id, State: models.ImportQueued, Source: source, Owner: im.owner,
+        ImportReport: models.ImportReport{Mode: task.opts.Mode, Rejected: []models.RejectedRow{}},
+        CreatedAt: created, HeartbeatAt: &created,
+    }
+    if err := im.store.CreateImport(ctx, task.job); err != nil {
+        task.cleanup()
+        return models.ImportJob{}, err
+    }
+
+    select {
+    case im.queue <- task:
+        logrus.Infof("Queued import %s from %s", id, source)
+        return task.job, nil
+    default:
+        im.finish(ctx, &task.job, ErrImportQueueFull)
+        task.cleanup()
+        return models.ImportJob{}, ErrImportQueueFull
+    }
+}
+
+// Run imports queued jobs until the context is cancelled. Jobs left queued or running by an instance that
+// stopped can never finish, so they are marked failed, first and then on every heartbeat. Once the context is
+// cancelled, the jobs still queued are failed and their uploaded files removed; a running import is finished first.
+func (im *Importer) Run(ctx context.Context) {
+    im.failStale(ctx)
+    go im.heartbeat(ctx)
+    for {
+        select {
+        case <-ctx.Done():
+            im.drain()
+            return
+        case task := <-im.queue:
+            if ctx.Err() != nil {
+                im.abandon(task)
+                continue
+            }
+            im.run(ctx, task)
+        }
+    }
+}
+
+// heartbeat records that the unfinished jobs of this importer are alive and fails the stale jobs of other
+// instances, until the context is cancelled; it then drains the queue without waiting for a running import
+func (im *Importer) heartbeat(ctx context.Context) {
+    ticker := time.NewTicker(importHeartbeat)
+    defer ticker.Stop()
+    for {
+        select {
+        case <-ctx.Done():
+            im.drain()
+            return
+        case <-ticker.C:
+            if err := im.store.HeartbeatImports(ctx, im.owner, time.Now().UTC()); err != nil {
+                logrus.Warnf("Failed to record import heartbeat: %v", err)
+            }
+            im.failStale(ctx)
+        }
+    }
+}
+
+// run imports one queued job, saving its progress before and after the load
+func (im *Importer) run(ctx context.Context, task importTask) {
+    defer task.cleanup()
+    job := &task.job
+
+    started := time.Now().UTC()
+    job.State, job.StartedAt, job.HeartbeatAt = models.ImportRunning, &started, &started
+    if err := im.store.UpdateImport(ctx, *job); err != nil {
+        logrus.Errorf("Failed to start import %s: %v", job.ID, err)
+    }
+
+    file, err := os.Open(task.path)
+    if err != nil {
+        im.finish(ctx, job, fmt.Errorf("could not open file: %w", err))
+        return
+    }
+    defer file.Close()
+
+    job.ImportReport, err = LoadTrailsFromReader(im.store, file, models.DefaultMapping(), job.Source, task.opts)
+    im.finish(ctx, job, err)
+}
+
+// finish marks the job succeeded, or failed with the given error, and saves it
+func (im *Importer) finish(ctx context.Context, job *models.ImportJob, err error) {
+    finished := time.Now().UTC()
+    job.State, job.FinishedAt, job.HeartbeatAt = models.ImportSucceeded, &finished, &finished
+    if err != nil {
+        job.State, job.Error = models.ImportFailed, err.Error()
+        logrus.Errorf("Import %s failed: %v", job.ID, err)
+    } else {
+        logrus.Infof("Import %s imported %d of %d rows", job.ID, job.RowsImported, job.RowsRead)
+    }
+
+    if err := im.store.UpdateImport(ctx, *job); err != nil {
+        logrus.Errorf("Failed to save import %s: %v", job.ID, err)
+    }
+}
+
+// failStale marks failed the unfinished jobs of other instances that stopped sending heartbeats
+func (im *Importer) failStale(ctx context.Context) {
+    cutoff := time.Now().UTC().Add(-importStaleAfter)
+    failed, err := im.store.FailStaleImports(ctx, im.owner, cutoff, "interrupted: the server running it stopped")
+    if err != nil {
+        logrus.Warnf("Failed to check for interrupted imports: %v", err)
+        return
+    }
+    if failed > 0 {
+        logrus.Warnf("Marked %d interrupted imports as failed", failed)
+    }
+}
+
+// drain abandons every job still waiting in the queue
+func (im *Importer) drain() {
+    for {
+        select {
+        case task := <-im.queue:
+            im.abandon(task)
+        default:
+            return
+        }
+    }
+}
+
+// abandon fails a queued job that will not run because the importer is stopping, and removes its file.
+// The job is saved without the cancelled context so the failure is still recorded.
+func (im *Importer) abandon(task importTask) {
+    im.finish(context.Background(), &task.job, errors.New("cancelled: the server shut down before the import ran"))
+    task.cleanup()
+}
+
+// cleanup removes the temporary copy of an uploaded CSV
+func (t importTask) cleanup() {
+    if t.remove {
+        if err := os.Remove(t.path); err != nil && !os.IsNotExist(err) {
+            logrus.Warnf("Failed to remove import file %s: %v", t.path, err)
+        }
+    }
+}
+
+// newInstanceID identifies this server process as the owner of the imports it queues: its host name, which is
+// the pod name on Kubernetes, and its start time, which tells restarts on the same host apart
+func newInstanceID() string {
+    host, err := os.Hostname()
+    if err != nil || host == "" {
+        host = "trail-finder"
+    }
+    return fmt.Sprintf("%s-%s", host, strconv.FormatInt(time.Now().UnixNano(), 36))
+}
+
+// newImportID returns a random identifier for an import job
+func newImportID() (string, error) {
+    b := make([]byte, 8)
+    if _, err := rand.Read(b); err != nil {
+        return "", fmt.Errorf("failed to generate import id: %w", err)
+    }
+    return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,210 @@
+package handlers
+
+import (
+    "fmt"
+    "net/url"
+    "strconv"
+    "strings"
+    "trail-finder/models"
+    "trail-finder/store"
+)
+
+// Page sizes for trails listings. Larger limits are capped rather than rejected.
+const (
+    DefaultPageSize = 10
+    MaxPageSize     = 100
+)
+
+// pagination is the page of a listing requested with page and limit, or with a cursor from a previous response
+type pagination struct {
+    Page   int           // 1-based page number, when no cursor is used
+    Limit  int           // Number of results per page
+    Cursor *store.Cursor // Position to continue from, if given
+}
+
+// parsePagination reads the page, limit and cursor parameters. Missing or invalid page and limit values fall
+// back to the first page of DefaultPageSize results; a cursor must match the requested sort, be positional
+// for searches and distance sorts, and for searches come from a store with the given ranking.
+func parsePagination(query url.Values, keys []store.SortKey, positional bool, ranking string) (pagination, error) {
+    pg := pagination{Page: 1, Limit: DefaultPageSize}
+    if limit, err := strconv.Atoi(query.Get("limit")); err == nil && limit > 0 {
+        pg.Limit = limit
+    }
+    if pg.Limit > MaxPageSize {
+        pg.Limit = MaxPageSize
+    }
+
+    if raw := query.Get("cursor"); raw != "" {
+        if query.Get("page") != "" {
+            return pagination{}, fmt.Errorf("cursor and page cannot be used together")
+        }
+        cursor, err := store.DecodeCursor(raw, keys, positional, ranking)
+        if err != nil {
+            return pagination{}, err
+        }
+        pg.Page, pg.Cursor = 0, cursor
+        return pg, nil
+    }
+
+    if page, err := strconv.Atoi(query.Get("page")); err == nil && page > 1 {
+        pg.Page = page
+    }
+    return pg, nil
+}
+
+// parseAsOf reads the as_of parameter: the ID of the dataset version to list, or 0 for the current trails.
+// Searches always run against the current trails.
+func parseAsOf(query url.Values, search bool) (int, error) {
+    raw := query.Get("as_of")
+    if raw == "" {
+        return 0, nil
+    }
+    id, err := strconv.Atoi(raw)
+    if err != nil || id <= 0 {
+        return 0, fmt.Errorf("invalid as_of %q: must be a dataset version ID", raw)
+    }
+    if search {
+        return 0, fmt.Errorf("as_of cannot be combined with q")
+    }
+    return id, nil
+}
+
+// offset returns the number of results before the page, for page numbers and positional cursors
+func (pg pagination) offset() int {
+    if pg.Cursor != nil {
+        return pg.Cursor.Offset
+    }
+    return (pg.Page - 1) * pg.Limit
+}
+
+// apply sets the list options for the page. One extra trail is requested to tell whether a next page exists.
+func (pg pagination) apply(opts *store.ListOptions) {
+    opts.Limit = pg.Limit + 1
+    if pg.Cursor != nil && !pg.Cursor.Positional {
+        opts.After = pg.Cursor
+    } else {
+        opts.Offset = pg.offset()
+    }
+}
+
+// links builds the RFC 8288 Link header for the neighbouring pages of a response to the request URL.
+// Page-numbered requests get first, prev, next and last links; cursor requests get first and next.
+func (pg pagination) links(requestURL *url.URL, page trailPage) string {
+    link := func(rel string, set func(url.Values)) string {
+        query := requestURL.Query()
+        query.Del("cursor")
+        query.Del("page")
+        set(query)
+        target := url.URL{Path: requestURL.Path, RawQuery: query.Encode()}
+        return fmt.Sprintf("<%s>; rel=\"%s\"", target.String(), rel)
+    }
+    atPage := func(n int) func(url.Values) {
+        return func(query url.Values) { query.Set("page", strconv.Itoa(n)) }
+    }
+
+    links := []string{link("first", atPage(1))}
+    if pg.Cursor != nil {
+        if page.nextCursor != "" {
+            links = append(links, link("next", func(query url.Values) { query.Set("cursor", page.nextCursor) }))
+        }
+        return strings.Join(links, ", ")
+    }
+
+    lastPage := (page.total + pg.Limit - 1) / pg.Limit
+    if lastPage < 1 {
+        lastPage = 1
+    }
+    if pg.Page > 1 {
+        links = append(links, link("prev", atPage(pg.Page-1)))
+    }
+    if pg.Page < lastPage {
+        links = append(links, link("next", atPage(pg.Page+1)))
+    }
+    return strings.Join(append(links, link("last", atPage(lastPage))), ", ")
+}
+
+// ParseSort converts a sort parameter such as name,-park_spaces into sort keys.
+// A leading - sorts that column in descending order; ties are always broken by FID.
+// The distance key orders by the distance from the near point, which measureFrom sets.
+func ParseSort(raw string) ([]store.SortKey, error) {
+    if strings.TrimSpace(raw) == "" {
+        return nil, nil
+    }
+
+    var keys []store.SortKey
+    for _, part := range strings.Split(raw, ",") {
+        part = strings.TrimSpace(part)
+        key := store.SortKey{Field: strings.TrimPrefix(part, "-"), Desc: strings.HasPrefix(part, "-")}
+        if _, ok := filterableFields[key.Field]; !ok && key.Field != store.DistanceField {
+            return nil, fmt.Errorf("invalid sort: unknown field %q", key.Field)
+        }
+        keys = append(keys, key)
+    }
+    return keys, nil
+}
+
+// measureFrom sets the point that distance sort keys are measured from, which must be given with near
+func measureFrom(keys []store.SortKey, point *models.Point) error {
+    for i := range keys {
+        if keys[i].Field != store.DistanceField {
+            continue
+        }
+        if point == nil {
+            return fmt.Errorf("invalid sort: %s needs near=latitude,longitude", store.DistanceField)
+        }
+        keys[i].From = point
+    }
+    return nil
+}
+
+// setDistance records the distance of a trail from the near point, when one was given and the trail has coordinates
+func setDistance(trail *models.Trail, point *models.Point) {
+    if point == nil {
+        return
+    }
+    if distance, ok := trail.DistanceFrom(*point); ok {
+        trail.Distance = &distance
+    }
+}
+
+// ParseFields converts a fields parameter such as name,difficulty,fee into the list of columns to return.
+// An empty parameter returns nil, meaning every column.
+func ParseFields(raw string) ([]string, error) {
+    if strings.TrimSpace(raw) == "" {
+        return nil, nil
+    }
+
+    var fields []string
+    seen := map[string]bool{}
+    for _, field := range strings.Split(raw, ",") {
+        field = strings.TrimSpace(field)
+        if _, ok := filterableFields[field]; !ok {
+            return nil, fmt.Errorf("invalid fields: unknown field %q", field)
+        }
+        if !seen[field] {
+            seen[field] = true
+            fields = append(fields, field)
+        }
+    }
+    return fields, nil
+}
+
+// projectTrails keeps only the requested columns of each trail
+func projectTrails(trails []models.Trail, fields []string) []map[string]interface{} {
+    projected := make([]map[string]interface{}, len(trails))
+    for i := range trails {
+        projected[i] = trails[i].Project(fields)
+    }
+    return projected
+}
+
+// projectSearchResults keeps only the requested columns of each search result, along with its rank and highlights
+func projectSearchResults(results []store.SearchResult, fields []string) []map[string]interface{} {
+    projected := make([]map[string]interface{}, len(results))
+    for i := range results {
+        projected[i] = results[i].Project(fields)
+        projected[i]["rank"] = results[i].Rank
+        projected[i]["highlights"] = results[i].Highlights
+    }
+    return projected
+}
@@ -0,0 +1,40 @@
+package handlers
+
+import (
+    "testing"
+    "trail-finder/models"
+    "trail-finder/store"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestParseSort(t *testing.T) {
+    keys, err := ParseSort("name, -park_spaces")
+    require.NoError(t, err)
+    assert.Equal(t, []store.SortKey{{Field: "name"}, {Field: "park_spaces", Desc: true}}, keys)
+
+    keys, err = ParseSort("")
+    require.NoError(t, err)
+    assert.Nil(t, keys)
+
+    _, err = ParseSort("name,-popularity")
+    assert.Error(t, err)
+
+    // Distance keys are measured from the near point
+    keys, err = ParseSort("-distance,name")
+    require.NoError(t, err)
+    assert.Error(t, measureFrom(keys, nil))
+    point := &models.Point{Latitude: 40, Longitude: -105}
+    require.NoError(t, measureFrom(keys, point))
+    assert.Equal(t, []store.SortKey{{Field: store.DistanceField, Desc: true, From: point}, {Field: "name"}}, keys)
+}
+
+func TestParseFields(t *testing.T) {
+    fields, err := ParseFields("name,difficulty,fee,name")
+    require.NoError(t, err)
+    assert.Equal(t, []string{"name", "difficulty", "fee"}, fields)
+
+    _, err = ParseFields("name,password")
+    assert.Error(t, err)
+}
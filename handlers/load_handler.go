@@ -0,0 +1,642 @@
+package handlers
+
+import (
+    "bufio"
+    "context"
+    "crypto/sha256"
+    "encoding/csv"
+    "encoding/hex"
+    "encoding/json"
+    "errors"
+    "fmt"
+    "hash"
+    "io"
+    "mime"
+    "net/http"
+    "os"
+    "path/filepath"
+    "strconv"
+    "strings"
+    "trail-finder/models"
+    "trail-finder/store"
+
+    "github.com/sirupsen/logrus"
+)
+
+// DefaultMaxUploadBytes is the default size limit of a CSV uploaded to /load
+const DefaultMaxUploadBytes = 32 << 20
+
+// uploadField is the multipart form field holding the uploaded CSV
+const uploadField = "file"
+
+// ErrInvalidCSV is returned when a CSV cannot be parsed or its header does not match the column mapping
+var ErrInvalidCSV = errors.New("invalid CSV")
+
+// errLoadForbidden is returned for server-side file paths outside the allow-listed load directory
+var errLoadForbidden = errors.New("file path is not allowed")
+
+// LoadTrailsFromRequest handles POST /load by queueing an import job that replaces the stored trails with
+// a CSV or GeoJSON FeatureCollection sent by the client:
+//
+//   - multipart/form-data with the file in the file field
+//   - a raw text/csv or application/geo+json request body
+//   - application/json {"file_path": "..."} naming a file inside the handler's LoadDir, when one is configured
+//
+// GeoJSON is recognized by its content, so uploads and server-side files may be either format.
+// Uploads larger than MaxUploadBytes are rejected. The file is saved and the request answered with
+// 202 Accepted and the queued job; GET /imports/{id} follows the import as the background worker runs it
+// and reports every rejected row. With ?strict=true the import fails, leaving the stored trails untouched,
+// if any row is rejected. With ?mode=upsert the CSV is merged into the stored trails instead of replacing
+// them, and ?soft_delete=true also soft-deletes the stored trails missing from it.
+//
+// With ?dry_run=true nothing is queued or stored: the CSV is read right away and the response is
+// 200 OK with its validation report and the diff against the stored trails.
+func (h *TrailHandler) LoadTrailsFromRequest(w http.ResponseWriter, r *http.Request) {
+    r.Body = http.MaxBytesReader(w, r.Body, h.MaxUploadBytes)
+
+    var opts LoadOptions
+    var err error
+    if opts.Strict, err = boolParam(r, "strict"); err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    if opts.DryRun, err = boolParam(r, "dry_run"); err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    if opts.SoftDelete, err = boolParam(r, "soft_delete"); err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    if opts.Mode, err = models.ParseImportMode(r.URL.Query().Get("mode")); err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+    if err := opts.validate(); err != nil {
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
+
+    mediaType, _, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+    if err != nil {
+        writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be multipart/form-data, text/csv, application/geo+json or application/json")
+        return
+    }
+
+    var source, path string
+    remove := true
+    switch mediaType {
+    case "multipart/form-data":
+        source, path, err = saveMultipart(r)
+    case "text/csv", "application/geo+json":
+        source = "request body"
+        path, err = saveUpload(r.Body)
+    case "application/json":
+        remove = false
+        source, path, err = h.requestedFilePath(r)
+    default:
+        writeError(w, http.StatusUnsupportedMediaType, "Content-Type must be multipart/form-data, text/csv, application/geo+json or application/json")
+        return
+    }
+    if err != nil {
+        writeLoadError(w, err)
+        return
+    }
+
+    if opts.DryRun {
+        h.dryRun(w, source, importTask{path: path, remove: remove, opts: opts})
+        return
+    }
+
+    job, err := h.Importer.Submit(r.Context(), source, path, remove, opts)
+    if err != nil {
+        writeLoadError(w, err)
+        return
+    }
+
+    w.Header().Set("Location", "/imports/"+job.ID)
+    writeJSON(w, http.StatusAccepted, job)
+}
+
+// dryRun compares the saved CSV of a load request with the stored trails and responds with the report
+func (h *TrailHandler) dryRun(w http.ResponseWriter, source string, task importTask) {
+    defer task.cleanup()
+
+    file, err := os.Open(task.path)
+    if err != nil {
+        writeStoreError(w, fmt.Errorf("could not open file: %w", err), "read CSV")
+        return
+    }
+    defer file.Close()
+
+    report, err := LoadTrailsFromReader(h.Store, file, models.DefaultMapping(), source, task.opts)
+    switch {
+    case errors.Is(err, ErrInvalidCSV), errors.Is(err, ErrInvalidGeoJSON), errors.Is(err, ErrRowsRejected):
+        writeError(w, http.StatusUnprocessableEntity, err.Error())
+    case err != nil:
+        writeStoreError(w, err, "compare trails")
+    default:
+        writeJSON(w, http.StatusOK, report)
+    }
+}
+
+// boolParam parses an optional true/false query parameter, which defaults to false
+func boolParam(r *http.Request, name string) (bool, error) {
+    raw := r.URL.Query().Get(name)
+    if raw == "" {
+        return false, nil
+    }
+    value, err := strconv.ParseBool(raw)
+    if err != nil {
+        return false, fmt.Errorf("invalid %s %q: must be true or false", name, raw)
+    }
+    return value, nil
+}
+
+// saveMultipart saves the CSV in the file field of a multipart upload to a temporary file
+func saveMultipart(r *http.Request) (string, string, error) {
+    reader, err := r.MultipartReader()
+    if err != nil {
+        return "", "", badRequest("invalid multipart upload: %v", err)
+    }
+    for {
+        part, err := reader.NextPart()
+        if err == io.EOF {
+            return "", "", badRequest("multipart upload must include a %s field", uploadField)
+        }
+        if err != nil {
+            return "", "", loadReadError(err, "invalid multipart upload")
+        }
+        if part.FormName() != uploadField {
+            part.Close()
+            continue
+        }
+
+        source := part.FileName()
+        if source == "" {
+            source = "upload"
+        }
+        path, err := saveUpload(part)
+        part.Close()
+        return source, path, err
+    }
+}
+
+// saveUpload copies an uploaded CSV to a temporary file for the import worker, returning its path
+func saveUpload(r io.Reader) (string, error) {
+    file, err := os.CreateTemp("", "trail-import-*.csv")
+    if err != nil {
+        return "", fmt.Errorf("could not save upload: %w", err)
+    }
+    defer file.Close()
+
+    if _, err := io.Copy(file, r); err != nil {
+        os.Remove(file.Name())
+        return "", loadReadError(err, "could not read upload")
+    }
+    return file.Name(), nil
+}
+
+// requestedFilePath resolves the server-side file named by a JSON request, which must be inside LoadDir
+func (h *TrailHandler) requestedFilePath(r *http.Request) (string, string, error) {
+    var request struct {
+        FilePath string `json:"file_path"`
+    }
+    if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+        return "", "", loadReadError(err, "Invalid JSON")
+    }
+    if request.FilePath == "" {
+        return "", "", badRequest("File path must be provided")
+    }
+    if h.LoadDir == "" {
+        return "", "", fmt.Errorf("%w: loading server-side files is disabled, upload the CSV instead", errLoadForbidden)
+    }
+
+    path, err := resolveLoadPath(h.LoadDir, request.FilePath)
+    if err != nil {
+        return "", "", err
+    }
+    return request.FilePath, path, nil
+}
+
+// resolveLoadPath resolves a path relative to the load directory, rejecting absolute paths and paths that
+// leave the directory, directly or through symbolic links
+func resolveLoadPath(dir, path string) (string, error) {
+    if !filepath.IsLocal(path) {
+        return "", fmt.Errorf("%w: %q must be relative to the load directory", errLoadForbidden, path)
+    }
+
+    root, err := filepath.EvalSymlinks(dir)
+    if err != nil {
+        return "", fmt.Errorf("could not resolve load directory: %w", err)
+    }
+    resolved, err := filepath.EvalSymlinks(filepath.Join(root, path))
+    if err != nil {
+        return "", badRequest("could not open file %q", path)
+    }
+    if rel, err := filepath.Rel(root, resolved); err != nil || !filepath.IsLocal(rel) {
+        return "", fmt.Errorf("%w: %q is outside the load directory", errLoadForbidden, path)
+    }
+    return resolved, nil
+}
+
+// requestError is a load failure caused by the request itself
+type requestError struct {
+    message string
+}
+
+func (e *requestError) Error() string {
+    return e.message
+}
+
+// badRequest returns a requestError with a formatted message
+func badRequest(format string, args ...interface{}) error {
+    return &requestError{message: fmt.Sprintf(format, args...)}
+}
+
+// loadReadError keeps size limit errors, which are reported separately, and turns other read errors into bad requests
+func loadReadError(err error, message string) error {
+    var tooLarge *http.MaxBytesError
+    if errors.As(err, &tooLarge) {
+        return err
+    }
+    return badRequest("%s: %v", message, err)
+}
+
+// writeLoadError responds to a failed load with the status matching its cause
+func writeLoadError(w http.ResponseWriter, err error) {
+    var tooLarge *http.MaxBytesError
+    var invalid *requestError
+    switch {
+    case errors.As(err, &tooLarge):
+        writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("upload must not exceed %d bytes", tooLarge.Limit))
+    case errors.Is(err, errLoadForbidden):
+        logrus.Warnf("Rejected load: %v", err)
+        writeError(w, http.StatusForbidden, err.Error())
+    case errors.As(err, &invalid):
+        logrus.Warnf("Rejected load: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
+    case errors.Is(err, ErrImportQueueFull):
+        writeError(w, http.StatusServiceUnavailable, err.Error())
+    default:
+        writeStoreError(w, err, "queue import")
+    }
+}
+
+// LoadDefaultData loads default data from a CSV if the store has no trails
+func LoadDefaultData(s store.TrailStore, filename string) error {
+    isEmpty, err := IsTableEmpty(s)
+    if err != nil {
+        logrus.Errorf("Failed to check if table is empty: %v", err)
+        return err
+    }
+
+    if isEmpty {
+        logrus.Infof("Loading default data from: %s", filename)
+        return LoadTrails(s, filename)
+    }
+
+    logrus.Info("Trails data already exists. No default data loaded.")
+    return nil
+}
+
+// IsTableEmpty checks if the store has no trails
+func IsTableEmpty(s store.TrailStore) (bool, error) {
+    if s == nil {
+        return false, fmt.Errorf("trail store is not initialized")
+    }
+
+    count, err := s.Count(context.Background(), nil)
+    if err != nil {
+        logrus.Errorf("Failed to check table row count: %v", err)
+        return false, err
+    }
+
+    return count == 0, nil
+}
+
+// LoadTrails loads data from CSV into the store, replacing existing data
+func LoadTrails(s store.TrailStore, filename string) error {
+    return LoadTrailsWithMapping(s, filename, models.DefaultMapping())
+}
+
+// LoadTrailsWithMapping loads data from CSV into the store using the given column mapping,
+// replacing existing data. Columns are located by header name rather than by position.
+func LoadTrailsWithMapping(s store.TrailStore, filename string, mapping *models.ColumnMapping) error {
+    _, err := LoadTrailsFile(s, filename, mapping, LoadOptions{})
+    return err
+}
+
+// LoadTrailsFile loads data from a CSV file like LoadTrailsWithMapping, returning the validation report of its rows
+func LoadTrailsFile(s store.TrailStore, filename string, mapping *models.ColumnMapping, opts LoadOptions) (models.ImportReport, error) {
+    file, err := os.Open(filename)
+    if err != nil {
+        logrus.Errorf("Could not open file: %v", err)
+        return models.ImportReport{Rejected: []models.RejectedRow{}}, fmt.Errorf("could not open file: %w", err)
+    }
+    defer file.Close()
+
+    return LoadTrailsFromReader(s, file, mapping, filename, opts)
+}
+
+// LoadOptions controls how a CSV load treats rejected rows
+type LoadOptions struct {
+    Mode       models.ImportMode // Replace the stored trails (the default) or upsert the CSV rows into them
+    SoftDelete bool              // With upsert, soft-delete stored trails missing from the CSV
+    Strict     bool              // Abort the load, leaving the stored trails untouched, if any row is rejected
+    DryRun     bool              // Compare the CSV with the stored trails instead of storing it
+}
+
+// validate checks that the options can be combined, filling in the default mode
+func (o *LoadOptions) validate() error {
+    if o.Mode == "" {
+        o.Mode = models.ImportReplace
+    }
+    if o.SoftDelete && o.Mode != models.ImportUpsert {
+        return badRequest("soft delete only applies to upsert loads")
+    }
+    return nil
+}
+
+// ErrRowsRejected is returned by strict loads that rejected at least one row
+var ErrRowsRejected = errors.New("rows were rejected")
+
+// LoadTrailsFromReader parses CSV from r row by row and replaces the stored trails with it, or in upsert
+// mode merges it into them. Input starting with a JSON object is read as a GeoJSON FeatureCollection
+// instead, one row per feature. Source names the file in log messages and errors. Rows with the wrong number of
+// columns, values that cannot be converted, trails that fail validation and repeated FIDs are skipped, each
+// reason being recorded in the returned report along with the counts of the rows read before any error.
+// A successful load snapshots the resulting trails as a dataset version, identified in the report; a dry run
+// stores nothing and reports the diff against the stored trails instead.
+func LoadTrailsFromReader(s store.TrailStore, r io.Reader, mapping *models.ColumnMapping, source string, opts LoadOptions) (models.ImportReport, error) {
+    if err := opts.validate(); err != nil {
+        return models.ImportReport{Rejected: []models.RejectedRow{}}, err
+    }
+    report := models.ImportReport{Mode: opts.Mode, Rejected: []models.RejectedRow{}}
+
+    // Check if the store is initialized
+    if s == nil {
+        return report, fmt.Errorf("trail store is not initialized")
+    }
+
+    // Hash the file as it is read, to identify it in the dataset version recorded by the load
+    digest := sha256.New()
+    input := bufio.NewReader(io.TeeReader(r, digest))
+    var records rowReader
+    var err error
+    if isGeoJSON(input) {
+        records, err = newGeoJSONRows(input, mapping, source)
+    } else {
+        records, err = newCSVRows(input, mapping, source)
+    }
+    if err != nil {
+        return report, err
+    }
+
+    rows := &sourceTrails{rows: records, source: source, strict: opts.Strict, report: &report, seen: map[int]int{}}
+
+    if opts.DryRun || opts.Mode == models.ImportUpsert {
+        // Comparing the CSV with the stored trails needs all of its trails at once
+        trails := []models.Trail{}
+        for rows.Next() {
+            trails = append(trails, rows.Trail())
+        }
+        if err := rows.Err(); err != nil {
+            return report, err
+        }
+        if opts.DryRun {
+            return report, dryRun(s, trails, opts, source, &report)
+        }
+        return report, merge(s, trails, opts, source, versionLabel(source, digest), &report)
+    }
+
+    // Stream the trails into the store, replacing the stored ones and recording them as a dataset version
+    // in one atomic operation
+    count, version, err := s.ReplaceAllFrom(context.Background(), rows, versionLabel(source, digest))
+    if rowsErr := rows.Err(); rowsErr != nil {
+        return report, rowsErr
+    }
+    if err != nil {
+        logrus.Errorf("Failed to replace trails: %v", err)
+        return report, fmt.Errorf("failed to replace trails: %w", err)
+    }
+    report.RowsImported, report.Version = count, version.ID
+
+    logrus.Infof("Trails data replaced successfully from: %s", source)
+    logrus.Infof("Recorded dataset version %d with %d trails from: %s", version.ID, version.TrailCount, source)
+    return report, nil
+}
+
+// versionLabel describes the dataset version recorded by a load, identified by the SHA-256 of its file.
+// The store only reads the digest once the file has been read to the end.
+func versionLabel(source string, digest hash.Hash) store.VersionLabel {
+    return func() models.DatasetVersion {
+        return models.DatasetVersion{Source: source, SHA256: hex.EncodeToString(digest.Sum(nil))}
+    }
+}
+
+// dryRun records in the report what loading the trails would change, without storing them
+func dryRun(s store.TrailStore, trails []models.Trail, opts LoadOptions, source string, report *models.ImportReport) error {
+    stored, err := s.List(context.Background(), store.ListOptions{})
+    if err != nil {
+        logrus.Errorf("Failed to list stored trails: %v", err)
+        return fmt.Errorf("failed to list stored trails: %w", err)
+    }
+    diff := models.DiffTrails(stored, trails)
+    if opts.Mode == models.ImportUpsert && !opts.SoftDelete {
+        diff.Removed = []models.TrailSummary{} // Upserts keep the stored trails missing from the CSV
+    }
+    report.Diff = &diff
+    logrus.Infof("Dry run of %s would add %d, remove %d and change %d trails",
+        source, len(diff.Added), len(diff.Removed), len(diff.Changed))
+    return nil
+}
+
+// merge upserts the trails into the store and records them as a dataset version, noting what happened to each
+// trail in the report
+func merge(s store.TrailStore, trails []models.Trail, opts LoadOptions, source string, label store.VersionLabel, report *models.ImportReport) error {
+    counts, version, err := s.Merge(context.Background(), trails, opts.SoftDelete, label)
+    if err != nil {
+        logrus.Errorf("Failed to merge trails: %v", err)
+        return fmt.Errorf("failed to merge trails: %w", err)
+    }
+    report.RowsImported, report.MergeCounts, report.Version = len(trails), counts, version.ID
+    logrus.Infof("Trails data merged from %s: %d inserted, %d updated, %d unchanged, %d deleted",
+        source, counts.Inserted, counts.Updated, counts.Unchanged, counts.Deleted)
+    logrus.Infof("Recorded dataset version %d with %d trails from: %s", version.ID, version.TrailCount, source)
+    return nil
+}
+
+// rowReader reads the records of a source file one at a time, returning io.EOF after the last one
+type rowReader interface {
+    Read() (sourceRow, error)
+}
+
+// sourceRow is one record of a CSV or GeoJSON file, with its values in the order of its header
+type sourceRow struct {
+    line     int // Line of a CSV row, or position of a GeoJSON feature
+    header   []string
+    columns  models.ColumnIndex
+    values   []string
+    point    *[2]float64          // Longitude and latitude of the record's geometry, if it has one
+    rejected []models.RejectedRow // Reasons the record could not be read as a row, if any
+}
+
+// csvRows reads the rows of a CSV after its header row
+type csvRows struct {
+    reader  *csv.Reader
+    header  []string
+    columns models.ColumnIndex
+    source  string
+}
+
+// newCSVRows reads the header row of a CSV and resolves the column positions from it
+func newCSVRows(r io.Reader, mapping *models.ColumnMapping, source string) (*csvRows, error) {
+    reader := csv.NewReader(r)
+    reader.FieldsPerRecord = -1 // Short and long rows are reported as rejected rather than failing the load
+    header, err := reader.Read()
+    if err == io.EOF {
+        return nil, fmt.Errorf("%w: CSV file is empty: %s", ErrInvalidCSV, source)
+    }
+    if err != nil {
+        return nil, csvReadError(source, err)
+    }
+
+    columns, err := mapping.Resolve(header)
+    if err != nil {
+        logrus.Errorf("Invalid CSV header in %s: %v", source, err)
+        return nil, fmt.Errorf("%w: invalid CSV header: %v", ErrInvalidCSV, err)
+    }
+    return &csvRows{reader: reader, header: header, columns: columns, source: source}, nil
+}
+
+func (c *csvRows) Read() (sourceRow, error) {
+    row, err := c.reader.Read()
+    if err == io.EOF {
+        return sourceRow{}, err
+    }
+    if err != nil {
+        return sourceRow{}, csvReadError(c.source, err)
+    }
+    line, _ := c.reader.FieldPos(0)
+    return sourceRow{line: line, header: c.header, columns: c.columns, values: row}, nil
+}
+
+// sourceTrails streams the trails of a CSV or GeoJSON file as a store.TrailSource. Rows that cannot be
+// imported are skipped and recorded in the report; in strict mode, Err fails the load once every row has
+// been read if any was rejected.
+type sourceTrails struct {
+    rows   rowReader
+    source string
+    strict bool
+    report *models.ImportReport
+    seen   map[int]int // Line each FID was first imported from
+    trail  models.Trail
+    err    error
+}
+
+func (c *sourceTrails) Next() bool {
+    for c.err == nil {
+        row, err := c.rows.Read()
+        if err == io.EOF {
+            c.finish()
+            return false
+        }
+        if err != nil {
+            c.err = err
+            return false
+        }
+        c.report.RowsRead++
+
+        var trail models.Trail
+        reasons := row.rejected
+        if len(reasons) == 0 {
+            trail, reasons = checkRow(row, c.seen)
+        }
+        if len(reasons) > 0 {
+            logrus.Warnf("Skipping row %d of %s: %s", row.line, c.source, strings.TrimSpace(reasons[0].Column+" "+reasons[0].Reason))
+            c.report.Reject(reasons...)
+            continue
+        }
+        c.seen[trail.FID] = row.line
+        c.trail = trail
+        return true
+    }
+    return false
+}
+
+func (c *sourceTrails) Trail() models.Trail {
+    return c.trail
+}
+
+func (c *sourceTrails) Err() error {
+    return c.err
+}
+
+// finish fails a strict load that rejected rows once the whole file has been read
+func (c *sourceTrails) finish() {
+    if c.strict && c.report.RowsRejected > 0 {
+        logrus.Errorf("Strict load of %s rejected %d of %d rows", c.source, c.report.RowsRejected, c.report.RowsRead)
+        c.err = fmt.Errorf("%w: %d of %d rows rejected, the stored trails were left unchanged",
+            ErrRowsRejected, c.report.RowsRejected, c.report.RowsRead)
+    }
+}
+
+// checkRow converts a source row into a trail, returning the reasons it is rejected when it cannot be imported.
+// The point of a GeoJSON feature sets the trail's coordinates. Seen maps the FIDs already imported to the
+// line they came from.
+func checkRow(row sourceRow, seen map[int]int) (models.Trail, []models.RejectedRow) {
+    if len(row.values) != len(row.header) {
+        return models.Trail{}, []models.RejectedRow{{
+            Line: row.line, Value: strings.Join(row.values, ","),
+            Reason: fmt.Sprintf("expected %d columns, got %d", len(row.header), len(row.values)),
+        }}
+    }
+    rejected := func(field, reason string) models.RejectedRow {
+        column, value := field, "" // Fields without a column in this file are reported by name
+        if i, ok := row.columns[field]; ok {
+            column, value = row.header[i], row.values[i]
+        }
+        if row.point != nil && (field == "latitude" || field == "longitude") {
+            column, value = "geometry", fmt.Sprintf("[%g, %g]", row.point[0], row.point[1])
+        }
+        return models.RejectedRow{Line: row.line, Column: column, Value: value, Reason: reason}
+    }
+
+    trail, err := models.TrailFromRecord(row.columns, row.values)
+    var invalid *models.RecordError
+    if errors.As(err, &invalid) {
+        reasons := make([]models.RejectedRow, len(invalid.Values))
+        for i, v := range invalid.Values {
+            reasons[i] = rejected(v.Field, v.Reason)
+        }
+        return models.Trail{}, reasons
+    }
+    if row.point != nil {
+        trail.Longitude, trail.Latitude = &row.point[0], &row.point[1]
+    }
+
+    var validation *models.ValidationError
+    if errors.As(trail.Validate(), &validation) {
+        reasons := make([]models.RejectedRow, len(validation.Fields))
+        for i, f := range validation.Fields {
+            reasons[i] = rejected(f.Field, f.Message)
+        }
+        return models.Trail{}, reasons
+    }
+
+    if first, ok := seen[trail.FID]; ok {
+        return models.Trail{}, []models.RejectedRow{rejected("fid", fmt.Sprintf("duplicates the fid of line %d", first))}
+    }
+    return trail, nil
+}
+
+// csvReadError wraps an error from reading CSV, marking malformed CSV as ErrInvalidCSV
+func csvReadError(source string, err error) error {
+    logrus.Errorf("Could not read CSV from %s: %v", source, err)
+    var parseErr *csv.ParseError
+    if errors.As(err, &parseErr) {
+        return fmt.Errorf("%w: could not read CSV: %v", ErrInvalidCSV, err)
+    }
+    return fmt.Errorf("could not read CSV: %w", err)
+}
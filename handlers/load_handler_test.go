@@ -0,0 +1,416 @@
+package handlers
+
+import (
+    "bytes"
+    "context"
+    "encoding/json"
+    "mime/multipart"
+    "net/http"
+    "net/http/httptest"
+    "os"
+    "path/filepath"
+    "strings"
+    "testing"
+    "time"
+    "trail-finder/models"
+    "trail-finder/store"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// uploadCSV is a small CSV in the Boulder County layout, with one row that cannot be converted
+const uploadCSV = "FID,AccessName,RESTROOMS\n1,Mesa Trail,Yes\n2,Flagstaff Summit,No\n3,Broken,Maybe\n"
+
+// postLoad sends a /load request with the given content type and body
+func postLoad(h *TrailHandler, contentType string, body []byte) *httptest.ResponseRecorder {
+    req := httptest.NewRequest(http.MethodPost, "/load", bytes.NewReader(body))
+    req.Header.Set("Content-Type", contentType)
+    w := httptest.NewRecorder()
+    h.LoadTrailsFromRequest(w, req)
+    return w
+}
+
+// multipartBody builds a multipart/form-data body with the CSV in the given field
+func multipartBody(t *testing.T, field, csv string) (string, []byte) {
+    var body bytes.Buffer
+    writer := multipart.NewWriter(&body)
+    require.NoError(t, writer.WriteField("note", "ignored"))
+    part, err := writer.CreateFormFile(field, "trails.csv")
+    require.NoError(t, err)
+    part.Write([]byte(csv))
+    require.NoError(t, writer.Close())
+    return writer.FormDataContentType(), body.Bytes()
+}
+
+// runImports runs the handler's import worker until the test ends
+func runImports(t *testing.T, h *TrailHandler) {
+    ctx, cancel := context.WithCancel(context.Background())
+    t.Cleanup(cancel)
+    go h.Importer.Run(ctx)
+}
+
+// decodeImport decodes the import job of a /load or /imports/{id} response
+func decodeImport(t *testing.T, w *httptest.ResponseRecorder) models.ImportJob {
+    var job models.ImportJob
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &job), w.Body.String())
+    return job
+}
+
+// waitForImport polls the store until the import job has finished
+func waitForImport(t *testing.T, s store.ImportStore, id string) models.ImportJob {
+    deadline := time.Now().Add(5 * time.Second)
+    for {
+        job, err := s.GetImport(context.Background(), id)
+        require.NoError(t, err)
+        if job.State.Finished() {
+            return job
+        }
+        if time.Now().After(deadline) {
+            t.Fatalf("import %s did not finish, last state %s", id, job.State)
+        }
+        time.Sleep(5 * time.Millisecond)
+    }
+}
+
+func TestLoadTrailsUpload(t *testing.T) {
+    contentType, body := multipartBody(t, "file", uploadCSV)
+
+    tests := []struct {
+        name, contentType string
+        body              []byte
+        source            string
+    }{
+        {"multipart", contentType, body, "trails.csv"},
+        {"raw CSV", "text/csv; charset=utf-8", []byte(uploadCSV), "request body"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := setupTestStore(t)
+            require.NoError(t, s.Upsert(context.Background(), mockTrail()))
+            h := NewTrailHandler(s)
+            runImports(t, h)
+
+            w := postLoad(h, tt.contentType, tt.body)
+            require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+            queued := decodeImport(t, w)
+            assert.Equal(t, models.ImportQueued, queued.State)
+            assert.Equal(t, "/imports/"+queued.ID, w.Header().Get("Location"))
+
+            job := waitForImport(t, s, queued.ID)
+            assert.Equal(t, models.ImportSucceeded, job.State, job.Error)
+            assert.Equal(t, tt.source, job.Source)
+            assert.Equal(t, 3, job.RowsRead)
+            assert.Equal(t, 2, job.RowsImported)
+            assert.Equal(t, 1, job.RowsRejected)
+            require.Len(t, job.Rejected, 1)
+            assert.Equal(t, models.RejectedRow{Line: 4, Column: "RESTROOMS", Value: "Maybe", Reason: "is not a yes/no value"}, job.Rejected[0])
+            require.NotNil(t, job.StartedAt)
+            require.NotNil(t, job.FinishedAt)
+
+            trail, err := s.Get(context.Background(), 2)
+            require.NoError(t, err)
+            assert.Equal(t, "flagstaff summit", trail.Name)
+            count, err := s.Count(context.Background(), nil)
+            require.NoError(t, err)
+            assert.Equal(t, 2, count, "expected the upload to replace the stored trails")
+        })
+    }
+}
+
+// uploadGeoJSON is a small FeatureCollection with the properties of the Boulder County layout and one
+// feature whose geometry is not a point. Its type member follows the features, which is valid GeoJSON.
+const uploadGeoJSON = `{
+  "features": [
+    {"type": "Feature", "properties": {"FID": 1, "AccessName": "Mesa Trail", "RESTROOMS": "Yes"},
+     "geometry": {"type": "Point", "coordinates": [-105.2831, 39.9992]}},
+    {"type": "Feature", "properties": {"FID": 2, "AccessName": "Flagstaff Summit", "RESTROOMS": false, "ParkSpaces": null},
+     "geometry": {"type": "Point", "coordinates": [-105.3059, 40.0013]}},
+    {"type": "Feature", "properties": {"FID": 3, "AccessName": "Mesa Loop"},
+     "geometry": {"type": "LineString", "coordinates": [[-105.28, 39.99], [-105.29, 39.98]]}},
+    {"type": "Feature", "properties": {"FID": 4, "AccessName": "Unmapped"}, "geometry": null}
+  ],
+  "type": "FeatureCollection"
+}`
+
+func TestLoadTrailsGeoJSON(t *testing.T) {
+    s := setupTestStore(t)
+    require.NoError(t, s.Upsert(context.Background(), mockTrail()))
+    h := NewTrailHandler(s)
+    runImports(t, h)
+
+    w := postLoad(h, "application/geo+json", []byte(uploadGeoJSON))
+    require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+    job := waitForImport(t, s, decodeImport(t, w).ID)
+    assert.Equal(t, models.ImportSucceeded, job.State, job.Error)
+    assert.Equal(t, 4, job.RowsRead)
+    assert.Equal(t, 3, job.RowsImported)
+    assert.Equal(t, []models.RejectedRow{{Line: 3, Column: "geometry", Value: "LineString", Reason: "must be a Point"}}, job.Rejected)
+
+    // Properties go through the column mapping and the point sets the coordinates
+    trail, err := s.Get(context.Background(), 1)
+    require.NoError(t, err)
+    assert.Equal(t, "mesa trail", trail.Name)
+    assert.True(t, trail.Restrooms)
+    require.NotNil(t, trail.Latitude)
+    require.NotNil(t, trail.Longitude)
+    assert.Equal(t, 39.9992, *trail.Latitude)
+    assert.Equal(t, -105.2831, *trail.Longitude)
+
+    trail, err = s.Get(context.Background(), 2)
+    require.NoError(t, err)
+    assert.False(t, trail.Restrooms)
+    assert.Nil(t, trail.ParkSpaces)
+
+    trail, err = s.Get(context.Background(), 4)
+    require.NoError(t, err)
+    assert.Nil(t, trail.Latitude, "expected a feature without geometry to have no coordinates")
+}
+
+func TestLoadTrailsFailedImport(t *testing.T) {
+    tests := []struct {
+        name, body, message string
+    }{
+        {"malformed CSV", "FID,AccessName\n1,\"Mesa\n", "invalid CSV"},
+        {"malformed CSV after valid rows", "FID,AccessName\n5,Flagstaff\n6,\"Mesa\n", "invalid CSV"},
+        {"header without required columns", "ID,Name\n1,Mesa\n", "invalid CSV header"},
+        {"empty CSV", "", "CSV file is empty"},
+        {"GeoJSON feature", `{"type": "Feature", "properties": {"FID": 5}}`, "not a FeatureCollection"},
+        {"malformed GeoJSON after valid features", `{"type": "FeatureCollection", "features": [{"type": "Feature", "properties": {"FID": 5, "AccessName": "Flagstaff"}}, {"type":`, "invalid GeoJSON"},
+        {"GeoJSON without features", `{"type": "FeatureCollection"}`, "no features array"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := setupTestStore(t)
+            require.NoError(t, s.Upsert(context.Background(), mockTrail()))
+            h := NewTrailHandler(s)
+            runImports(t, h)
+
+            w := postLoad(h, "text/csv", []byte(tt.body))
+            require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+            job := waitForImport(t, s, decodeImport(t, w).ID)
+            assert.Equal(t, models.ImportFailed, job.State)
+            assert.Contains(t, job.Error, tt.message)
+
+            // Failed imports leave the stored trails untouched
+            _, err := s.Get(context.Background(), 1)
+            assert.NoError(t, err)
+            _, err = s.Get(context.Background(), 5)
+            assert.ErrorIs(t, err, store.ErrNotFound)
+        })
+    }
+}
+
+func TestLoadTrailsRejected(t *testing.T) {
+    dir := t.TempDir()
+    require.NoError(t, os.WriteFile(filepath.Join(dir, "trails.csv"), []byte(uploadCSV), 0o644))
+    outside := filepath.Join(t.TempDir(), "secret.csv")
+    require.NoError(t, os.WriteFile(outside, []byte(uploadCSV), 0o644))
+    require.NoError(t, os.Symlink(outside, filepath.Join(dir, "link.csv")))
+
+    missingField, missingBody := multipartBody(t, "upload", uploadCSV)
+
+    tests := []struct {
+        name, loadDir, contentType, body string
+        maxBytes                         int64
+        status                           int
+        message                          string
+    }{
+        {"path mode disabled", "", "application/json", `{"file_path": "trails.csv"}`, 0, http.StatusForbidden, "loading server-side files is disabled"},
+        {"path traversal", dir, "application/json", `{"file_path": "../secret.csv"}`, 0, http.StatusForbidden, "must be relative to the load directory"},
+        {"absolute path", dir, "application/json", `{"file_path": "` + outside + `"}`, 0, http.StatusForbidden, "must be relative to the load directory"},
+        {"symlink out of the directory", dir, "application/json", `{"file_path": "link.csv"}`, 0, http.StatusForbidden, "outside the load directory"},
+        {"missing file", dir, "application/json", `{"file_path": "nope.csv"}`, 0, http.StatusBadRequest, "could not open file"},
+        {"missing file field", "", missingField, string(missingBody), 0, http.StatusBadRequest, "must include a file field"},
+        {"too large", "", "text/csv", uploadCSV, 16, http.StatusRequestEntityTooLarge, "must not exceed 16 bytes"},
+        {"unsupported media type", "", "application/xml", "<trails/>", 0, http.StatusUnsupportedMediaType, "Content-Type must be"},
+    }
+
+    for _, tt := range tests {
+        t.Run(tt.name, func(t *testing.T) {
+            s := setupTestStore(t)
+            h := NewTrailHandler(s)
+            h.LoadDir = tt.loadDir
+            if tt.maxBytes > 0 {
+                h.MaxUploadBytes = tt.maxBytes
+            }
+
+            w := postLoad(h, tt.contentType, []byte(tt.body))
+            assert.Equal(t, tt.status, w.Code, w.Body.String())
+            assert.Contains(t, decodeError(t, w).Message, tt.message)
+
+            // Rejected requests do not create import jobs
+            jobs, err := s.ListImports(context.Background(), 0)
+            require.NoError(t, err)
+            assert.Empty(t, jobs)
+        })
+    }
+
+    // Paths inside the load directory are imported
+    s := setupTestStore(t)
+    h := NewTrailHandler(s)
+    h.LoadDir = dir
+    runImports(t, h)
+    w := postLoad(h, "application/json", []byte(`{"file_path": "trails.csv"}`))
+    require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+    job := waitForImport(t, s, decodeImport(t, w).ID)
+    assert.Equal(t, models.ImportSucceeded, job.State, job.Error)
+    assert.Equal(t, "trails.csv", job.Source)
+}
+
+func TestLoadTrailsReport(t *testing.T) {
+    const csv = "FID,AccessName,RESTROOMS,PARKSPACES\n" +
+        "1,Mesa Trail,Yes,3\n" +
+        "2,,Maybe,-1\n" +
+        "1,Duplicate,No,\n" +
+        "4,Short\n" +
+        "5,Chautauqua,No,\n"
+
+    s := setupTestStore(t)
+    require.NoError(t, s.Upsert(context.Background(), mockTrail()))
+    report, err := LoadTrailsFromReader(s, strings.NewReader(csv), models.DefaultMapping(), "test.csv", LoadOptions{})
+    require.NoError(t, err)
+
+    assert.Equal(t, 5, report.RowsRead)
+    assert.Equal(t, 2, report.RowsImported)
+    assert.Equal(t, 3, report.RowsRejected)
+    assert.Equal(t, []models.RejectedRow{
+        {Line: 3, Column: "RESTROOMS", Value: "Maybe", Reason: "is not a yes/no value"},
+        {Line: 4, Column: "FID", Value: "1", Reason: "duplicates the fid of line 2"},
+        {Line: 5, Value: "4,Short", Reason: "expected 4 columns, got 2"},
+    }, report.Rejected)
+
+    // Trails that convert but fail validation are reported by field
+    report, err = LoadTrailsFromReader(s, strings.NewReader("FID,AccessName\n-1,\n"), models.DefaultMapping(), "test.csv", LoadOptions{})
+    require.NoError(t, err)
+    assert.Equal(t, []models.RejectedRow{
+        {Line: 2, Column: "FID", Value: "-1", Reason: "must not be negative"},
+        {Line: 2, Column: "AccessName", Value: "", Reason: "is required"},
+    }, report.Rejected)
+}
+
+func TestLoadTrailsStrict(t *testing.T) {
+    s := setupTestStore(t)
+    require.NoError(t, s.Upsert(context.Background(), mockTrail()))
+
+    report, err := LoadTrailsFromReader(s, strings.NewReader(uploadCSV), models.DefaultMapping(), "test.csv", LoadOptions{Strict: true})
+    assert.ErrorIs(t, err, ErrRowsRejected)
+    assert.Equal(t, 1, report.RowsRejected)
+    assert.Equal(t, 0, report.RowsImported)
+    _, err = s.Get(context.Background(), 1)
+    assert.NoError(t, err, "Expected a strict load with rejected rows to leave the stored trails untouched")
+
+    // Through /load, the strict import fails and keeps its report
+    h := NewTrailHandler(s)
+    runImports(t, h)
+    req := httptest.NewRequest(http.MethodPost, "/load?strict=true", strings.NewReader(uploadCSV))
+    req.Header.Set("Content-Type", "text/csv")
+    w := httptest.NewRecorder()
+    h.LoadTrailsFromRequest(w, req)
+    require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+    job := waitForImport(t, s, decodeImport(t, w).ID)
+    assert.Equal(t, models.ImportFailed, job.State)
+    assert.Contains(t, job.Error, "1 of 3 rows rejected")
+    assert.Equal(t, 1, job.RowsRejected)
+    require.Len(t, job.Rejected, 1)
+    assert.Equal(t, "RESTROOMS", job.Rejected[0].Column)
+
+    req = httptest.NewRequest(http.MethodPost, "/load?strict=maybe", strings.NewReader(uploadCSV))
+    req.Header.Set("Content-Type", "text/csv")
+    w = httptest.NewRecorder()
+    h.LoadTrailsFromRequest(w, req)
+    assert.Equal(t, http.StatusBadRequest, w.Code)
+    assert.Contains(t, decodeError(t, w).Message, "invalid strict")
+}
+
+func TestLoadTrailsDryRun(t *testing.T) {
+    s := setupTestStore(t)
+    stored := mockTrail()
+    stored.FID, stored.Name = 2, "flagstaff summit"
+    require.NoError(t, s.Upsert(context.Background(), stored))
+    require.NoError(t, s.Upsert(context.Background(), models.Trail{FID: 9, Name: "gone", HorseTrail: models.HorseTrailNA}))
+    h := NewTrailHandler(s)
+
+    req := httptest.NewRequest(http.MethodPost, "/load?dry_run=true", strings.NewReader(uploadCSV))
+    req.Header.Set("Content-Type", "text/csv")
+    w := httptest.NewRecorder()
+    h.LoadTrailsFromRequest(w, req)
+    require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+
+    var report models.ImportReport
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &report))
+    assert.Equal(t, 3, report.RowsRead)
+    assert.Equal(t, 0, report.RowsImported)
+    assert.Equal(t, 1, report.RowsRejected)
+    require.NotNil(t, report.Diff)
+    assert.Equal(t, []models.TrailSummary{{FID: 1, Name: "mesa trail"}}, report.Diff.Added)
+    assert.Equal(t, []models.TrailSummary{{FID: 9, Name: "gone"}}, report.Diff.Removed)
+    require.Len(t, report.Diff.Changed, 1)
+    assert.Equal(t, 2, report.Diff.Changed[0].FID)
+    assert.Equal(t, models.FieldChange{Field: "restrooms", Old: true, New: false}, report.Diff.Changed[0].Fields[0])
+
+    // Dry runs store nothing and are not recorded as imports
+    count, err := s.Count(context.Background(), nil)
+    require.NoError(t, err)
+    assert.Equal(t, 2, count)
+    jobs, err := s.ListImports(context.Background(), 0)
+    require.NoError(t, err)
+    assert.Empty(t, jobs)
+
+    // A strict dry run fails like the load would
+    req = httptest.NewRequest(http.MethodPost, "/load?dry_run=true&strict=true", strings.NewReader(uploadCSV))
+    req.Header.Set("Content-Type", "text/csv")
+    w = httptest.NewRecorder()
+    h.LoadTrailsFromRequest(w, req)
+    assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+    assert.Contains(t, decodeError(t, w).Message, "1 of 3 rows rejected")
+}
+
+func TestLoadTrailsUpsert(t *testing.T) {
+    s := setupTestStore(t)
+    edited := mockTrail()
+    edited.FID, edited.Name = 2, "flagstaff summit"
+    require.NoError(t, s.Upsert(context.Background(), edited))
+    require.NoError(t, s.Upsert(context.Background(), models.Trail{FID: 9, Name: "kept", HorseTrail: models.HorseTrailNA}))
+    h := NewTrailHandler(s)
+    runImports(t, h)
+
+    load := func(query string) models.ImportJob {
+        req := httptest.NewRequest(http.MethodPost, "/load?"+query, strings.NewReader(uploadCSV))
+        req.Header.Set("Content-Type", "text/csv")
+        w := httptest.NewRecorder()
+        h.LoadTrailsFromRequest(w, req)
+        require.Equal(t, http.StatusAccepted, w.Code, w.Body.String())
+        queued := decodeImport(t, w)
+        assert.Equal(t, models.ImportUpsert, queued.Mode)
+        return waitForImport(t, s, queued.ID)
+    }
+
+    job := load("mode=upsert")
+    assert.Equal(t, models.ImportSucceeded, job.State, job.Error)
+    assert.Equal(t, 2, job.RowsImported)
+    assert.Equal(t, models.MergeCounts{Inserted: 1, Updated: 1}, job.MergeCounts)
+    count, err := s.Count(context.Background(), nil)
+    require.NoError(t, err)
+    assert.Equal(t, 3, count, "Expected upserts to keep trails missing from the CSV")
+
+    job = load("mode=upsert&soft_delete=true")
+    assert.Equal(t, models.ImportSucceeded, job.State, job.Error)
+    assert.Equal(t, models.MergeCounts{Unchanged: 2, Deleted: 1}, job.MergeCounts)
+    _, err = s.Get(context.Background(), 9)
+    assert.ErrorIs(t, err, store.ErrNotFound)
+
+    for query, message := range map[string]string{
+        "mode=merge":       "invalid mode",
+        "soft_delete=true": "soft delete only applies to upsert loads",
+    } {
+        req := httptest.NewRequest(http.MethodPost, "/load?"+query, strings.NewReader(uploadCSV))
+        req.Header.Set("Content-Type", "text/csv")
+        w := httptest.NewRecorder()
+        h.LoadTrailsFromRequest(w, req)
+        assert.Equal(t, http.StatusBadRequest, w.Code, query)
+        assert.Contains(t, decodeError(t, w).Message, message)
+    }
+}
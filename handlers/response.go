@@ -0,0 +1,59 @@
+package handlers
+
+import (
+    "encoding/json"
+    "errors"
+    "net/http"
+    "strings"
+    "trail-finder/db"
+    "trail-finder/models"
+    "trail-finder/store"
+
+    "github.com/sirupsen/logrus"
+)
+
+// errorBody is the JSON error envelope returned by every endpoint:
+// {"error": {"code": "not_found", "message": "...", "details": [{"field": "...", "message": "..."}]}}
+type errorBody struct {
+    Error errorDetail `json:"error"`
+}
+
+// errorDetail describes a failed request; details name the invalid fields of a rejected trail
+type errorDetail struct {
+    Code    string              `json:"code"`
+    Message string              `json:"message"`
+    Details []models.FieldError `json:"details,omitempty"`
+}
+
+// writeJSON responds with the given status and value encoded as JSON
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+    w.Header().Set("Content-Type", "application/json")
+    w.WriteHeader(status)
+    json.NewEncoder(w).Encode(v)
+}
+
+// writeError responds with the JSON error envelope. The code is derived from the status text,
+// such as unprocessable_entity for 422.
+func writeError(w http.ResponseWriter, status int, message string, details ...models.FieldError) {
+    code := strings.ToLower(strings.ReplaceAll(http.StatusText(status), " ", "_"))
+    writeJSON(w, status, errorBody{Error: errorDetail{Code: code, Message: message, Details: details}})
+}
+
+// writeStoreError responds to a failed store call with the status matching the error.
+// Unexpected errors are logged and reported without their internal details.
+func writeStoreError(w http.ResponseWriter, err error, action string) {
+    switch {
+    case errors.Is(err, store.ErrNotFound):
+        writeError(w, http.StatusNotFound, "Trail not found")
+    case errors.Is(err, store.ErrConflict):
+        writeError(w, http.StatusConflict, "A trail with this fid already exists")
+    case errors.Is(err, store.ErrInvalidCursor):
+        writeError(w, http.StatusBadRequest, err.Error())
+    case errors.Is(err, db.ErrAcquireTimeout):
+        logrus.Errorf("Failed to acquire database connection: %v", err)
+        writeError(w, http.StatusServiceUnavailable, "Database is busy, please retry")
+    default:
+        logrus.Errorf("Failed to %s: %v", action, err)
+        writeError(w, http.StatusInternalServerError, "Failed to "+action)
+    }
+}
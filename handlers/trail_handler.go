@@ -2,258 +2,302 @@ package handlers
 
 import (
     "context"
-    "encoding/csv"
     "encoding/json"
+    "errors"
     "fmt"
-    "io/ioutil"
     "net/http"
-    "os"
-    "strconv"
     "strings"
     "trail-finder/models"
+    "trail-finder/store"
     "github.com/sirupsen/logrus"
-    "trail-finder/db"
 )
 
-// LoadTrailsFromRequest handles loading a new CSV file from a client request
-func LoadTrailsFromRequest(w http.ResponseWriter, r *http.Request) {
-    var request struct {
-        FilePath string `json:"file_path"`
-    }
+// TrailHandler serves the trail endpoints from the injected TrailStore
+type TrailHandler struct {
+    Store store.TrailStore
+
+    // LoadDir is the only directory /load may read server-side files from; empty disables file paths
+    LoadDir string
+    // MaxUploadBytes caps the size of a CSV uploaded to /load
+    MaxUploadBytes int64
+    // Importer runs the imports queued by /load; its Run method must be started for them to complete
+    Importer *Importer
+}
+
+// NewTrailHandler creates a handler backed by the given store
+func NewTrailHandler(s store.TrailStore) *TrailHandler {
+    return &TrailHandler{Store: s, MaxUploadBytes: DefaultMaxUploadBytes, Importer: NewImporter(s)}
+}
+
+// GetTrails handles GET requests to filter trails from the store. Trails are listed a page at a time as JSON,
+// or exported as GeoJSON, CSV or NDJSON when the format parameter or Accept header asks for it.
+func (h *TrailHandler) GetTrails(w http.ResponseWriter, r *http.Request) {
+    w.Header().Add("Vary", "Accept")
 
-    body, err := ioutil.ReadAll(r.Body)
+    // Fetch filter query parameters and normalize them to their typed values
+    filters, err := ParseFilters(r.URL.Query())
     if err != nil {
-        logrus.Error("Invalid request body")
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
+        logrus.Warnf("Invalid trail filters: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    if err := json.Unmarshal(body, &request); err != nil {
-        logrus.Error("Invalid JSON")
-        http.Error(w, "Invalid JSON", http.StatusBadRequest)
+    // Ordering, measuring distances from the near point, and the columns to return
+    near, err := parseNear(r.URL.Query())
+    if err != nil {
+        logrus.Warnf("Invalid trail filters: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
         return
     }
-
-    filePath := request.FilePath
-    if filePath == "" {
-        logrus.Error("File path must be provided")
-        http.Error(w, "File path must be provided", http.StatusBadRequest)
+    sortKeys, err := ParseSort(r.URL.Query().Get("sort"))
+    if err == nil {
+        err = measureFrom(sortKeys, near)
+    }
+    if err != nil {
+        logrus.Warnf("Invalid trail sort: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
         return
     }
-
-    if err := LoadTrails(filePath); err != nil {
-        logrus.Errorf("Error loading trails: %v", err)
-        http.Error(w, err.Error(), http.StatusInternalServerError)
+    fields, err := ParseFields(r.URL.Query().Get("fields"))
+    if err != nil {
+        logrus.Warnf("Invalid trail fields: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
         return
     }
 
-    logrus.Infof("Trails loaded successfully from: %s", filePath)
-    w.WriteHeader(http.StatusOK)
-    w.Write([]byte("Trails loaded successfully from: " + filePath))
-}
-
-// LoadDefaultData loads default data from a CSV if the trails table is empty
-func LoadDefaultData(filename string) error {
-    isEmpty, err := IsTableEmpty()
+    // Exports hold every matching trail rather than a page of them
+    format, err := parseFormat(r)
+    if errors.Is(err, errNotAcceptable) {
+        logrus.Warnf("Unacceptable trail format: %v", err)
+        writeError(w, http.StatusNotAcceptable, err.Error())
+        return
+    }
     if err != nil {
-        logrus.Errorf("Failed to check if table is empty: %v", err)
-        return err
+        logrus.Warnf("Invalid trail format: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
     }
-
-    if isEmpty {
-        logrus.Infof("Loading default data from: %s", filename)
-        return LoadTrails(filename)
+    if format != nil {
+        h.exportTrails(w, r, format, store.ListOptions{Filters: filters, Sort: sortKeys}, fields, near)
+        return
     }
 
-    logrus.Info("Trails data already exists. No default data loaded.")
-    return nil
-}
-
-// IsTableEmpty checks if the trails table is empty
-func IsTableEmpty() (bool, error) {
-    if db.DbConn == nil {
-        return false, fmt.Errorf("database connection is not initialized")
+    // Pagination parameters: page/limit, or a cursor from a previous response
+    search := strings.TrimSpace(r.URL.Query().Get("q"))
+    ranking := ""
+    if search != "" {
+        ranking = h.Store.SearchRanking()
     }
-
-    var count int
-    err := db.DbConn.QueryRow(context.Background(), "SELECT COUNT(*) FROM trails").Scan(&count)
+    pg, err := parsePagination(r.URL.Query(), sortKeys, search != "" || store.SortsByDistance(sortKeys), ranking)
     if err != nil {
-        logrus.Errorf("Failed to check table row count: %v", err)
-        return false, err
+        logrus.Warnf("Invalid trail pagination: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
     }
 
-    return count == 0, nil
-}
+    asOf, err := parseAsOf(r.URL.Query(), search != "")
+    if err != nil {
+        logrus.Warnf("Invalid trail version: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
+    }
 
+    logrus.Infof("Filters: %v", filters)
 
-// LoadTrails loads data from CSV into PostgreSQL, replacing existing data
-func LoadTrails(filename string) error {
-    // Check if the database connection is initialized
-    if db.DbConn == nil {
-        return fmt.Errorf("database connection is not initialized")
+    // Query the store, ranking by relevance when a search text is given
+    opts := store.ListOptions{Filters: filters, Sort: sortKeys}
+    var page trailPage
+    if search != "" {
+        page, err = h.searchPage(r.Context(), search, opts, pg, fields, near)
+    } else {
+        page, err = h.listPage(r.Context(), opts, pg, fields, asOf, near)
     }
-
-    file, err := os.Open(filename)
-    if err != nil {
-        logrus.Errorf("Could not open file: %v", err)
-        return fmt.Errorf("could not open file: %w", err)
+    if asOf > 0 && errors.Is(err, store.ErrNotFound) {
+        writeError(w, http.StatusNotFound, "Version not found")
+        return
     }
-    defer file.Close()
-
-    reader := csv.NewReader(file)
-    rows, err := reader.ReadAll()
     if err != nil {
-        logrus.Errorf("Could not read CSV: %v", err)
-        return fmt.Errorf("could not read CSV: %w", err)
+        writeStoreError(w, err, "query trails")
+        return
     }
 
-    // Start a transaction to ensure atomicity
-    tx, err := db.DbConn.Begin(context.Background())
-    if err != nil {
-        logrus.Errorf("Could not start transaction: %v", err)
-        return fmt.Errorf("could not start transaction: %w", err)
+    // Set the response header to JSON, with navigation links to the neighbouring pages
+    w.Header().Set("Content-Type", "application/json")
+    if links := pg.links(r.URL, page); links != "" {
+        w.Header().Set("Link", links)
     }
 
-    // Clear the existing data in the trails table
-    _, err = tx.Exec(context.Background(), "DELETE FROM trails")
-    if err != nil {
-        tx.Rollback(context.Background())
-        logrus.Errorf("Could not clear existing data: %v", err)
-        return fmt.Errorf("could not clear existing data: %w", err)
+    // Prepare the response with pagination info
+    response := map[string]interface{}{
+        "limit":   pg.Limit,
+        "total":   page.total,
+        "results": page.results,
+    }
+    if pg.Cursor == nil {
+        response["page"] = pg.Page
+    }
+    if page.nextCursor != "" {
+        response["next_cursor"] = page.nextCursor
+    }
+    if search != "" {
+        response["q"] = search
+    }
+    if asOf > 0 {
+        response["as_of"] = asOf
+    }
+    if near != nil {
+        response["near"] = near
     }
 
-    // Insert new data from CSV
-    for i, row := range rows {
-        if i == 0 || len(row) < 32 {
-            continue // Skip header or invalid rows
-        }
-
-        fid, err := strconv.Atoi(row[0])
-        if err != nil {
-            continue // Skip rows where FID is not an integer
-        }
-
-        name := strings.ToLower(row[30])
-        restrooms := strings.ToLower(row[1])
-        picnic := strings.ToLower(row[2])
-        fishing := strings.ToLower(row[3])
-        bikeTrail := strings.ToLower(row[11])
-        horseTrail := strings.ToLower(row[25])
-        difficulty := strings.ToLower(row[21])
-        fee := strings.ToLower(row[10])
-        recycleBin := strings.ToLower(row[28])
-        grills := strings.ToLower(row[13])
-        bikeRack := strings.ToLower(row[11])
-        dogTube := strings.ToLower(row[12])
-
-        _, err = tx.Exec(context.Background(), `
-            INSERT INTO trails (fid, name, restrooms, picnic, fishing, type, difficulty, access_type, th_leash, bike_trail, horse_trail, fee, recycle_bin, grills, bike_rack, dog_tube )
-            VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
-        `, fid, name, restrooms, picnic, fishing, row[8], difficulty, row[6], row[31], bikeTrail, horseTrail, fee, recycleBin, grills, bikeRack, dogTube)
+    // Respond with the filtered trails
+    logrus.Infof("Responding with %d of %d results", page.count, page.total)
+    json.NewEncoder(w).Encode(response)
+}
 
-        if err != nil {
-            tx.Rollback(context.Background())
-            logrus.Errorf("Failed to insert data: %v", err)
-            return fmt.Errorf("failed to insert data: %w", err)
+// exportParams are the listing parameters that do not apply to exports, which are neither paged nor ranked
+var exportParams = []string{"page", "limit", "cursor", "q"}
+
+// exportTrails writes every trail matching the options in the export format, as the store reads them, so large
+// listings are never held in memory. as_of exports the trails of a dataset version. The response only starts
+// once the first trail is read, so invalid requests still get an error response; a failure after that can only
+// cut the export short.
+func (h *TrailHandler) exportTrails(w http.ResponseWriter, r *http.Request, format *exportFormat, opts store.ListOptions, fields []string, near *models.Point) {
+    query := r.URL.Query()
+    for _, param := range exportParams {
+        if query.Get(param) != "" {
+            message := fmt.Sprintf("%s does not apply to %s exports, which hold every matching trail", param, format.name)
+            logrus.Warnf("Invalid trail export: %s", message)
+            writeError(w, http.StatusBadRequest, message)
+            return
         }
     }
-
-    err = tx.Commit(context.Background())
+    asOf, err := parseAsOf(query, false)
     if err != nil {
-        logrus.Errorf("Could not commit transaction: %v", err)
-        return fmt.Errorf("could not commit transaction: %w", err)
+        logrus.Warnf("Invalid trail version: %v", err)
+        writeError(w, http.StatusBadRequest, err.Error())
+        return
     }
 
-    logrus.Infof("Trails data replaced successfully from: %s", filename)
-    return nil
-}
-
-// GetTrails handles GET requests to filter trails from PostgreSQL
-func GetTrails(w http.ResponseWriter, r *http.Request) {
-    // Fetch filter query parameters
-    restrooms := strings.ToLower(r.URL.Query().Get("restrooms"))
-    fishing := strings.ToLower(r.URL.Query().Get("fishing"))
-    bikeTrail := strings.ToLower(r.URL.Query().Get("bike_trail"))
-    horseTrail := strings.ToLower(r.URL.Query().Get("horse_trail"))
-
-    // Pagination parameters
-    page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-    limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-    if page < 1 {
-        page = 1
+    var out trailWriter
+    count := 0
+    write := func(trail models.Trail) error {
+        if out == nil {
+            w.Header().Set("Content-Type", format.contentType)
+            out = format.newWriter(w, fields, near != nil)
+        }
+        setDistance(&trail, near)
+        count++
+        return out.Write(&trail)
     }
-    if limit < 1 {
-        limit = 10
+    if asOf > 0 {
+        err = h.Store.StreamVersionTrails(r.Context(), asOf, opts, write)
+    } else {
+        err = h.Store.Stream(r.Context(), opts, write)
     }
-    offset := (page - 1) * limit
-
-    // Initialize query and args
-    query := "SELECT * FROM trails WHERE 1=1"
-    args := []interface{}{}
-    i := 1
 
-    // Build the query based on the filters
-    if restrooms != "" {
-        query += fmt.Sprintf(" AND LOWER(restrooms) = $%d", i)
-        args = append(args, restrooms)
-        i++
-    }
-    if fishing != "" {
-        query += fmt.Sprintf(" AND LOWER(fishing) = $%d", i)
-        args = append(args, fishing)
-        i++
-    }
-    if bikeTrail != "" {
-        query += fmt.Sprintf(" AND LOWER(bike_trail) = $%d", i)
-        args = append(args, bikeTrail)
-        i++
-    }
-    if horseTrail != "" {
-        query += fmt.Sprintf(" AND LOWER(horse_trail) = $%d", i)
-        args = append(args, horseTrail)
-        i++
+    switch {
+    case out != nil && err != nil:
+        logrus.Errorf("Failed to export trails as %s after %d trails: %v", format.name, count, err)
+        return
+    case asOf > 0 && errors.Is(err, store.ErrNotFound):
+        writeError(w, http.StatusNotFound, "Version not found")
+        return
+    case err != nil:
+        writeStoreError(w, err, "export trails")
+        return
+    case out == nil:
+        w.Header().Set("Content-Type", format.contentType)
+        out = format.newWriter(w, fields, near != nil)
     }
+    if err := out.Close(); err != nil {
+        logrus.Errorf("Failed to export trails as %s: %v", format.name, err)
+        return
+    }
+    logrus.Infof("Exported %d trails as %s", count, format.name)
+}
 
-    // Add pagination to the query
-    query += fmt.Sprintf(" LIMIT $%d OFFSET $%d", i, i+1)
-    args = append(args, limit, offset)
-
-    logrus.Infof("SQL Query: %s", query)
-    logrus.Infof("Arguments: %v", args)
+// trailPage is one page of a trails listing or search, ready to be encoded
+type trailPage struct {
+    results    interface{}
+    count      int    // Number of results on the page
+    total      int    // Number of results across every page
+    nextCursor string // Cursor for the following page, empty on the last page
+}
 
-    // Execute the query
-    rows, err := db.DbConn.Query(context.Background(), query, args...)
+// listPage fetches one page of the trails matching the options, with the total number of matches.
+// A positive asOf lists the trails of that dataset version instead of the current ones, and a near point
+// adds the distance of each trail from it.
+func (h *TrailHandler) listPage(ctx context.Context, opts store.ListOptions, pg pagination, fields []string, asOf int, near *models.Point) (trailPage, error) {
+    pg.apply(&opts)
+    var trails []models.Trail
+    var total int
+    var err error
+    if asOf > 0 {
+        trails, total, err = h.Store.ListVersionTrails(ctx, asOf, opts)
+    } else {
+        trails, total, err = h.listCurrent(ctx, opts)
+    }
     if err != nil {
-        logrus.Errorf("Failed to query trails: %v", err)
-        http.Error(w, "Failed to query trails", http.StatusInternalServerError)
-        return
+        return trailPage{}, err
     }
-    defer rows.Close()
 
-    // Prepare the response
-    var filteredTrails []models.Trail
-    for rows.Next() {
-        var trail models.Trail
-        err := rows.Scan(&trail.FID, &trail.Name, &trail.Restrooms, &trail.Picnic, &trail.Fishing, &trail.Type, &trail.Difficulty, &trail.AccessType, &trail.THLeash, &trail.BikeTrail, &trail.HorseTrail, &trail.Fee, &trail.RecycleBin, &trail.Grills, &trail.BikeRack, &trail.DogTube)
-        if err != nil {
-            logrus.Errorf("Failed to scan trails: %v", err)
-            http.Error(w, "Failed to scan trails", http.StatusInternalServerError)
-            return
+    page := trailPage{total: total}
+    if len(trails) > pg.Limit {
+        trails = trails[:pg.Limit]
+        if store.SortsByDistance(opts.Sort) {
+            page.nextCursor = store.PositionCursorAt(pg.offset()+pg.Limit, opts.Sort, "").Encode()
+        } else {
+            page.nextCursor = store.CursorAfter(&trails[len(trails)-1], opts.Sort).Encode()
         }
-        filteredTrails = append(filteredTrails, trail)
     }
+    for i := range trails {
+        setDistance(&trails[i], near)
+    }
+    page.count, page.results = len(trails), trails
+    if fields != nil {
+        page.results = projectTrails(trails, fields)
+    }
+    return page, nil
+}
 
-    // Set the response header to JSON
-    w.Header().Set("Content-Type", "application/json")
+// listCurrent lists the current trails matching the options and counts every match
+func (h *TrailHandler) listCurrent(ctx context.Context, opts store.ListOptions) ([]models.Trail, int, error) {
+    trails, err := h.Store.List(ctx, opts)
+    if err != nil {
+        return nil, 0, err
+    }
+    total, err := h.Store.Count(ctx, opts.Filters)
+    if err != nil {
+        return nil, 0, err
+    }
+    return trails, total, nil
+}
 
-    // Prepare the response with pagination info
-    response := map[string]interface{}{
-        "page":    page,
-        "limit":   limit,
-        "results": filteredTrails,
+// searchPage fetches one page of the search results. Every match is ranked to count them,
+// so the page is cut from the ranked results rather than by the store. Its cursor records the store's
+// search ranking, as pages of other rankings hold different trails at the same position.
+func (h *TrailHandler) searchPage(ctx context.Context, text string, opts store.ListOptions, pg pagination, fields []string, near *models.Point) (trailPage, error) {
+    results, err := h.Store.Search(ctx, text, opts)
+    if err != nil {
+        return trailPage{}, err
     }
 
-    // Respond with the filtered trails
-    logrus.Infof("Responding with %d results for page %d", len(filteredTrails), page)
-    json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+    offset := pg.offset()
+    page := trailPage{total: len(results)}
+    if offset > len(results) {
+        offset = len(results)
+    }
+    results = results[offset:]
+    if len(results) > pg.Limit {
+        results = results[:pg.Limit]
+        page.nextCursor = store.PositionCursorAt(offset+pg.Limit, opts.Sort, h.Store.SearchRanking()).Encode()
+    }
+    for i := range results {
+        setDistance(&results[i].Trail, near)
+    }
+    page.count, page.results = len(results), results
+    if fields != nil {
+        page.results = projectSearchResults(results, fields)
+    }
+    return page, nil
+}
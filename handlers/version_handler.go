@@ -0,0 +1,82 @@
+package handlers
+
+import (
+    "errors"
+    "fmt"
+    "net/http"
+    "strconv"
+    "trail-finder/store"
+
+    "github.com/sirupsen/logrus"
+)
+
+// DefaultVersionsLimit is the number of dataset versions GET /versions returns unless limit is given
+const DefaultVersionsLimit = 20
+
+// ListVersions handles GET /versions, returning the most recent dataset versions, newest first
+func (h *TrailHandler) ListVersions(w http.ResponseWriter, r *http.Request) {
+    limit := DefaultVersionsLimit
+    if raw := r.URL.Query().Get("limit"); raw != "" {
+        n, err := strconv.Atoi(raw)
+        if err != nil || n <= 0 {
+            writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid limit %q: must be a positive number", raw))
+            return
+        }
+        limit = min(n, MaxPageSize)
+    }
+
+    versions, err := h.Store.ListVersions(r.Context(), limit)
+    if err != nil {
+        writeStoreError(w, err, "list versions")
+        return
+    }
+    writeJSON(w, http.StatusOK, map[string]interface{}{"results": versions})
+}
+
+// GetVersion handles GET /versions/{id}, describing a dataset version
+func (h *TrailHandler) GetVersion(w http.ResponseWriter, r *http.Request) {
+    id, ok := pathVersionID(w, r)
+    if !ok {
+        return
+    }
+    version, err := h.Store.GetVersion(r.Context(), id)
+    if errors.Is(err, store.ErrNotFound) {
+        writeError(w, http.StatusNotFound, "Version not found")
+        return
+    }
+    if err != nil {
+        writeStoreError(w, err, "get version")
+        return
+    }
+    writeJSON(w, http.StatusOK, version)
+}
+
+// RollbackVersion handles POST /versions/{id}/rollback, replacing the current trails with those of a dataset
+// version. The restored trails are recorded as a new version, which is returned.
+func (h *TrailHandler) RollbackVersion(w http.ResponseWriter, r *http.Request) {
+    id, ok := pathVersionID(w, r)
+    if !ok {
+        return
+    }
+    version, err := h.Store.Rollback(r.Context(), id)
+    if errors.Is(err, store.ErrNotFound) {
+        writeError(w, http.StatusNotFound, "Version not found")
+        return
+    }
+    if err != nil {
+        writeStoreError(w, err, "roll back trails")
+        return
+    }
+    logrus.Infof("Rolled back trails to version %d, recorded as version %d", id, version.ID)
+    writeJSON(w, http.StatusOK, version)
+}
+
+// pathVersionID parses the {id} path segment, responding with 400 when it is not a version ID
+func pathVersionID(w http.ResponseWriter, r *http.Request) (int, bool) {
+    id, err := strconv.Atoi(r.PathValue("id"))
+    if err != nil || id <= 0 {
+        writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid version ID %q", r.PathValue("id")))
+        return 0, false
+    }
+    return id, true
+}
@@ -0,0 +1,96 @@
+package handlers
+
+import (
+    "crypto/sha256"
+    "encoding/hex"
+    "encoding/json"
+    "net/http"
+    "strings"
+    "testing"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestDatasetVersions(t *testing.T) {
+    s := setupTestStore(t)
+    h := NewTrailHandler(s)
+    mux := trailMux(h)
+    mux.HandleFunc("GET /versions", h.ListVersions)
+    mux.HandleFunc("GET /versions/{id}", h.GetVersion)
+    mux.HandleFunc("POST /versions/{id}/rollback", h.RollbackVersion)
+
+    // Every successful load records a version with the hash of the file
+    first, err := LoadTrailsFromReader(s, strings.NewReader(uploadCSV), models.DefaultMapping(), "first.csv", LoadOptions{})
+    require.NoError(t, err)
+    assert.Equal(t, 1, first.Version)
+    second, err := LoadTrailsFromReader(s, strings.NewReader("FID,AccessName\n7,Sawhill\n"), models.DefaultMapping(), "second.csv",
+        LoadOptions{Mode: models.ImportUpsert})
+    require.NoError(t, err)
+    assert.Equal(t, 2, second.Version)
+    dryRun, err := LoadTrailsFromReader(s, strings.NewReader(uploadCSV), models.DefaultMapping(), "dry.csv", LoadOptions{DryRun: true})
+    require.NoError(t, err)
+    assert.Zero(t, dryRun.Version, "dry runs must not record a version")
+
+    w := serve(mux, http.MethodGet, "/versions/1", "")
+    require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    var version models.DatasetVersion
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &version))
+    sum := sha256.Sum256([]byte(uploadCSV))
+    assert.Equal(t, hex.EncodeToString(sum[:]), version.SHA256)
+    assert.Equal(t, "first.csv", version.Source)
+    assert.Equal(t, 2, version.TrailCount)
+
+    w = serve(mux, http.MethodGet, "/versions?limit=1", "")
+    require.Equal(t, http.StatusOK, w.Code)
+    var list struct {
+        Results []models.DatasetVersion `json:"results"`
+    }
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &list))
+    require.Len(t, list.Results, 1)
+    assert.Equal(t, 2, list.Results[0].ID)
+    assert.Equal(t, 3, list.Results[0].TrailCount)
+
+    // as_of lists the trails of a version, with filters and pagination
+    w = serve(mux, http.MethodGet, "/trails?as_of=1&restrooms=false", "")
+    require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    var page struct {
+        AsOf    int            `json:"as_of"`
+        Total   int            `json:"total"`
+        Results []models.Trail `json:"results"`
+    }
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &page))
+    assert.Equal(t, 1, page.AsOf)
+    assert.Equal(t, 1, page.Total)
+    require.Len(t, page.Results, 1)
+    assert.Equal(t, "flagstaff summit", page.Results[0].Name)
+
+    // Rolling back restores the trails of the version as a new version
+    w = serve(mux, http.MethodPost, "/versions/1/rollback", "")
+    require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+    require.NoError(t, json.Unmarshal(w.Body.Bytes(), &version))
+    assert.Equal(t, 3, version.ID)
+    assert.Equal(t, 1, version.RollbackOf)
+    w = serve(mux, http.MethodGet, "/trails/7", "")
+    assert.Equal(t, http.StatusNotFound, w.Code)
+
+    tests := []struct {
+        method, target string
+        status         int
+        message        string
+    }{
+        {http.MethodGet, "/trails?as_of=9", http.StatusNotFound, "Version not found"},
+        {http.MethodGet, "/trails?as_of=latest", http.StatusBadRequest, "invalid as_of"},
+        {http.MethodGet, "/trails?as_of=1&q=mesa", http.StatusBadRequest, "as_of cannot be combined with q"},
+        {http.MethodGet, "/trails/facets?as_of=1", http.StatusBadRequest, "as_of"},
+        {http.MethodGet, "/versions/9", http.StatusNotFound, "Version not found"},
+        {http.MethodGet, "/versions/first", http.StatusBadRequest, "invalid version ID"},
+        {http.MethodPost, "/versions/9/rollback", http.StatusNotFound, "Version not found"},
+    }
+    for _, tt := range tests {
+        w := serve(mux, tt.method, tt.target, "")
+        assert.Equal(t, tt.status, w.Code, tt.target)
+        assert.Contains(t, decodeError(t, w).Message, tt.message, tt.target)
+    }
+}
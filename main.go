@@ -1,18 +1,24 @@
 package main
 
 import (
+    "context"
     "flag"
     "net/http"
     "os"
     "os/signal"
-    "os/exec"
+    "strconv"
     "syscall"
+    "time"
     "trail-finder/handlers"
     "github.com/sirupsen/logrus"
     "github.com/joho/godotenv"
     "trail-finder/db"
+    "trail-finder/store"
 )
 
+// importShutdownWait is how long shutdown waits for a running import, within Kubernetes' 30 second grace period
+const importShutdownWait = 20 * time.Second
+
 func main() {
     // Initialize logger
     handlers.InitLogger()
@@ -24,22 +30,13 @@ func main() {
 
     // Define command-line flags
     startServer := flag.Bool("server", false, "Start the trail server")
-    dbConnString := flag.String("db", os.Getenv("DB_CONN_STRING"), "Postgres connection string")
+    dbConnString := flag.String("db", os.Getenv("DB_CONN_STRING"), "Database connection string: postgres://... or sqlite://path/to/trails.db")
     runMigrations := flag.Bool("migrate", false, "Run database migrations")
     flag.Parse()
 
     if *runMigrations {
-        if err := db.InitDB(*dbConnString); err != nil {
-            logrus.Fatalf("Failed to initialize database: %v", err)
-        }
-        defer db.CloseDB()
-
-        // Run the migrations
-        cmd := exec.Command("trail-cli", "migrate")
-        cmd.Stdout = os.Stdout
-        cmd.Stderr = os.Stderr
-        err := cmd.Run()
-        if err != nil {
+        // Apply the embedded migrations without starting the server
+        if err := db.Migrate(*dbConnString); err != nil {
             logrus.Fatalf("Failed to run migrations: %v", err)
         }
         logrus.Info("Migrations completed.")
@@ -48,23 +45,60 @@ func main() {
 
 
     if *startServer {
-        // Initialize the database connection
-        if err := db.InitDB(*dbConnString); err != nil {
+        // Connect to the database named by the connection string's scheme
+        trailStore, closeStore, err := store.Open(*dbConnString)
+        if err != nil {
             logrus.Fatalf("Failed to initialize database: %v", err)
         }
         // Ensure the database is closed on exit
-        defer db.CloseDB()
+        defer closeStore()
+
+        trailHandler := handlers.NewTrailHandler(trailStore)
+
+        // /load accepts uploads up to LOAD_MAX_BYTES, and server-side files only from LOAD_DIR
+        trailHandler.LoadDir = os.Getenv("LOAD_DIR")
+        if value := os.Getenv("LOAD_MAX_BYTES"); value != "" {
+            maxBytes, err := strconv.ParseInt(value, 10, 64)
+            if err != nil || maxBytes <= 0 {
+                logrus.Fatalf("LOAD_MAX_BYTES must be a positive number of bytes, got %q", value)
+            }
+            trailHandler.MaxUploadBytes = maxBytes
+        }
 
         // Load default data if the table is empty
-        if err := handlers.LoadDefaultData("./BoulderTrailHeads.csv"); err != nil {
+        if err := handlers.LoadDefaultData(trailStore, "./BoulderTrailHeads.csv"); err != nil {
             logrus.Warnf("Failed to load default data: %v", err)
         }
 
-        // Register the /load endpoint
-        http.HandleFunc("/load", handlers.LoadTrailsFromRequest)
+        // Run the imports queued by /load in the background until shutdown
+        importCtx, stopImports := context.WithCancel(context.Background())
+        importsDone := make(chan struct{})
+        go func() {
+            trailHandler.Importer.Run(importCtx)
+            close(importsDone)
+        }()
+
+        // Register the /load endpoint, and the /imports endpoints that follow the jobs it queues
+        http.HandleFunc("POST /load", trailHandler.LoadTrailsFromRequest)
+        http.HandleFunc("GET /imports", trailHandler.ListImports)
+        http.HandleFunc("GET /imports/{id}", trailHandler.GetImport)
+
+        // Register the /versions endpoints: the dataset versions recorded by imports, and rolling back to one
+        http.HandleFunc("GET /versions", trailHandler.ListVersions)
+        http.HandleFunc("GET /versions/{id}", trailHandler.GetVersion)
+        http.HandleFunc("POST /versions/{id}/rollback", trailHandler.RollbackVersion)
+
+        // Register the /trails endpoints: listing and creating trails, and reading or changing a single trail
+        http.HandleFunc("GET /trails", trailHandler.GetTrails)
+        http.HandleFunc("POST /trails", trailHandler.CreateTrail)
+        http.HandleFunc("GET /trails/facets", trailHandler.GetFacets)
+        http.HandleFunc("GET /trails/{fid}", trailHandler.GetTrail)
+        http.HandleFunc("PUT /trails/{fid}", trailHandler.ReplaceTrail)
+        http.HandleFunc("PATCH /trails/{fid}", trailHandler.PatchTrail)
+        http.HandleFunc("DELETE /trails/{fid}", trailHandler.DeleteTrail)
 
-        // Register the /trails endpoint
-        http.HandleFunc("/trails", handlers.GetTrails)
+        // Register the /healthz endpoint used by the readiness probe
+        http.HandleFunc("/healthz", trailHandler.HealthCheck)
 
         // Handle graceful shutdown
         go func() {
@@ -72,6 +106,13 @@ func main() {
             signal.Notify(c, os.Interrupt, syscall.SIGTERM)
             <-c
             logrus.Info("Received termination signal, shutting down...")
+            // Fail the queued imports and remove their uploads, giving a running import a moment to finish
+            stopImports()
+            select {
+            case <-importsDone:
+            case <-time.After(importShutdownWait):
+                logrus.Warn("Import still running at shutdown, it will be marked failed by another instance")
+            }
             os.Exit(0)
         }()
 
@@ -0,0 +1,17 @@
+ALTER TABLE trails
+    DROP COLUMN IF EXISTS aka,
+    DROP COLUMN IF EXISTS address,
+    DROP COLUMN IF EXISTS access_id,
+    DROP COLUMN IF EXISTS trash_cans,
+    DROP COLUMN IF EXISTS park_spaces,
+    DROP COLUMN IF EXISTS ada_surface,
+    DROP COLUMN IF EXISTS ada_toilet,
+    DROP COLUMN IF EXISTS ada_fishing,
+    DROP COLUMN IF EXISTS ada_camping,
+    DROP COLUMN IF EXISTS ada_picnic,
+    DROP COLUMN IF EXISTS ada_parking,
+    DROP COLUMN IF EXISTS ada_facility,
+    DROP COLUMN IF EXISTS ada_facility_name,
+    DROP COLUMN IF EXISTS date_from,
+    DROP COLUMN IF EXISTS date_to,
+    DROP COLUMN IF EXISTS dog_compost;
@@ -0,0 +1,17 @@
+ALTER TABLE trails
+    ADD COLUMN IF NOT EXISTS aka TEXT,
+    ADD COLUMN IF NOT EXISTS address TEXT,
+    ADD COLUMN IF NOT EXISTS access_id TEXT,
+    ADD COLUMN IF NOT EXISTS trash_cans INTEGER,
+    ADD COLUMN IF NOT EXISTS park_spaces INTEGER,
+    ADD COLUMN IF NOT EXISTS ada_surface TEXT,
+    ADD COLUMN IF NOT EXISTS ada_toilet TEXT,
+    ADD COLUMN IF NOT EXISTS ada_fishing TEXT,
+    ADD COLUMN IF NOT EXISTS ada_camping TEXT,
+    ADD COLUMN IF NOT EXISTS ada_picnic TEXT,
+    ADD COLUMN IF NOT EXISTS ada_parking TEXT,
+    ADD COLUMN IF NOT EXISTS ada_facility TEXT,
+    ADD COLUMN IF NOT EXISTS ada_facility_name TEXT,
+    ADD COLUMN IF NOT EXISTS date_from TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS date_to TIMESTAMP,
+    ADD COLUMN IF NOT EXISTS dog_compost TEXT;
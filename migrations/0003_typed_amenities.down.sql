@@ -0,0 +1,75 @@
+ALTER TABLE trails
+    ALTER COLUMN name DROP NOT NULL,
+    ALTER COLUMN difficulty DROP NOT NULL,
+    ALTER COLUMN access_type DROP NOT NULL,
+    ALTER COLUMN aka DROP NOT NULL,
+    ALTER COLUMN address DROP NOT NULL,
+    ALTER COLUMN access_id DROP NOT NULL,
+    ALTER COLUMN ada_surface DROP NOT NULL,
+    ALTER COLUMN ada_parking DROP NOT NULL,
+    ALTER COLUMN ada_facility_name DROP NOT NULL,
+    ALTER COLUMN name DROP DEFAULT,
+    ALTER COLUMN difficulty DROP DEFAULT,
+    ALTER COLUMN access_type DROP DEFAULT,
+    ALTER COLUMN aka DROP DEFAULT,
+    ALTER COLUMN address DROP DEFAULT,
+    ALTER COLUMN access_id DROP DEFAULT,
+    ALTER COLUMN ada_surface DROP DEFAULT,
+    ALTER COLUMN ada_parking DROP DEFAULT,
+    ALTER COLUMN ada_facility_name DROP DEFAULT;
+
+ALTER TABLE trails
+    DROP CONSTRAINT IF EXISTS trails_horse_trail_check,
+    ALTER COLUMN horse_trail DROP NOT NULL,
+    ALTER COLUMN horse_trail DROP DEFAULT;
+UPDATE trails SET horse_trail = REPLACE(horse_trail, '_', ' ');
+
+ALTER TABLE trails
+    ALTER COLUMN dog_tube TYPE TEXT USING dog_tube::TEXT;
+
+ALTER TABLE trails
+    ALTER COLUMN restrooms DROP NOT NULL,
+    ALTER COLUMN picnic DROP NOT NULL,
+    ALTER COLUMN fishing DROP NOT NULL,
+    ALTER COLUMN th_leash DROP NOT NULL,
+    ALTER COLUMN bike_trail DROP NOT NULL,
+    ALTER COLUMN fee DROP NOT NULL,
+    ALTER COLUMN recycle_bin DROP NOT NULL,
+    ALTER COLUMN grills DROP NOT NULL,
+    ALTER COLUMN bike_rack DROP NOT NULL,
+    ALTER COLUMN ada_toilet DROP NOT NULL,
+    ALTER COLUMN ada_fishing DROP NOT NULL,
+    ALTER COLUMN ada_camping DROP NOT NULL,
+    ALTER COLUMN ada_picnic DROP NOT NULL,
+    ALTER COLUMN ada_facility DROP NOT NULL,
+    ALTER COLUMN dog_compost DROP NOT NULL,
+    ALTER COLUMN restrooms DROP DEFAULT,
+    ALTER COLUMN picnic DROP DEFAULT,
+    ALTER COLUMN fishing DROP DEFAULT,
+    ALTER COLUMN th_leash DROP DEFAULT,
+    ALTER COLUMN bike_trail DROP DEFAULT,
+    ALTER COLUMN fee DROP DEFAULT,
+    ALTER COLUMN recycle_bin DROP DEFAULT,
+    ALTER COLUMN grills DROP DEFAULT,
+    ALTER COLUMN bike_rack DROP DEFAULT,
+    ALTER COLUMN ada_toilet DROP DEFAULT,
+    ALTER COLUMN ada_fishing DROP DEFAULT,
+    ALTER COLUMN ada_camping DROP DEFAULT,
+    ALTER COLUMN ada_picnic DROP DEFAULT,
+    ALTER COLUMN ada_facility DROP DEFAULT,
+    ALTER COLUMN dog_compost DROP DEFAULT,
+    ALTER COLUMN restrooms TYPE TEXT USING (CASE WHEN restrooms THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN picnic TYPE TEXT USING (CASE WHEN picnic THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN fishing TYPE TEXT USING (CASE WHEN fishing THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN th_leash TYPE TEXT USING (CASE WHEN th_leash THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN bike_trail TYPE TEXT USING (CASE WHEN bike_trail THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN fee TYPE TEXT USING (CASE WHEN fee THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN recycle_bin TYPE TEXT USING (CASE WHEN recycle_bin THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN grills TYPE TEXT USING (CASE WHEN grills THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN bike_rack TYPE TEXT USING (CASE WHEN bike_rack THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN ada_toilet TYPE TEXT USING (CASE WHEN ada_toilet THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN ada_fishing TYPE TEXT USING (CASE WHEN ada_fishing THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN ada_camping TYPE TEXT USING (CASE WHEN ada_camping THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN ada_picnic TYPE TEXT USING (CASE WHEN ada_picnic THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN ada_facility TYPE TEXT USING (CASE WHEN ada_facility THEN 'yes' ELSE 'no' END),
+    ALTER COLUMN dog_compost TYPE TEXT USING (CASE WHEN dog_compost THEN 'yes' ELSE 'no' END);
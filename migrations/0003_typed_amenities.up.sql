@@ -0,0 +1,97 @@
+-- Convert yes/no amenity columns to booleans
+ALTER TABLE trails
+    ALTER COLUMN restrooms TYPE BOOLEAN USING COALESCE(LOWER(TRIM(restrooms)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN picnic TYPE BOOLEAN USING COALESCE(LOWER(TRIM(picnic)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN fishing TYPE BOOLEAN USING COALESCE(LOWER(TRIM(fishing)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN th_leash TYPE BOOLEAN USING COALESCE(LOWER(TRIM(th_leash)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN bike_trail TYPE BOOLEAN USING COALESCE(LOWER(TRIM(bike_trail)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN fee TYPE BOOLEAN USING COALESCE(LOWER(TRIM(fee)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN recycle_bin TYPE BOOLEAN USING COALESCE(LOWER(TRIM(recycle_bin)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN grills TYPE BOOLEAN USING COALESCE(LOWER(TRIM(grills)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN bike_rack TYPE BOOLEAN USING COALESCE(LOWER(TRIM(bike_rack)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN ada_toilet TYPE BOOLEAN USING COALESCE(LOWER(TRIM(ada_toilet)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN ada_fishing TYPE BOOLEAN USING COALESCE(LOWER(TRIM(ada_fishing)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN ada_camping TYPE BOOLEAN USING COALESCE(LOWER(TRIM(ada_camping)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN ada_picnic TYPE BOOLEAN USING COALESCE(LOWER(TRIM(ada_picnic)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN ada_facility TYPE BOOLEAN USING COALESCE(LOWER(TRIM(ada_facility)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN dog_compost TYPE BOOLEAN USING COALESCE(LOWER(TRIM(dog_compost)) IN ('yes', 'y', 'true', 't', '1'), FALSE),
+    ALTER COLUMN restrooms SET DEFAULT FALSE,
+    ALTER COLUMN picnic SET DEFAULT FALSE,
+    ALTER COLUMN fishing SET DEFAULT FALSE,
+    ALTER COLUMN th_leash SET DEFAULT FALSE,
+    ALTER COLUMN bike_trail SET DEFAULT FALSE,
+    ALTER COLUMN fee SET DEFAULT FALSE,
+    ALTER COLUMN recycle_bin SET DEFAULT FALSE,
+    ALTER COLUMN grills SET DEFAULT FALSE,
+    ALTER COLUMN bike_rack SET DEFAULT FALSE,
+    ALTER COLUMN ada_toilet SET DEFAULT FALSE,
+    ALTER COLUMN ada_fishing SET DEFAULT FALSE,
+    ALTER COLUMN ada_camping SET DEFAULT FALSE,
+    ALTER COLUMN ada_picnic SET DEFAULT FALSE,
+    ALTER COLUMN ada_facility SET DEFAULT FALSE,
+    ALTER COLUMN dog_compost SET DEFAULT FALSE,
+    ALTER COLUMN restrooms SET NOT NULL,
+    ALTER COLUMN picnic SET NOT NULL,
+    ALTER COLUMN fishing SET NOT NULL,
+    ALTER COLUMN th_leash SET NOT NULL,
+    ALTER COLUMN bike_trail SET NOT NULL,
+    ALTER COLUMN fee SET NOT NULL,
+    ALTER COLUMN recycle_bin SET NOT NULL,
+    ALTER COLUMN grills SET NOT NULL,
+    ALTER COLUMN bike_rack SET NOT NULL,
+    ALTER COLUMN ada_toilet SET NOT NULL,
+    ALTER COLUMN ada_fishing SET NOT NULL,
+    ALTER COLUMN ada_camping SET NOT NULL,
+    ALTER COLUMN ada_picnic SET NOT NULL,
+    ALTER COLUMN ada_facility SET NOT NULL,
+    ALTER COLUMN dog_compost SET NOT NULL;
+
+-- Dog tubes are counted in the source data
+ALTER TABLE trails
+    ALTER COLUMN dog_tube TYPE INTEGER USING (CASE WHEN TRIM(dog_tube) ~ '^[0-9]+$' THEN TRIM(dog_tube)::INTEGER END);
+
+-- Normalize horse trail values to the HorseTrail enum
+UPDATE trails SET horse_trail = CASE
+    WHEN LOWER(TRIM(horse_trail)) LIKE 'designated%' THEN 'designated'
+    WHEN LOWER(TRIM(horse_trail)) IN ('possible') THEN 'possible'
+    WHEN LOWER(TRIM(horse_trail)) IN ('not recommended', 'not_recommended') THEN 'not_recommended'
+    WHEN LOWER(TRIM(horse_trail)) IN ('not allowed', 'not_allowed') THEN 'not_allowed'
+    WHEN LOWER(TRIM(horse_trail)) IN ('pull through', 'pull_through') THEN 'pull_through'
+    ELSE 'na'
+END;
+ALTER TABLE trails
+    ALTER COLUMN horse_trail SET DEFAULT 'na',
+    ALTER COLUMN horse_trail SET NOT NULL,
+    ADD CONSTRAINT trails_horse_trail_check
+        CHECK (horse_trail IN ('possible', 'not_recommended', 'designated', 'not_allowed', 'pull_through', 'na'));
+
+-- Text columns default to an empty string so rows always scan into the Trail model
+UPDATE trails SET
+    name = COALESCE(name, ''),
+    difficulty = COALESCE(difficulty, ''),
+    access_type = COALESCE(access_type, ''),
+    aka = COALESCE(aka, ''),
+    address = COALESCE(address, ''),
+    access_id = COALESCE(access_id, ''),
+    ada_surface = COALESCE(ada_surface, ''),
+    ada_parking = COALESCE(ada_parking, ''),
+    ada_facility_name = COALESCE(ada_facility_name, '');
+ALTER TABLE trails
+    ALTER COLUMN name SET DEFAULT '',
+    ALTER COLUMN difficulty SET DEFAULT '',
+    ALTER COLUMN access_type SET DEFAULT '',
+    ALTER COLUMN aka SET DEFAULT '',
+    ALTER COLUMN address SET DEFAULT '',
+    ALTER COLUMN access_id SET DEFAULT '',
+    ALTER COLUMN ada_surface SET DEFAULT '',
+    ALTER COLUMN ada_parking SET DEFAULT '',
+    ALTER COLUMN ada_facility_name SET DEFAULT '',
+    ALTER COLUMN name SET NOT NULL,
+    ALTER COLUMN difficulty SET NOT NULL,
+    ALTER COLUMN access_type SET NOT NULL,
+    ALTER COLUMN aka SET NOT NULL,
+    ALTER COLUMN address SET NOT NULL,
+    ALTER COLUMN access_id SET NOT NULL,
+    ALTER COLUMN ada_surface SET NOT NULL,
+    ALTER COLUMN ada_parking SET NOT NULL,
+    ALTER COLUMN ada_facility_name SET NOT NULL;
@@ -0,0 +1,4 @@
+ALTER TABLE trails
+    DROP CONSTRAINT IF EXISTS trails_pkey,
+    DROP COLUMN IF EXISTS type,
+    DROP COLUMN IF EXISTS fid;
@@ -0,0 +1,28 @@
+-- Reconcile the trails table with the schema previously created in application code:
+-- every table ends up with an integer fid primary key supplied by the data source and a type column.
+ALTER TABLE trails
+    ADD COLUMN IF NOT EXISTS fid INTEGER,
+    ADD COLUMN IF NOT EXISTS type TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS access_type TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS th_leash BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Rows loaded before fid was stored get a sequential identifier
+UPDATE trails SET fid = COALESCE((SELECT MAX(fid) FROM trails), -1) + numbered.n
+FROM (SELECT ctid, ROW_NUMBER() OVER () AS n FROM trails WHERE fid IS NULL) AS numbered
+WHERE trails.ctid = numbered.ctid;
+
+-- fid values come from the data source, so drop the SERIAL default if the table had one
+ALTER TABLE trails ALTER COLUMN fid DROP DEFAULT;
+DROP SEQUENCE IF EXISTS trails_fid_seq;
+
+ALTER TABLE trails ALTER COLUMN fid SET NOT NULL;
+
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM information_schema.table_constraints
+        WHERE table_name = 'trails' AND constraint_type = 'PRIMARY KEY'
+    ) THEN
+        ALTER TABLE trails ADD CONSTRAINT trails_pkey PRIMARY KEY (fid);
+    END IF;
+END $$;
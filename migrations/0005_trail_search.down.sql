@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS trails_search_vector_idx;
+ALTER TABLE trails DROP COLUMN IF EXISTS search_vector;
@@ -0,0 +1,13 @@
+-- Trigram similarity backs the fuzzy part of trailhead search
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Weighted full-text document over the searchable columns: names rank above AKAs, which rank above addresses.
+-- The 'simple' configuration keeps trailhead names as written instead of stemming them as English.
+ALTER TABLE trails
+    ADD COLUMN IF NOT EXISTS search_vector TSVECTOR GENERATED ALWAYS AS (
+        setweight(to_tsvector('simple', COALESCE(name, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(aka, '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(address, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS trails_search_vector_idx ON trails USING GIN (search_vector);
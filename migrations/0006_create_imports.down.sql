@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS imports_created_at_idx;
+DROP TABLE IF EXISTS imports;
@@ -0,0 +1,19 @@
+-- Import jobs created by POST /load, kept as the history shown by GET /imports and trail-cli imports.
+-- Rejected rows are stored as a JSON array of {"line", "error"} objects.
+CREATE TABLE IF NOT EXISTS imports (
+    id TEXT PRIMARY KEY,
+    state TEXT NOT NULL
+        CONSTRAINT imports_state_check
+        CHECK (state IN ('queued', 'running', 'succeeded', 'failed')),
+    source TEXT NOT NULL DEFAULT '',
+    rows_read INTEGER NOT NULL DEFAULT 0,
+    rows_imported INTEGER NOT NULL DEFAULT 0,
+    rows_rejected INTEGER NOT NULL DEFAULT 0,
+    rejected_rows JSONB NOT NULL DEFAULT '[]',
+    error TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL,
+    started_at TIMESTAMPTZ,
+    finished_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS imports_created_at_idx ON imports (created_at DESC);
@@ -0,0 +1,10 @@
+ALTER TABLE imports
+    DROP COLUMN IF EXISTS trails_deleted,
+    DROP COLUMN IF EXISTS trails_unchanged,
+    DROP COLUMN IF EXISTS trails_updated,
+    DROP COLUMN IF EXISTS trails_inserted,
+    DROP COLUMN IF EXISTS mode;
+
+-- Soft-deleted trails would reappear once the column is gone, so they are removed for good
+DELETE FROM trails WHERE deleted_at IS NOT NULL;
+ALTER TABLE trails DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,13 @@
+-- Upsert imports keep stored trails that are missing from the CSV, or soft-delete them by setting deleted_at.
+-- Soft-deleted trails are hidden from every query and revived when a later import or insert brings their FID back.
+ALTER TABLE trails ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMPTZ;
+
+-- Import jobs record how they applied the CSV and, for upserts, what happened to each trail
+ALTER TABLE imports
+    ADD COLUMN IF NOT EXISTS mode TEXT NOT NULL DEFAULT 'replace'
+        CONSTRAINT imports_mode_check
+        CHECK (mode IN ('replace', 'upsert')),
+    ADD COLUMN IF NOT EXISTS trails_inserted INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS trails_updated INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS trails_unchanged INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS trails_deleted INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,4 @@
+ALTER TABLE imports DROP COLUMN IF EXISTS version_id;
+
+DROP TABLE IF EXISTS trail_versions;
+DROP TABLE IF EXISTS dataset_versions;
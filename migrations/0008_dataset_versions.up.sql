@@ -0,0 +1,53 @@
+-- Every successful import or rollback records a dataset version with a snapshot of the trails it left,
+-- so earlier datasets can be listed, queried with GET /trails?as_of= and restored.
+CREATE TABLE IF NOT EXISTS dataset_versions (
+    id SERIAL PRIMARY KEY,
+    source TEXT NOT NULL DEFAULT '',
+    sha256 TEXT NOT NULL DEFAULT '',
+    rollback_of INTEGER NOT NULL DEFAULT 0,
+    trail_count INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS dataset_versions_created_at_idx ON dataset_versions (created_at DESC);
+
+-- The trails of each version, with the columns of the trails table apart from the generated search vector
+CREATE TABLE IF NOT EXISTS trail_versions (
+    version_id INTEGER NOT NULL REFERENCES dataset_versions (id) ON DELETE CASCADE,
+    fid INTEGER NOT NULL,
+    name TEXT NOT NULL DEFAULT '',
+    restrooms BOOLEAN NOT NULL DEFAULT FALSE,
+    picnic BOOLEAN NOT NULL DEFAULT FALSE,
+    fishing BOOLEAN NOT NULL DEFAULT FALSE,
+    type TEXT NOT NULL DEFAULT '',
+    difficulty TEXT NOT NULL DEFAULT '',
+    access_type TEXT NOT NULL DEFAULT '',
+    th_leash BOOLEAN NOT NULL DEFAULT FALSE,
+    bike_trail BOOLEAN NOT NULL DEFAULT FALSE,
+    horse_trail TEXT NOT NULL DEFAULT 'na',
+    fee BOOLEAN NOT NULL DEFAULT FALSE,
+    recycle_bin BOOLEAN NOT NULL DEFAULT FALSE,
+    grills BOOLEAN NOT NULL DEFAULT FALSE,
+    bike_rack BOOLEAN NOT NULL DEFAULT FALSE,
+    dog_tube INTEGER,
+    aka TEXT NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    access_id TEXT NOT NULL DEFAULT '',
+    trash_cans INTEGER,
+    park_spaces INTEGER,
+    ada_surface TEXT NOT NULL DEFAULT '',
+    ada_toilet BOOLEAN NOT NULL DEFAULT FALSE,
+    ada_fishing BOOLEAN NOT NULL DEFAULT FALSE,
+    ada_camping BOOLEAN NOT NULL DEFAULT FALSE,
+    ada_picnic BOOLEAN NOT NULL DEFAULT FALSE,
+    ada_parking TEXT NOT NULL DEFAULT '',
+    ada_facility BOOLEAN NOT NULL DEFAULT FALSE,
+    ada_facility_name TEXT NOT NULL DEFAULT '',
+    date_from TIMESTAMP,
+    date_to TIMESTAMP,
+    dog_compost BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (version_id, fid)
+);
+
+-- Successful import jobs record the dataset version they created
+ALTER TABLE imports ADD COLUMN IF NOT EXISTS version_id INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+ALTER TABLE trail_versions
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude;
+
+ALTER TABLE trails
+    DROP COLUMN IF EXISTS longitude,
+    DROP COLUMN IF EXISTS latitude;
@@ -0,0 +1,11 @@
+-- Trailhead coordinates in WGS 84 decimal degrees, from the point geometries of GeoJSON imports.
+-- CSV exports without coordinates leave them unset.
+ALTER TABLE trails
+    ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION
+        CONSTRAINT trails_latitude_check CHECK (latitude BETWEEN -90 AND 90),
+    ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION
+        CONSTRAINT trails_longitude_check CHECK (longitude BETWEEN -180 AND 180);
+
+ALTER TABLE trail_versions
+    ADD COLUMN IF NOT EXISTS latitude DOUBLE PRECISION,
+    ADD COLUMN IF NOT EXISTS longitude DOUBLE PRECISION;
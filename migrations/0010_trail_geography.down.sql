@@ -0,0 +1,3 @@
+-- The PostGIS extension is left installed, as other schemas may use it
+DROP INDEX IF EXISTS trails_location_idx;
+ALTER TABLE trails DROP COLUMN IF EXISTS location;
@@ -0,0 +1,22 @@
+-- Where the PostGIS extension is available, trails get a geography point generated from their coordinates,
+-- with a spatial index for near queries. Without it the migration changes nothing, and distances are
+-- computed from latitude and longitude with the haversine formula instead.
+DO $$
+BEGIN
+    IF NOT EXISTS (SELECT 1 FROM pg_available_extensions WHERE name = 'postgis') THEN
+        RAISE NOTICE 'PostGIS is not available, near queries will use the haversine formula';
+        RETURN;
+    END IF;
+
+    BEGIN
+        CREATE EXTENSION IF NOT EXISTS postgis;
+    EXCEPTION WHEN insufficient_privilege THEN
+        RAISE NOTICE 'Not allowed to create the PostGIS extension, near queries will use the haversine formula';
+        RETURN;
+    END;
+
+    ALTER TABLE trails ADD COLUMN IF NOT EXISTS location geography(Point, 4326)
+        GENERATED ALWAYS AS (ST_SetSRID(ST_MakePoint(longitude, latitude), 4326)::geography) STORED;
+    CREATE INDEX IF NOT EXISTS trails_location_idx ON trails USING GIST (location);
+END
+$$;
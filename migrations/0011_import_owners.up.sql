@@ -0,0 +1,6 @@
+-- Import jobs belong to the server instance that queued them, which records a heartbeat on its unfinished jobs
+-- while it runs. Other instances only fail the unfinished jobs whose heartbeat stopped, so replicas sharing the
+-- database no longer fail each other's imports when one of them restarts.
+ALTER TABLE imports
+    ADD COLUMN IF NOT EXISTS owner TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ;
@@ -0,0 +1,12 @@
+ALTER TABLE trail_versions ALTER COLUMN ada_parking SET DEFAULT '';
+ALTER TABLE trails
+    DROP CONSTRAINT IF EXISTS trails_ada_parking_check,
+    ALTER COLUMN ada_parking SET DEFAULT '';
+
+ALTER TABLE trail_versions
+    DROP COLUMN IF EXISTS ada_parking_count,
+    DROP COLUMN IF EXISTS horse_trail_count;
+
+ALTER TABLE trails
+    DROP COLUMN IF EXISTS ada_parking_count,
+    DROP COLUMN IF EXISTS horse_trail_count;
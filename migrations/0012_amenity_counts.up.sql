@@ -0,0 +1,33 @@
+-- The source data counts some amenities: "Designated x 2" horse trails and "Yes x 2" ADA parking. The count
+-- is kept in a column of its own next to the enum value. Trails stored before this version lost their horse
+-- trail count when version 3 normalized the values, so it stays unset until the next load.
+ALTER TABLE trails
+    ADD COLUMN IF NOT EXISTS horse_trail_count INTEGER
+        CONSTRAINT trails_horse_trail_count_check CHECK (horse_trail_count >= 0),
+    ADD COLUMN IF NOT EXISTS ada_parking_count INTEGER
+        CONSTRAINT trails_ada_parking_count_check CHECK (ada_parking_count >= 0);
+
+ALTER TABLE trail_versions
+    ADD COLUMN IF NOT EXISTS horse_trail_count INTEGER,
+    ADD COLUMN IF NOT EXISTS ada_parking_count INTEGER;
+
+-- ADA parking holds only yes, no and offsite, so it becomes an enum like horse_trail; blank values mean no
+UPDATE trails SET ada_parking_count = SUBSTRING(ada_parking FROM 'x\s*([0-9]+)\s*$')::INTEGER
+WHERE ada_parking ~ 'x\s*[0-9]+\s*$';
+UPDATE trails SET ada_parking = CASE
+    WHEN LOWER(TRIM(ada_parking)) LIKE 'yes%' THEN 'yes'
+    WHEN LOWER(TRIM(ada_parking)) = 'offsite' THEN 'offsite'
+    ELSE 'no'
+END;
+UPDATE trail_versions SET ada_parking_count = SUBSTRING(ada_parking FROM 'x\s*([0-9]+)\s*$')::INTEGER
+WHERE ada_parking ~ 'x\s*[0-9]+\s*$';
+UPDATE trail_versions SET ada_parking = CASE
+    WHEN LOWER(TRIM(ada_parking)) LIKE 'yes%' THEN 'yes'
+    WHEN LOWER(TRIM(ada_parking)) = 'offsite' THEN 'offsite'
+    ELSE 'no'
+END;
+
+ALTER TABLE trails
+    ALTER COLUMN ada_parking SET DEFAULT 'no',
+    ADD CONSTRAINT trails_ada_parking_check CHECK (ada_parking IN ('yes', 'no', 'offsite'));
+ALTER TABLE trail_versions ALTER COLUMN ada_parking SET DEFAULT 'no';
@@ -0,0 +1,16 @@
+// Package migrations embeds the versioned SQL migrations that define the trails schema.
+// They are the single source of truth for the schema: the server, trail-cli and the tests all apply them.
+package migrations
+
+import "embed"
+
+// FS holds the PostgreSQL migration files in golang-migrate's {version}_{title}.{up|down}.sql layout
+//
+//go:embed *.sql
+var FS embed.FS
+
+// SQLiteFS holds the SQLite migrations under sqlite/. They share version numbers with the PostgreSQL
+// migrations, starting at the version whose schema they first create.
+//
+//go:embed sqlite/*.sql
+var SQLiteFS embed.FS
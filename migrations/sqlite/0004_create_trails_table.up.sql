@@ -0,0 +1,38 @@
+-- SQLite databases start from the trails schema as of version 4 of the PostgreSQL migrations.
+-- Booleans are stored as 0/1 and dates as timestamps; counts that were not recorded are NULL.
+CREATE TABLE IF NOT EXISTS trails (
+    fid INTEGER PRIMARY KEY,
+    name TEXT NOT NULL DEFAULT '',
+    restrooms BOOLEAN NOT NULL DEFAULT FALSE,
+    picnic BOOLEAN NOT NULL DEFAULT FALSE,
+    fishing BOOLEAN NOT NULL DEFAULT FALSE,
+    type TEXT NOT NULL DEFAULT '',
+    difficulty TEXT NOT NULL DEFAULT '',
+    access_type TEXT NOT NULL DEFAULT '',
+    th_leash BOOLEAN NOT NULL DEFAULT FALSE,
+    bike_trail BOOLEAN NOT NULL DEFAULT FALSE,
+    horse_trail TEXT NOT NULL DEFAULT 'na'
+        CONSTRAINT trails_horse_trail_check
+        CHECK (horse_trail IN ('possible', 'not_recommended', 'designated', 'not_allowed', 'pull_through', 'na')),
+    fee BOOLEAN NOT NULL DEFAULT FALSE,
+    recycle_bin BOOLEAN NOT NULL DEFAULT FALSE,
+    grills BOOLEAN NOT NULL DEFAULT FALSE,
+    bike_rack BOOLEAN NOT NULL DEFAULT FALSE,
+    dog_tube INTEGER,
+    aka TEXT NOT NULL DEFAULT '',
+    address TEXT NOT NULL DEFAULT '',
+    access_id TEXT NOT NULL DEFAULT '',
+    trash_cans INTEGER,
+    park_spaces INTEGER,
+    ada_surface TEXT NOT NULL DEFAULT '',
+    ada_toilet BOOLEAN NOT NULL DEFAULT FALSE,
+    ada_fishing BOOLEAN NOT NULL DEFAULT FALSE,
+    ada_camping BOOLEAN NOT NULL DEFAULT FALSE,
+    ada_picnic BOOLEAN NOT NULL DEFAULT FALSE,
+    ada_parking TEXT NOT NULL DEFAULT '',
+    ada_facility BOOLEAN NOT NULL DEFAULT FALSE,
+    ada_facility_name TEXT NOT NULL DEFAULT '',
+    date_from TIMESTAMP,
+    date_to TIMESTAMP,
+    dog_compost BOOLEAN NOT NULL DEFAULT FALSE
+);
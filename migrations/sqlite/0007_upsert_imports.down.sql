@@ -0,0 +1,8 @@
+ALTER TABLE imports DROP COLUMN trails_deleted;
+ALTER TABLE imports DROP COLUMN trails_unchanged;
+ALTER TABLE imports DROP COLUMN trails_updated;
+ALTER TABLE imports DROP COLUMN trails_inserted;
+ALTER TABLE imports DROP COLUMN mode;
+
+DELETE FROM trails WHERE deleted_at IS NOT NULL;
+ALTER TABLE trails DROP COLUMN deleted_at;
@@ -0,0 +1,10 @@
+-- Soft deletes and upsert import counts, as in version 7 of the PostgreSQL migrations
+ALTER TABLE trails ADD COLUMN deleted_at TIMESTAMP;
+
+ALTER TABLE imports ADD COLUMN mode TEXT NOT NULL DEFAULT 'replace'
+    CONSTRAINT imports_mode_check
+    CHECK (mode IN ('replace', 'upsert'));
+ALTER TABLE imports ADD COLUMN trails_inserted INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE imports ADD COLUMN trails_updated INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE imports ADD COLUMN trails_unchanged INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE imports ADD COLUMN trails_deleted INTEGER NOT NULL DEFAULT 0;
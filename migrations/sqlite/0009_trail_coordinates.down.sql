@@ -0,0 +1,5 @@
+ALTER TABLE trail_versions DROP COLUMN longitude;
+ALTER TABLE trail_versions DROP COLUMN latitude;
+
+ALTER TABLE trails DROP COLUMN longitude;
+ALTER TABLE trails DROP COLUMN latitude;
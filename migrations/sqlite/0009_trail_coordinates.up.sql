@@ -0,0 +1,8 @@
+-- Trailhead coordinates, as in version 9 of the PostgreSQL migrations
+ALTER TABLE trails ADD COLUMN latitude REAL
+    CONSTRAINT trails_latitude_check CHECK (latitude BETWEEN -90 AND 90);
+ALTER TABLE trails ADD COLUMN longitude REAL
+    CONSTRAINT trails_longitude_check CHECK (longitude BETWEEN -180 AND 180);
+
+ALTER TABLE trail_versions ADD COLUMN latitude REAL;
+ALTER TABLE trail_versions ADD COLUMN longitude REAL;
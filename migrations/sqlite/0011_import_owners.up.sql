@@ -0,0 +1,3 @@
+-- Import owners and heartbeats, as in version 11 of the PostgreSQL migrations
+ALTER TABLE imports ADD COLUMN owner TEXT NOT NULL DEFAULT '';
+ALTER TABLE imports ADD COLUMN heartbeat_at TIMESTAMP;
@@ -0,0 +1,5 @@
+ALTER TABLE trail_versions DROP COLUMN ada_parking_count;
+ALTER TABLE trail_versions DROP COLUMN horse_trail_count;
+
+ALTER TABLE trails DROP COLUMN ada_parking_count;
+ALTER TABLE trails DROP COLUMN horse_trail_count;
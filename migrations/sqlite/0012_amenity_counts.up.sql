@@ -0,0 +1,25 @@
+-- Amenity counts and the ada_parking enum, as in version 12 of the PostgreSQL migrations. SQLite cannot add
+-- a CHECK constraint to an existing column, so ada_parking values are only checked by the application, which
+-- validates every trail it writes.
+ALTER TABLE trails ADD COLUMN horse_trail_count INTEGER
+    CONSTRAINT trails_horse_trail_count_check CHECK (horse_trail_count >= 0);
+ALTER TABLE trails ADD COLUMN ada_parking_count INTEGER
+    CONSTRAINT trails_ada_parking_count_check CHECK (ada_parking_count >= 0);
+
+ALTER TABLE trail_versions ADD COLUMN horse_trail_count INTEGER;
+ALTER TABLE trail_versions ADD COLUMN ada_parking_count INTEGER;
+
+UPDATE trails SET ada_parking_count = CAST(TRIM(SUBSTR(ada_parking, INSTR(ada_parking, 'x') + 1)) AS INTEGER)
+WHERE LOWER(ada_parking) GLOB 'yes*x*[0-9]';
+UPDATE trails SET ada_parking = CASE
+    WHEN LOWER(TRIM(ada_parking)) LIKE 'yes%' THEN 'yes'
+    WHEN LOWER(TRIM(ada_parking)) = 'offsite' THEN 'offsite'
+    ELSE 'no'
+END;
+UPDATE trail_versions SET ada_parking_count = CAST(TRIM(SUBSTR(ada_parking, INSTR(ada_parking, 'x') + 1)) AS INTEGER)
+WHERE LOWER(ada_parking) GLOB 'yes*x*[0-9]';
+UPDATE trail_versions SET ada_parking = CASE
+    WHEN LOWER(TRIM(ada_parking)) LIKE 'yes%' THEN 'yes'
+    WHEN LOWER(TRIM(ada_parking)) = 'offsite' THEN 'offsite'
+    ELSE 'no'
+END;
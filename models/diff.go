@@ -0,0 +1,94 @@
+package models
+
+import (
+    "sort"
+    "time"
+)
+
+// TrailSummary identifies a trail in a dataset diff
+type TrailSummary struct {
+    FID  int    `json:"fid"`
+    Name string `json:"name"`
+}
+
+// FieldChange is a column whose value differs between the stored and the incoming trail
+type FieldChange struct {
+    Field string      `json:"field"`
+    Old   interface{} `json:"old"`
+    New   interface{} `json:"new"`
+}
+
+// TrailChange lists the changed columns of a trail present in both datasets
+type TrailChange struct {
+    FID    int           `json:"fid"`
+    Name   string        `json:"name"` // Name of the incoming trail
+    Fields []FieldChange `json:"fields"`
+}
+
+// DatasetDiff is what replacing the stored trails with an incoming dataset would change, keyed by FID
+type DatasetDiff struct {
+    Added     []TrailSummary `json:"added"`
+    Removed   []TrailSummary `json:"removed"`
+    Changed   []TrailChange  `json:"changed"`
+    Unchanged int            `json:"unchanged"`
+}
+
+// Empty reports whether the incoming dataset matches the stored one
+func (d DatasetDiff) Empty() bool {
+    return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0
+}
+
+// DiffTrails compares the stored trails with an incoming dataset. Each list is ordered by FID.
+func DiffTrails(stored, incoming []Trail) DatasetDiff {
+    diff := DatasetDiff{Added: []TrailSummary{}, Removed: []TrailSummary{}, Changed: []TrailChange{}}
+
+    current := make(map[int]Trail, len(stored))
+    for _, t := range stored {
+        current[t.FID] = t
+    }
+    for _, t := range incoming {
+        old, ok := current[t.FID]
+        if !ok {
+            diff.Added = append(diff.Added, TrailSummary{FID: t.FID, Name: t.Name})
+            continue
+        }
+        delete(current, t.FID)
+
+        if fields := diffFields(&old, &t); len(fields) > 0 {
+            diff.Changed = append(diff.Changed, TrailChange{FID: t.FID, Name: t.Name, Fields: fields})
+        } else {
+            diff.Unchanged++
+        }
+    }
+    for _, t := range current {
+        diff.Removed = append(diff.Removed, TrailSummary{FID: t.FID, Name: t.Name})
+    }
+
+    sort.Slice(diff.Added, func(i, j int) bool { return diff.Added[i].FID < diff.Added[j].FID })
+    sort.Slice(diff.Removed, func(i, j int) bool { return diff.Removed[i].FID < diff.Removed[j].FID })
+    sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].FID < diff.Changed[j].FID })
+    return diff
+}
+
+// diffFields returns the columns whose values differ between two trails, in TrailColumns order
+func diffFields(old, new *Trail) []FieldChange {
+    var changes []FieldChange
+    for _, column := range TrailColumns {
+        before, _ := old.FieldValue(column)
+        after, _ := new.FieldValue(column)
+        if !sameValue(before, after) {
+            changes = append(changes, FieldChange{Field: column, Old: before, New: after})
+        }
+    }
+    return changes
+}
+
+// sameValue compares two column values, treating timestamps at the same instant as equal
+func sameValue(a, b interface{}) bool {
+    at, aTime := a.(time.Time)
+    bt, bTime := b.(time.Time)
+    if aTime && bTime {
+        return at.Equal(bt)
+    }
+    return a == b
+}
@@ -0,0 +1,38 @@
+package models
+
+import (
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestDiffTrails(t *testing.T) {
+    date := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+    local := date.In(time.FixedZone("MST", -7*60*60))
+    spaces := 12
+
+    stored := []Trail{
+        {FID: 3, Name: "mesa", Restrooms: true, DateFrom: &date},
+        {FID: 1, Name: "chautauqua", ParkSpaces: &spaces},
+        {FID: 2, Name: "flagstaff"},
+    }
+    incoming := []Trail{
+        {FID: 3, Name: "mesa", Restrooms: true, DateFrom: &local},
+        {FID: 1, Name: "chautauqua park"},
+        {FID: 5, Name: "wonderland"},
+        {FID: 4, Name: "sawhill"},
+    }
+
+    diff := DiffTrails(stored, incoming)
+    assert.Equal(t, []TrailSummary{{FID: 4, Name: "sawhill"}, {FID: 5, Name: "wonderland"}}, diff.Added)
+    assert.Equal(t, []TrailSummary{{FID: 2, Name: "flagstaff"}}, diff.Removed)
+    assert.Equal(t, []TrailChange{{FID: 1, Name: "chautauqua park", Fields: []FieldChange{
+        {Field: "name", Old: "chautauqua", New: "chautauqua park"},
+        {Field: "park_spaces", Old: 12, New: nil},
+    }}}, diff.Changed)
+    assert.Equal(t, 1, diff.Unchanged, "Expected the same date in another zone to be unchanged")
+    assert.False(t, diff.Empty())
+
+    assert.True(t, DiffTrails(stored, stored).Empty())
+}
@@ -0,0 +1,34 @@
+package models
+
+import "math"
+
+// EarthRadiusKm is the mean radius of the Earth. Distances are measured on a sphere of this radius, as PostGIS
+// does for geography values when it is not using the spheroid, so every store reports the same distances.
+const EarthRadiusKm = 6371.0088
+
+// Point is a location in WGS 84 decimal degrees
+type Point struct {
+    Latitude  float64 `json:"latitude"`
+    Longitude float64 `json:"longitude"`
+}
+
+// DistanceKm returns the great-circle distance between two points, computed with the haversine formula
+func DistanceKm(a, b Point) float64 {
+    dLat := radians(b.Latitude - a.Latitude)
+    dLon := radians(b.Longitude - a.Longitude)
+    h := math.Pow(math.Sin(dLat/2), 2) + math.Cos(radians(a.Latitude))*math.Cos(radians(b.Latitude))*math.Pow(math.Sin(dLon/2), 2)
+    return EarthRadiusKm * 2 * math.Asin(math.Sqrt(math.Min(h, 1)))
+}
+
+// DistanceFrom returns the distance of the trail from a point, or false when the trail has no coordinates
+func (t *Trail) DistanceFrom(p Point) (float64, bool) {
+    if t.Latitude == nil || t.Longitude == nil {
+        return 0, false
+    }
+    return DistanceKm(p, Point{Latitude: *t.Latitude, Longitude: *t.Longitude}), true
+}
+
+// radians converts degrees to radians
+func radians(degrees float64) float64 {
+    return degrees * math.Pi / 180
+}
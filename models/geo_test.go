@@ -0,0 +1,30 @@
+package models
+
+import (
+    "math"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestDistanceKm(t *testing.T) {
+    boulder := Point{Latitude: 40.015, Longitude: -105.2705}
+    denver := Point{Latitude: 39.7392, Longitude: -104.9903}
+
+    assert.InDelta(t, 38.8, DistanceKm(boulder, denver), 0.1)
+    assert.Equal(t, DistanceKm(boulder, denver), DistanceKm(denver, boulder))
+    assert.Zero(t, DistanceKm(boulder, boulder))
+    assert.InDelta(t, math.Pi*EarthRadiusKm, DistanceKm(Point{Latitude: 0, Longitude: 0}, Point{Latitude: 0, Longitude: 180}), 1e-6)
+}
+
+func TestTrailDistanceFrom(t *testing.T) {
+    lat, lon := 40.0, -105.0
+    trail := Trail{Latitude: &lat}
+    _, ok := trail.DistanceFrom(Point{Latitude: lat, Longitude: lon})
+    assert.False(t, ok, "trails without both coordinates have no distance")
+
+    trail.Longitude = &lon
+    distance, ok := trail.DistanceFrom(Point{Latitude: 41, Longitude: lon})
+    assert.True(t, ok)
+    assert.InDelta(t, EarthRadiusKm*math.Pi/180, distance, 1e-9)
+}
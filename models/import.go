@@ -0,0 +1,110 @@
+package models
+
+import (
+    "fmt"
+    "time"
+)
+
+// ImportState is the lifecycle state of an import job
+type ImportState string
+
+// ImportState values; jobs move from queued to running, then to succeeded or failed
+const (
+    ImportQueued    ImportState = "queued"
+    ImportRunning   ImportState = "running"
+    ImportSucceeded ImportState = "succeeded"
+    ImportFailed    ImportState = "failed"
+)
+
+// Finished reports whether the job has stopped, successfully or not
+func (s ImportState) Finished() bool {
+    return s == ImportSucceeded || s == ImportFailed
+}
+
+// ImportMode is how an import applies the trails of a CSV to the stored ones
+type ImportMode string
+
+// ImportMode values
+const (
+    ImportReplace ImportMode = "replace" // Delete every stored trail and insert the CSV rows
+    ImportUpsert  ImportMode = "upsert"  // Insert new FIDs and update changed ones, keeping the other stored trails
+)
+
+// ParseImportMode parses an import mode, defaulting to replace when it is blank
+func ParseImportMode(value string) (ImportMode, error) {
+    switch mode := ImportMode(value); mode {
+    case "":
+        return ImportReplace, nil
+    case ImportReplace, ImportUpsert:
+        return mode, nil
+    default:
+        return "", fmt.Errorf("invalid mode %q: must be replace or upsert", value)
+    }
+}
+
+// MergeCounts counts what an upsert import did to each trail
+type MergeCounts struct {
+    Inserted  int `json:"trails_inserted"`  // Trails with a FID that was not stored
+    Updated   int `json:"trails_updated"`   // Stored trails with at least one changed column
+    Unchanged int `json:"trails_unchanged"` // Stored trails the CSV left as they were
+    Deleted   int `json:"trails_deleted"`   // Stored trails missing from the CSV that were soft-deleted
+}
+
+// RejectedRow is one reason a CSV row was rejected; a row with several invalid values has one entry for each
+type RejectedRow struct {
+    Line   int    `json:"line"`             // Line of the row in the CSV, counting the header as line 1, or number of the GeoJSON feature
+    Column string `json:"column,omitempty"` // CSV header or GeoJSON property of the invalid value, blank when the whole row is at fault
+    Value  string `json:"value"`            // The raw value as it appears in the CSV
+    Reason string `json:"reason"`           // Why the value was rejected
+}
+
+// ImportReport is the row-level validation report of a CSV import
+type ImportReport struct {
+    Mode         ImportMode    `json:"mode"`
+    RowsRead     int           `json:"rows_read"`     // Data rows read, not counting the header
+    RowsImported int           `json:"rows_imported"` // Rows stored as trails
+    RowsRejected int           `json:"rows_rejected"` // Rows skipped because they could not be converted
+    Rejected     []RejectedRow `json:"rejected_rows"` // Why each rejected row was skipped
+    MergeCounts                // Set by upsert imports
+    Diff         *DatasetDiff  `json:"diff,omitempty"` // What a dry run would change; dry runs import nothing
+    Version      int           `json:"version,omitempty"` // Dataset version recorded by a successful import
+}
+
+// Reject counts a rejected row and records the reasons it was rejected
+func (r *ImportReport) Reject(reasons ...RejectedRow) {
+    r.RowsRejected++
+    r.Rejected = append(r.Rejected, reasons...)
+}
+
+// ImportJob records one CSV import: where it came from, how far it got and what it rejected.
+// The report of a job keeps every rejected row entry; the API returns them a page at a time.
+type ImportJob struct {
+    ID     string      `json:"id"`
+    State  ImportState `json:"state"`
+    Source string      `json:"source"` // Uploaded file name or server-side path
+    ImportReport
+    Error       string     `json:"error,omitempty"`
+    Owner       string     `json:"owner,omitempty"` // Server instance that queued the job and runs it
+    CreatedAt   time.Time  `json:"created_at"`
+    StartedAt   *time.Time `json:"started_at"`
+    FinishedAt  *time.Time `json:"finished_at"`
+    HeartbeatAt *time.Time `json:"heartbeat_at,omitempty"` // When the owner last reported the unfinished job alive
+}
+
+// ImportJobPage is an import job whose report holds one page of its rejected row entries
+type ImportJobPage struct {
+    ImportJob
+    RejectedTotal  int  `json:"rejected_total"`  // Rejected row entries in the whole report
+    RejectedOffset int  `json:"rejected_offset"` // Position of the page's first entry in the report
+    Truncated      bool `json:"truncated"`       // Whether entries of the report were left out of the page
+}
+
+// PageRejected returns the job with up to limit of its rejected row entries, starting at offset
+func (j ImportJob) PageRejected(offset, limit int) ImportJobPage {
+    page := ImportJobPage{ImportJob: j, RejectedTotal: len(j.Rejected), RejectedOffset: offset}
+    start := min(offset, len(j.Rejected))
+    end := min(start+limit, len(j.Rejected))
+    page.Rejected = j.Rejected[start:end:end]
+    page.Truncated = end-start < len(j.Rejected)
+    return page
+}
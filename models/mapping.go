@@ -0,0 +1,165 @@
+package models
+
+import (
+    "encoding/json"
+    "fmt"
+    "os"
+    "strings"
+)
+
+// ColumnSpec describes how a single trails column is located in a source file
+type ColumnSpec struct {
+    Field    string   `json:"field"`    // Column name in the trails table
+    Header   string   `json:"header"`   // Preferred header name in the source file
+    Aliases  []string `json:"aliases"`  // Alternate header names accepted for this column
+    Required bool     `json:"required"` // Whether the source file must provide this column
+}
+
+// ColumnMapping is a declarative mapping from source file headers to trails columns
+type ColumnMapping struct {
+    Columns []ColumnSpec `json:"columns"`
+}
+
+// ColumnIndex holds the resolved position of each mapped field in a source row
+type ColumnIndex map[string]int
+
+// DefaultMapping returns the mapping for the Boulder County trailheads CSV
+func DefaultMapping() *ColumnMapping {
+    return &ColumnMapping{
+        Columns: []ColumnSpec{
+            {Field: "fid", Header: "FID", Aliases: []string{"OBJECTID"}, Required: true},
+            {Field: "name", Header: "AccessName", Aliases: []string{"Name", "TrailName"}, Required: true},
+            {Field: "restrooms", Header: "RESTROOMS", Aliases: []string{"Restroom"}},
+            {Field: "picnic", Header: "PICNIC"},
+            {Field: "fishing", Header: "FISHING"},
+            {Field: "type", Header: "Class", Aliases: []string{"Type"}},
+            {Field: "difficulty", Header: "ADAtrail", Aliases: []string{"Difficulty"}},
+            {Field: "access_type", Header: "AccessType"},
+            {Field: "th_leash", Header: "THLeash", Aliases: []string{"Leash"}},
+            {Field: "bike_trail", Header: "BikeTrail"},
+            {Field: "horse_trail", Header: "HorseTrail"},
+            {Field: "fee", Header: "Fee"},
+            {Field: "recycle_bin", Header: "RecycleBin"},
+            {Field: "grills", Header: "Grills"},
+            {Field: "bike_rack", Header: "BikeRack"},
+            {Field: "dog_tube", Header: "DogTube"},
+            {Field: "aka", Header: "AKA"},
+            {Field: "address", Header: "Address"},
+            {Field: "access_id", Header: "AccessID"},
+            {Field: "trash_cans", Header: "TrashCans"},
+            {Field: "park_spaces", Header: "ParkSpaces"},
+            {Field: "ada_surface", Header: "ADAsurface"},
+            {Field: "ada_toilet", Header: "ADAtoilet"},
+            {Field: "ada_fishing", Header: "ADAfishing"},
+            {Field: "ada_camping", Header: "ADAcamping"},
+            {Field: "ada_picnic", Header: "ADApicnic"},
+            {Field: "ada_parking", Header: "ADAparking"},
+            {Field: "ada_facility", Header: "ADAfacilit", Aliases: []string{"ADAfacility"}},
+            {Field: "ada_facility_name", Header: "ADAfacName", Aliases: []string{"ADAfacilityName"}},
+            {Field: "date_from", Header: "DateFrom"},
+            {Field: "date_to", Header: "DateTo"},
+            {Field: "dog_compost", Header: "DogCompost"},
+            {Field: "latitude", Header: "Latitude", Aliases: []string{"LAT", "Y"}},
+            {Field: "longitude", Header: "Longitude", Aliases: []string{"LON", "LONG", "X"}},
+        },
+    }
+}
+
+// LoadMapping reads a column mapping from a JSON file
+func LoadMapping(path string) (*ColumnMapping, error) {
+    data, err := os.ReadFile(path)
+    if err != nil {
+        return nil, fmt.Errorf("could not read mapping file: %w", err)
+    }
+
+    var mapping ColumnMapping
+    if err := json.Unmarshal(data, &mapping); err != nil {
+        return nil, fmt.Errorf("could not parse mapping file: %w", err)
+    }
+
+    if err := mapping.Validate(); err != nil {
+        return nil, err
+    }
+    return &mapping, nil
+}
+
+// Validate checks that the mapping is usable before it is applied to a file
+func (m *ColumnMapping) Validate() error {
+    if len(m.Columns) == 0 {
+        return fmt.Errorf("column mapping has no columns")
+    }
+
+    seen := map[string]bool{}
+    for _, col := range m.Columns {
+        if col.Field == "" {
+            return fmt.Errorf("column mapping entry is missing a field name")
+        }
+        if col.Header == "" && len(col.Aliases) == 0 {
+            return fmt.Errorf("column mapping for %q has no header or aliases", col.Field)
+        }
+        if seen[col.Field] {
+            return fmt.Errorf("column mapping has duplicate field %q", col.Field)
+        }
+        seen[col.Field] = true
+    }
+
+    if !seen["fid"] {
+        return fmt.Errorf("column mapping must include the fid field")
+    }
+    return nil
+}
+
+// Resolve matches the mapping against a header row and returns the index of each field.
+// Header names are matched case-insensitively; missing required headers are reported together.
+func (m *ColumnMapping) Resolve(header []string) (ColumnIndex, error) {
+    positions := make(map[string]int, len(header))
+    for i, name := range header {
+        key := normalizeHeader(name)
+        if _, exists := positions[key]; !exists {
+            positions[key] = i
+        }
+    }
+
+    index := ColumnIndex{}
+    var missing []string
+    for _, col := range m.Columns {
+        found := false
+        for _, name := range append([]string{col.Header}, col.Aliases...) {
+            if pos, ok := positions[normalizeHeader(name)]; ok && name != "" {
+                index[col.Field] = pos
+                found = true
+                break
+            }
+        }
+        if !found && col.Required {
+            missing = append(missing, col.displayName())
+        }
+    }
+
+    if len(missing) > 0 {
+        return nil, fmt.Errorf("missing required headers: %s", strings.Join(missing, ", "))
+    }
+    return index, nil
+}
+
+// Value returns the trimmed value of a field in the row, or an empty string if it is not mapped
+func (idx ColumnIndex) Value(row []string, field string) string {
+    pos, ok := idx[field]
+    if !ok || pos >= len(row) {
+        return ""
+    }
+    return strings.TrimSpace(row[pos])
+}
+
+// displayName returns the header name used when reporting this column
+func (c ColumnSpec) displayName() string {
+    if c.Header != "" {
+        return c.Header
+    }
+    return c.Aliases[0]
+}
+
+// normalizeHeader strips whitespace and a UTF-8 byte order mark and lowercases the header
+func normalizeHeader(name string) string {
+    return strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+}
@@ -0,0 +1,59 @@
+package models
+
+import (
+    "os"
+    "path/filepath"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestResolveReorderedHeaders(t *testing.T) {
+    mapping := &ColumnMapping{Columns: []ColumnSpec{
+        {Field: "fid", Header: "FID", Required: true},
+        {Field: "name", Header: "AccessName", Aliases: []string{"Name"}, Required: true},
+        {Field: "fee", Header: "Fee"},
+    }}
+
+    // Headers are reordered, differently cased and use an alias for the name column
+    columns, err := mapping.Resolve([]string{"\ufeffName", " fee ", "fid"})
+    assert.Nil(t, err, "Expected headers to resolve")
+
+    row := []string{"Flagstaff Summit West", "Yes", "0"}
+    assert.Equal(t, "0", columns.Value(row, "fid"))
+    assert.Equal(t, "Flagstaff Summit West", columns.Value(row, "name"))
+    assert.Equal(t, "Yes", columns.Value(row, "fee"))
+    assert.Equal(t, "", columns.Value(row, "grills"), "Expected unmapped field to be empty")
+}
+
+func TestResolveMissingRequiredHeaders(t *testing.T) {
+    _, err := DefaultMapping().Resolve([]string{"RESTROOMS", "PICNIC"})
+    assert.NotNil(t, err, "Expected an error for missing required headers")
+    assert.Contains(t, err.Error(), "FID")
+    assert.Contains(t, err.Error(), "AccessName")
+}
+
+func TestResolveOptionalHeaderMissing(t *testing.T) {
+    columns, err := DefaultMapping().Resolve([]string{"FID", "AccessName"})
+    assert.Nil(t, err, "Expected optional headers to be allowed to be missing")
+    assert.Equal(t, "", columns.Value([]string{"1", "Foothills"}, "restrooms"))
+}
+
+func TestLoadMapping(t *testing.T) {
+    path := filepath.Join(t.TempDir(), "mapping.json")
+    err := os.WriteFile(path, []byte(`{"columns": [
+        {"field": "fid", "header": "OBJECTID", "required": true},
+        {"field": "name", "header": "TRAIL_NAME", "aliases": ["NAME"], "required": true}
+    ]}`), 0o644)
+    assert.Nil(t, err)
+
+    mapping, err := LoadMapping(path)
+    assert.Nil(t, err, "Expected mapping file to load")
+    assert.Len(t, mapping.Columns, 2)
+
+    // A mapping without the fid field is rejected
+    err = os.WriteFile(path, []byte(`{"columns": [{"field": "name", "header": "NAME"}]}`), 0o644)
+    assert.Nil(t, err)
+    _, err = LoadMapping(path)
+    assert.NotNil(t, err, "Expected mapping without fid to be rejected")
+}
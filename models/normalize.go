@@ -0,0 +1,251 @@
+package models
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+)
+
+// HorseTrail describes whether horses can use the trails at a trailhead
+type HorseTrail string
+
+// HorseTrail values, as stored in the trails table
+const (
+    HorseTrailPossible       HorseTrail = "possible"
+    HorseTrailNotRecommended HorseTrail = "not_recommended"
+    HorseTrailDesignated     HorseTrail = "designated"
+    HorseTrailNotAllowed     HorseTrail = "not_allowed"
+    HorseTrailPullThrough    HorseTrail = "pull_through"
+    HorseTrailNA             HorseTrail = "na"
+)
+
+// HorseTrailValues lists every accepted HorseTrail value
+var HorseTrailValues = []HorseTrail{
+    HorseTrailPossible, HorseTrailNotRecommended, HorseTrailDesignated,
+    HorseTrailNotAllowed, HorseTrailPullThrough, HorseTrailNA,
+}
+
+// Valid reports whether h is one of the stored HorseTrail values
+func (h HorseTrail) Valid() bool {
+    for _, v := range HorseTrailValues {
+        if h == v {
+            return true
+        }
+    }
+    return false
+}
+
+// ADAParking describes the accessible parking at a trailhead
+type ADAParking string
+
+// ADAParking values, as stored in the trails table
+const (
+    ADAParkingYes     ADAParking = "yes"
+    ADAParkingNo      ADAParking = "no"
+    ADAParkingOffsite ADAParking = "offsite"
+)
+
+// ADAParkingValues lists every accepted ADAParking value
+var ADAParkingValues = []ADAParking{ADAParkingYes, ADAParkingNo, ADAParkingOffsite}
+
+// Valid reports whether a is one of the stored ADAParking values
+func (a ADAParking) Valid() bool {
+    for _, v := range ADAParkingValues {
+        if a == v {
+            return true
+        }
+    }
+    return false
+}
+
+// FieldKind identifies how the values of a trails column are typed
+type FieldKind int
+
+const (
+    KindText FieldKind = iota
+    KindBool
+    KindCount
+    KindHorseTrail
+    KindADAParking
+    KindDate
+    KindCoordinate
+)
+
+// FieldKinds maps each trails column to the kind of value it holds
+var FieldKinds = map[string]FieldKind{
+    "fid":               KindCount,
+    "name":              KindText,
+    "restrooms":         KindBool,
+    "picnic":            KindBool,
+    "fishing":           KindBool,
+    "type":              KindText,
+    "difficulty":        KindText,
+    "access_type":       KindText,
+    "th_leash":          KindBool,
+    "bike_trail":        KindBool,
+    "horse_trail":       KindHorseTrail,
+    "horse_trail_count": KindCount,
+    "fee":               KindBool,
+    "recycle_bin":       KindBool,
+    "grills":            KindBool,
+    "bike_rack":         KindBool,
+    "dog_tube":          KindCount,
+    "aka":               KindText,
+    "address":           KindText,
+    "access_id":         KindText,
+    "trash_cans":        KindCount,
+    "park_spaces":       KindCount,
+    "ada_surface":       KindText,
+    "ada_toilet":        KindBool,
+    "ada_fishing":       KindBool,
+    "ada_camping":       KindBool,
+    "ada_picnic":        KindBool,
+    "ada_parking":       KindADAParking,
+    "ada_parking_count": KindCount,
+    "ada_facility":      KindBool,
+    "ada_facility_name": KindText,
+    "date_from":         KindDate,
+    "date_to":           KindDate,
+    "dog_compost":       KindBool,
+    "latitude":          KindCoordinate,
+    "longitude":         KindCoordinate,
+}
+
+// ParseYesNo converts a yes/no style value to a boolean, treating a blank value as false
+func ParseYesNo(value string) (bool, error) {
+    switch strings.ToLower(strings.TrimSpace(value)) {
+    case "yes", "y", "true", "t", "1":
+        return true, nil
+    case "no", "n", "false", "f", "0", "":
+        return false, nil
+    }
+    return false, fmt.Errorf("%q is not a yes/no value", value)
+}
+
+// ParseHorseTrail converts a source or query value to a HorseTrail.
+// Source values such as "Designated x 2" and "Not Recommended" are accepted alongside the stored form;
+// use SplitAmenityCount first to keep the count of a "Designated x 2" value.
+func ParseHorseTrail(value string) (HorseTrail, error) {
+    key := strings.ToLower(strings.TrimSpace(value))
+    key = strings.Join(strings.FieldsFunc(key, func(r rune) bool { return r == ' ' || r == '_' || r == '-' }), "_")
+
+    if strings.HasPrefix(key, "designated") {
+        return HorseTrailDesignated, nil
+    }
+    switch key {
+    case "", "na", "n/a":
+        return HorseTrailNA, nil
+    }
+    for _, v := range HorseTrailValues {
+        if key == string(v) {
+            return v, nil
+        }
+    }
+    return "", fmt.Errorf("%q is not a valid horse_trail value (expected one of %s)", value, joinHorseTrailValues())
+}
+
+// ParseADAParking converts a source or query value to an ADAParking, treating a blank value as no.
+// Counted source values such as "Yes x 2" are accepted as yes.
+func ParseADAParking(value string) (ADAParking, error) {
+    key, _ := SplitAmenityCount(strings.ToLower(strings.TrimSpace(value)))
+    if key == "" {
+        return ADAParkingNo, nil
+    }
+    for _, v := range ADAParkingValues {
+        if key == string(v) {
+            return v, nil
+        }
+    }
+    return "", fmt.Errorf("%q is not a valid ada_parking value (expected one of %s)", value, joinADAParkingValues())
+}
+
+// SplitAmenityCount separates the count from a source amenity value such as "Designated x 2", returning
+// the value without it. The count is nil when the value has none.
+func SplitAmenityCount(value string) (string, *int) {
+    value = strings.TrimSpace(value)
+    i := strings.LastIndexAny(value, "xX")
+    if i < 0 {
+        return value, nil
+    }
+    n, err := strconv.Atoi(strings.TrimSpace(value[i+1:]))
+    if err != nil || n < 0 || strings.TrimSpace(value[:i]) == "" {
+        return value, nil
+    }
+    return strings.TrimSpace(value[:i]), &n
+}
+
+// ParseCount converts a non-negative integer value
+func ParseCount(value string) (int, error) {
+    n, err := strconv.Atoi(strings.TrimSpace(value))
+    if err != nil || n < 0 {
+        return 0, fmt.Errorf("%q is not a non-negative integer", value)
+    }
+    return n, nil
+}
+
+// ParseCoordinate converts a latitude or longitude in decimal degrees, checking it is within range for the field
+func ParseCoordinate(field, value string) (float64, error) {
+    f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+    if err != nil {
+        return 0, fmt.Errorf("%q is not a number", value)
+    }
+    limit := coordinateLimits[field]
+    if f < -limit || f > limit {
+        return 0, fmt.Errorf("%q is outside -%g to %g", value, limit, limit)
+    }
+    return f, nil
+}
+
+// coordinateLimits holds the largest absolute value of each coordinate column, in degrees
+var coordinateLimits = map[string]float64{"latitude": 90, "longitude": 180}
+
+// NormalizeValue converts a raw value for the given column into its typed form.
+// It is shared by the CSV loader and the query parser so both accept the same spellings.
+func NormalizeValue(field, value string) (interface{}, error) {
+    kind, ok := FieldKinds[field]
+    if !ok {
+        return nil, fmt.Errorf("unknown field %q", field)
+    }
+
+    switch kind {
+    case KindBool:
+        return ParseYesNo(value)
+    case KindCount:
+        return ParseCount(value)
+    case KindHorseTrail:
+        return ParseHorseTrail(value)
+    case KindADAParking:
+        return ParseADAParking(value)
+    case KindCoordinate:
+        return ParseCoordinate(field, value)
+    case KindDate:
+        t, err := parseOptionalDate(strings.TrimSpace(value))
+        if err != nil {
+            return nil, err
+        }
+        if t == nil {
+            return nil, fmt.Errorf("a date value is required")
+        }
+        return *t, nil
+    default:
+        return strings.ToLower(strings.TrimSpace(value)), nil
+    }
+}
+
+// joinHorseTrailValues renders the accepted HorseTrail values for error messages
+func joinHorseTrailValues() string {
+    values := make([]string, len(HorseTrailValues))
+    for i, v := range HorseTrailValues {
+        values[i] = string(v)
+    }
+    return strings.Join(values, ", ")
+}
+
+// joinADAParkingValues renders the accepted ADAParking values for error messages
+func joinADAParkingValues() string {
+    values := make([]string, len(ADAParkingValues))
+    for i, v := range ADAParkingValues {
+        values[i] = string(v)
+    }
+    return strings.Join(values, ", ")
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestParseHorseTrail(t *testing.T) {
+    cases := map[string]HorseTrail{
+        "Possible":        HorseTrailPossible,
+        "Not Recommended": HorseTrailNotRecommended,
+        "not_recommended": HorseTrailNotRecommended,
+        "Designated x 3":  HorseTrailDesignated,
+        "Pull Through":    HorseTrailPullThrough,
+        "NA":              HorseTrailNA,
+    }
+    for raw, expected := range cases {
+        value, err := ParseHorseTrail(raw)
+        assert.Nil(t, err, "Expected %q to parse", raw)
+        assert.Equal(t, expected, value, "Unexpected value for %q", raw)
+    }
+
+    _, err := ParseHorseTrail("sometimes")
+    assert.NotNil(t, err, "Expected unknown horse trail value to be rejected")
+}
+
+func TestParseADAParking(t *testing.T) {
+    cases := map[string]ADAParking{
+        "Yes":     ADAParkingYes,
+        "Yes x 2": ADAParkingYes,
+        "No":      ADAParkingNo,
+        "":        ADAParkingNo,
+        "Offsite": ADAParkingOffsite,
+    }
+    for raw, expected := range cases {
+        value, err := ParseADAParking(raw)
+        assert.Nil(t, err, "Expected %q to parse", raw)
+        assert.Equal(t, expected, value, "Unexpected value for %q", raw)
+    }
+
+    _, err := ParseADAParking("nearby")
+    assert.NotNil(t, err, "Expected unknown ADA parking value to be rejected")
+}
+
+func TestSplitAmenityCount(t *testing.T) {
+    value, count := SplitAmenityCount(" Designated x 3 ")
+    assert.Equal(t, "Designated", value)
+    if assert.NotNil(t, count) {
+        assert.Equal(t, 3, *count)
+    }
+
+    for _, raw := range []string{"Designated", "Offsite", "x 2", "Designated x many"} {
+        value, count := SplitAmenityCount(raw)
+        assert.Equal(t, raw, value)
+        assert.Nil(t, count, "Expected no count in %q", raw)
+    }
+}
+
+func TestNormalizeValue(t *testing.T) {
+    value, err := NormalizeValue("grills", "Yes")
+    assert.Nil(t, err)
+    assert.Equal(t, true, value)
+
+    value, err = NormalizeValue("th_leash", "no")
+    assert.Nil(t, err)
+    assert.Equal(t, false, value)
+
+    value, err = NormalizeValue("park_spaces", "12")
+    assert.Nil(t, err)
+    assert.Equal(t, 12, value)
+
+    _, err = NormalizeValue("grills", "2")
+    assert.NotNil(t, err, "Expected a count to be rejected for a yes/no field")
+
+    _, err = NormalizeValue("park_spaces", "lots")
+    assert.NotNil(t, err, "Expected a non-numeric count to be rejected")
+
+    value, err = NormalizeValue("longitude", "-105.2705")
+    assert.Nil(t, err)
+    assert.Equal(t, -105.2705, value)
+
+    _, err = NormalizeValue("latitude", "95")
+    assert.NotNil(t, err, "Expected a latitude beyond the poles to be rejected")
+
+    _, err = NormalizeValue("colour", "red")
+    assert.NotNil(t, err, "Expected an unknown field to be rejected")
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+    "fmt"
+    "strconv"
+    "strings"
+    "time"
+)
+
+// dateLayouts are the timestamp formats accepted for DateFrom/DateTo, the first being the Boulder County export format
+var dateLayouts = []string{"1/2/2006 15:04", "1/2/2006", "2006-01-02", time.RFC3339}
+
+// ValueError describes an invalid value of one field in a source row
+type ValueError struct {
+    Field  string // Trail field the value maps to
+    Value  string // The trimmed value found in the row
+    Reason string
+}
+
+// RecordError lists every invalid value of a source row
+type RecordError struct {
+    Values []ValueError
+}
+
+func (e *RecordError) Error() string {
+    messages := make([]string, len(e.Values))
+    for i, v := range e.Values {
+        messages[i] = fmt.Sprintf("invalid %s %q: %s", v.Field, v.Value, v.Reason)
+    }
+    return strings.Join(messages, "; ")
+}
+
+// TrailFromRecord builds a Trail from a source row using the resolved column positions.
+// Values go through the same normalization as query parameters; blank counts, dates and coordinates are left nil.
+// Every value that cannot be converted is reported in a *RecordError.
+func TrailFromRecord(columns ColumnIndex, row []string) (Trail, error) {
+    var invalid []ValueError
+    reject := func(field, reason string) {
+        invalid = append(invalid, ValueError{Field: field, Value: columns.Value(row, field), Reason: reason})
+    }
+    text := func(field string) string {
+        return strings.ToLower(columns.Value(row, field))
+    }
+    yesNo := func(field string) bool {
+        v, err := ParseYesNo(columns.Value(row, field))
+        if err != nil {
+            reject(field, "is not a yes/no value")
+        }
+        return v
+    }
+    count := func(field string) *int {
+        v, err := parseOptionalInt(columns.Value(row, field))
+        if err != nil {
+            reject(field, "is not an integer")
+        }
+        return v
+    }
+    date := func(field string) *time.Time {
+        v, err := parseOptionalDate(columns.Value(row, field))
+        if err != nil {
+            reject(field, "is not a recognized date")
+        }
+        return v
+    }
+
+    coordinate := func(field string) *float64 {
+        v, err := parseOptionalFloat(columns.Value(row, field))
+        if err != nil {
+            reject(field, "is not a number")
+        }
+        return v
+    }
+
+    fid, err := strconv.Atoi(columns.Value(row, "fid"))
+    if err != nil {
+        reject("fid", "is not an integer")
+    }
+
+    // Counted amenities such as "Designated x 2" keep their count in a column of its own
+    horseTrailValue, horseTrailCount := SplitAmenityCount(columns.Value(row, "horse_trail"))
+    horseTrail, err := ParseHorseTrail(horseTrailValue)
+    if err != nil {
+        reject("horse_trail", "must be one of "+joinHorseTrailValues())
+    }
+    adaParkingValue, adaParkingCount := SplitAmenityCount(columns.Value(row, "ada_parking"))
+    adaParking, err := ParseADAParking(adaParkingValue)
+    if err != nil {
+        reject("ada_parking", "must be one of "+joinADAParkingValues())
+    }
+
+    trail := Trail{
+        FID:             fid,
+        Name:            text("name"),
+        Restrooms:       yesNo("restrooms"),
+        Picnic:          yesNo("picnic"),
+        Fishing:         yesNo("fishing"),
+        Type:            text("type"),
+        Difficulty:      text("difficulty"),
+        AccessType:      text("access_type"),
+        THLeash:         yesNo("th_leash"),
+        BikeTrail:       yesNo("bike_trail"),
+        HorseTrail:      horseTrail,
+        HorseTrailCount: horseTrailCount,
+        Fee:             yesNo("fee"),
+        RecycleBin:      yesNo("recycle_bin"),
+        Grills:          yesNo("grills"),
+        BikeRack:        yesNo("bike_rack"),
+        DogTube:         count("dog_tube"),
+        AKA:             text("aka"),
+        Address:         text("address"),
+        AccessID:        text("access_id"),
+        TrashCans:       count("trash_cans"),
+        ParkSpaces:      count("park_spaces"),
+        ADASurface:      text("ada_surface"),
+        ADAToilet:       yesNo("ada_toilet"),
+        ADAFishing:      yesNo("ada_fishing"),
+        ADACamping:      yesNo("ada_camping"),
+        ADAPicnic:       yesNo("ada_picnic"),
+        ADAParking:      adaParking,
+        ADAParkingCount: adaParkingCount,
+        ADAFacility:     yesNo("ada_facility"),
+        ADAFacilityName: text("ada_facility_name"),
+        DateFrom:        date("date_from"),
+        DateTo:          date("date_to"),
+        DogCompost:      yesNo("dog_compost"),
+        Latitude:        coordinate("latitude"),
+        Longitude:       coordinate("longitude"),
+    }
+    if len(invalid) > 0 {
+        return Trail{}, &RecordError{Values: invalid}
+    }
+    return trail, nil
+}
+
+// parseOptionalInt parses an integer, returning nil for a blank value
+func parseOptionalInt(value string) (*int, error) {
+    if value == "" {
+        return nil, nil
+    }
+    n, err := strconv.Atoi(value)
+    if err != nil {
+        return nil, fmt.Errorf("%q is not an integer", value)
+    }
+    return &n, nil
+}
+
+// parseOptionalFloat parses a decimal number, returning nil for a blank value
+func parseOptionalFloat(value string) (*float64, error) {
+    if value == "" {
+        return nil, nil
+    }
+    f, err := strconv.ParseFloat(value, 64)
+    if err != nil {
+        return nil, fmt.Errorf("%q is not a number", value)
+    }
+    return &f, nil
+}
+
+// parseOptionalDate parses a timestamp in any of the accepted layouts, returning nil for a blank value
+func parseOptionalDate(value string) (*time.Time, error) {
+    if value == "" {
+        return nil, nil
+    }
+    for _, layout := range dateLayouts {
+        if t, err := time.Parse(layout, value); err == nil {
+            return &t, nil
+        }
+    }
+    return nil, fmt.Errorf("%q is not a recognized date", value)
+}
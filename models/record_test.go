@@ -0,0 +1,114 @@
+package models
+
+import (
+    "encoding/csv"
+    "os"
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+)
+
+func TestTrailFromRecordBoulderCSV(t *testing.T) {
+    file, err := os.Open("../BoulderTrailHeads.csv")
+    if err != nil {
+        t.Fatalf("Failed to open CSV: %v", err)
+    }
+    defer file.Close()
+
+    rows, err := csv.NewReader(file).ReadAll()
+    if err != nil {
+        t.Fatalf("Failed to read CSV: %v", err)
+    }
+
+    columns, err := DefaultMapping().Resolve(rows[0])
+    if err != nil {
+        t.Fatalf("Failed to resolve headers: %v", err)
+    }
+
+    trail, err := TrailFromRecord(columns, rows[1])
+    assert.Nil(t, err, "Expected first row to convert")
+
+    assert.Equal(t, 0, trail.FID)
+    assert.Equal(t, "flagstaff summit west", trail.Name)
+    assert.Equal(t, "621 flagstaff summit rd", trail.Address)
+    assert.Equal(t, "279", trail.AccessID)
+    assert.True(t, trail.Fee)
+    assert.False(t, trail.BikeRack)
+    assert.False(t, trail.BikeTrail)
+    assert.Equal(t, HorseTrailNotRecommended, trail.HorseTrail)
+    assert.Equal(t, 1, *trail.DogTube)
+    assert.Equal(t, "moderate", trail.Difficulty)
+    assert.Equal(t, "wood shelter", trail.ADAFacilityName)
+    assert.Equal(t, 4, *trail.TrashCans)
+    assert.Equal(t, 12, *trail.ParkSpaces)
+    assert.Equal(t, time.Date(2005, 12, 31, 0, 0, 0, 0, time.UTC), *trail.DateFrom)
+    assert.Equal(t, "", trail.AKA, "Expected blank AKA to be trimmed")
+    assert.Nil(t, trail.HorseTrailCount)
+
+    // Every row in the bundled dataset converts cleanly
+    for i, row := range rows[1:] {
+        _, err := TrailFromRecord(columns, row)
+        assert.Nil(t, err, "Expected row %d to convert", i+2)
+    }
+}
+
+func TestTrailFromRecordInvalidValues(t *testing.T) {
+    columns := ColumnIndex{"fid": 0, "park_spaces": 1, "date_to": 2}
+
+    _, err := TrailFromRecord(columns, []string{"x", "1", ""})
+    assert.NotNil(t, err, "Expected non-integer fid to be rejected")
+
+    _, err = TrailFromRecord(columns, []string{"1", "many", ""})
+    assert.NotNil(t, err, "Expected non-integer park spaces to be rejected")
+
+    _, err = TrailFromRecord(columns, []string{"1", "", "someday"})
+    assert.NotNil(t, err, "Expected unparseable date to be rejected")
+
+    trail, err := TrailFromRecord(columns, []string{"1", "", ""})
+    assert.Nil(t, err)
+    assert.Nil(t, trail.ParkSpaces, "Expected blank park spaces to be nil")
+    assert.Nil(t, trail.DateTo, "Expected blank date to be nil")
+}
+
+func TestTrailFromRecordAmenityCounts(t *testing.T) {
+    columns := ColumnIndex{"fid": 0, "horse_trail": 1, "ada_parking": 2}
+
+    trail, err := TrailFromRecord(columns, []string{"1", "Designated x 2", "Yes x 3"})
+    assert.Nil(t, err)
+    assert.Equal(t, HorseTrailDesignated, trail.HorseTrail)
+    if assert.NotNil(t, trail.HorseTrailCount) {
+        assert.Equal(t, 2, *trail.HorseTrailCount)
+    }
+    assert.Equal(t, ADAParkingYes, trail.ADAParking)
+    if assert.NotNil(t, trail.ADAParkingCount) {
+        assert.Equal(t, 3, *trail.ADAParkingCount)
+    }
+
+    trail, err = TrailFromRecord(columns, []string{"2", "Designated", "Offsite"})
+    assert.Nil(t, err)
+    assert.Nil(t, trail.HorseTrailCount)
+    assert.Equal(t, ADAParkingOffsite, trail.ADAParking)
+    assert.Nil(t, trail.ADAParkingCount)
+
+    _, err = TrailFromRecord(columns, []string{"3", "NA", "Nearby"})
+    var invalid *RecordError
+    if assert.ErrorAs(t, err, &invalid) {
+        assert.Equal(t, []ValueError{{Field: "ada_parking", Value: "Nearby", Reason: "must be one of " + joinADAParkingValues()}}, invalid.Values)
+    }
+}
+
+func TestTrailFromRecordReportsEveryValue(t *testing.T) {
+    columns := ColumnIndex{"fid": 0, "restrooms": 1, "park_spaces": 2, "horse_trail": 3}
+
+    _, err := TrailFromRecord(columns, []string{"1", "Maybe", "many", "sometimes"})
+    var invalid *RecordError
+    if assert.ErrorAs(t, err, &invalid) {
+        assert.Equal(t, []ValueError{
+            {Field: "horse_trail", Value: "sometimes", Reason: "must be one of " + joinHorseTrailValues()},
+            {Field: "restrooms", Value: "Maybe", Reason: "is not a yes/no value"},
+            {Field: "park_spaces", Value: "many", Reason: "is not an integer"},
+        }, invalid.Values)
+        assert.Contains(t, err.Error(), `invalid restrooms "Maybe": is not a yes/no value`)
+    }
+}
@@ -1,52 +1,140 @@
 package models
 
 import (
-    "context"
-    "github.com/jackc/pgx/v4"
+    "time"
 )
 
 // Trail struct represents a trail entry in the database
 type Trail struct {
-    FID         int    `json:"fid"` // Updated to int
-    Name        string `json:"name"`
-    Restrooms   string `json:"restrooms"`
-    Picnic      string `json:"picnic"`
-    Fishing     string `json:"fishing"`
-    Type        string `json:"type"`
-    Difficulty  string `json:"difficulty"`
-    AccessType  string `json:"access_type"`
-    THLeash     string `json:"th_leash"`
-    BikeTrail   string `json:"bike_trail"`
-    HorseTrail  string `json:"horse_trail"`
-    Fee         string `json:"fee"`
-    RecycleBin  string `json:"recycle_bin"`
-    Grills      string `json:"grills"`
-    BikeRack    string `json:"bike_rack"`
-    DogTube     string `json:"dog_tube"`
+    FID             int        `json:"fid"` // Updated to int
+    Name            string     `json:"name"`
+    Restrooms       bool       `json:"restrooms"`
+    Picnic          bool       `json:"picnic"`
+    Fishing         bool       `json:"fishing"`
+    Type            string     `json:"type"`
+    Difficulty      string     `json:"difficulty"`
+    AccessType      string     `json:"access_type"`
+    THLeash         bool       `json:"th_leash"`
+    BikeTrail       bool       `json:"bike_trail"`
+    HorseTrail      HorseTrail `json:"horse_trail"`
+    HorseTrailCount *int       `json:"horse_trail_count"` // Designated horse trails, when the source counts them
+    Fee             bool       `json:"fee"`
+    RecycleBin      bool       `json:"recycle_bin"`
+    Grills          bool       `json:"grills"`
+    BikeRack        bool       `json:"bike_rack"`
+    DogTube         *int       `json:"dog_tube"`
+    AKA             string     `json:"aka"`
+    Address         string     `json:"address"`
+    AccessID        string     `json:"access_id"`
+    TrashCans       *int       `json:"trash_cans"`
+    ParkSpaces      *int       `json:"park_spaces"`
+    ADASurface      string     `json:"ada_surface"`
+    ADAToilet       bool       `json:"ada_toilet"`
+    ADAFishing      bool       `json:"ada_fishing"`
+    ADACamping      bool       `json:"ada_camping"`
+    ADAPicnic       bool       `json:"ada_picnic"`
+    ADAParking      ADAParking `json:"ada_parking"`
+    ADAParkingCount *int       `json:"ada_parking_count"` // Accessible parking spaces, when the source counts them
+    ADAFacility     bool       `json:"ada_facility"`
+    ADAFacilityName string     `json:"ada_facility_name"`
+    DateFrom        *time.Time `json:"date_from"`
+    DateTo          *time.Time `json:"date_to"`
+    DogCompost      bool       `json:"dog_compost"`
+    Latitude        *float64   `json:"latitude"`
+    Longitude       *float64   `json:"longitude"`
+
+    // Distance is the distance in km from the point of a near query. It is set on listings, not stored.
+    Distance *float64 `json:"distance_km,omitempty"`
+}
+
+// TrailColumns lists the trails table columns in the order used by ScanFields and Values
+var TrailColumns = []string{
+    "fid", "name", "restrooms", "picnic", "fishing", "type", "difficulty", "access_type", "th_leash",
+    "bike_trail", "horse_trail", "horse_trail_count", "fee", "recycle_bin", "grills", "bike_rack", "dog_tube",
+    "aka", "address", "access_id", "trash_cans", "park_spaces",
+    "ada_surface", "ada_toilet", "ada_fishing", "ada_camping", "ada_picnic", "ada_parking", "ada_parking_count", "ada_facility", "ada_facility_name",
+    "date_from", "date_to", "dog_compost", "latitude", "longitude",
 }
 
-// CreateTable creates the trails table in PostgreSQL
-func CreateTable(conn *pgx.Conn) error {
-    _, err := conn.Exec(context.Background(), `
-        CREATE TABLE IF NOT EXISTS trails (
-            fid INTEGER PRIMARY KEY,
-            name TEXT,
-            restrooms TEXT,
-            picnic TEXT,
-            fishing TEXT,
-            type TEXT,
-            difficulty TEXT,
-            access_type TEXT,
-            th_leash TEXT,
-            bike_trail TEXT,
-            horse_trail TEXT,
-            fee TEXT,
-            recycle_bin TEXT,
-            grills TEXT,
-            bike_rack TEXT,
-            dog_tube TEXT
-        )
-    `)
-    return err
+// ScanFields returns pointers to the trail fields in TrailColumns order, for use with rows.Scan
+func (t *Trail) ScanFields() []interface{} {
+    return []interface{}{
+        &t.FID, &t.Name, &t.Restrooms, &t.Picnic, &t.Fishing, &t.Type, &t.Difficulty, &t.AccessType, &t.THLeash,
+        &t.BikeTrail, &t.HorseTrail, &t.HorseTrailCount, &t.Fee, &t.RecycleBin, &t.Grills, &t.BikeRack, &t.DogTube,
+        &t.AKA, &t.Address, &t.AccessID, &t.TrashCans, &t.ParkSpaces,
+        &t.ADASurface, &t.ADAToilet, &t.ADAFishing, &t.ADACamping, &t.ADAPicnic, &t.ADAParking, &t.ADAParkingCount, &t.ADAFacility, &t.ADAFacilityName,
+        &t.DateFrom, &t.DateTo, &t.DogCompost, &t.Latitude, &t.Longitude,
+    }
 }
 
+// Values returns the trail field values in TrailColumns order, for use as query arguments
+func (t *Trail) Values() []interface{} {
+    return []interface{}{
+        t.FID, t.Name, t.Restrooms, t.Picnic, t.Fishing, t.Type, t.Difficulty, t.AccessType, t.THLeash,
+        t.BikeTrail, string(t.HorseTrail), t.HorseTrailCount, t.Fee, t.RecycleBin, t.Grills, t.BikeRack, t.DogTube,
+        t.AKA, t.Address, t.AccessID, t.TrashCans, t.ParkSpaces,
+        t.ADASurface, t.ADAToilet, t.ADAFishing, t.ADACamping, t.ADAPicnic, string(t.ADAParking), t.ADAParkingCount, t.ADAFacility, t.ADAFacilityName,
+        t.DateFrom, t.DateTo, t.DogCompost, t.Latitude, t.Longitude,
+    }
+}
+
+// FieldValue returns the value of a trails column, or false if the column does not exist.
+// Optional counts, dates and coordinates are dereferenced, so unset values are returned as nil.
+func (t *Trail) FieldValue(column string) (interface{}, bool) {
+    i, ok := trailColumnIndex[column]
+    if !ok {
+        return nil, false
+    }
+    // Values passes the enums to the driver as plain strings
+    switch column {
+    case "horse_trail":
+        return t.HorseTrail, true
+    case "ada_parking":
+        return t.ADAParking, true
+    }
+
+    switch v := t.Values()[i].(type) {
+    case *int:
+        if v == nil {
+            return nil, true
+        }
+        return *v, true
+    case *time.Time:
+        if v == nil {
+            return nil, true
+        }
+        return *v, true
+    case *float64:
+        if v == nil {
+            return nil, true
+        }
+        return *v, true
+    default:
+        return v, true
+    }
+}
+
+// Project returns the named columns of the trail keyed by column name, for responses that only include some fields.
+// Unknown column names are skipped, and the distance of a near query is kept whenever it is set.
+func (t *Trail) Project(columns []string) map[string]interface{} {
+    projected := make(map[string]interface{}, len(columns)+1)
+    for _, column := range columns {
+        if value, ok := t.FieldValue(column); ok {
+            projected[column] = value
+        }
+    }
+    if t.Distance != nil {
+        projected["distance_km"] = *t.Distance
+    }
+    return projected
+}
+
+// trailColumnIndex maps each column name to its position in TrailColumns
+var trailColumnIndex = func() map[string]int {
+    index := make(map[string]int, len(TrailColumns))
+    for i, column := range TrailColumns {
+        index[column] = i
+    }
+    return index
+}()
+
@@ -0,0 +1,18 @@
+package models
+
+import (
+    "encoding/json"
+    "testing"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestTrailProject(t *testing.T) {
+    spaces := 12
+    trail := Trail{FID: 4, Name: "mesa", Fee: true, HorseTrail: HorseTrailDesignated, ParkSpaces: &spaces}
+
+    data, err := json.Marshal(trail.Project([]string{"name", "fee", "horse_trail", "park_spaces", "dog_tube"}))
+    require.NoError(t, err)
+    assert.JSONEq(t, `{"name": "mesa", "fee": true, "horse_trail": "designated", "park_spaces": 12, "dog_tube": null}`, string(data))
+}
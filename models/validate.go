@@ -0,0 +1,91 @@
+package models
+
+import (
+    "fmt"
+    "sort"
+    "strings"
+)
+
+// FieldError describes why the value of one trail field is invalid
+type FieldError struct {
+    Field   string `json:"field"`
+    Message string `json:"message"`
+}
+
+// ValidationError lists every invalid field of a trail
+type ValidationError struct {
+    Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+    messages := make([]string, len(e.Fields))
+    for i, f := range e.Fields {
+        messages[i] = f.Field + ": " + f.Message
+    }
+    return "invalid trail: " + strings.Join(messages, "; ")
+}
+
+// Normalize brings a trail submitted through the API into the form the CSV loader stores:
+// text is trimmed and lowercased, and a missing horse_trail or ada_parking becomes the column default.
+func (t *Trail) Normalize() {
+    for _, text := range []*string{
+        &t.Name, &t.Type, &t.Difficulty, &t.AccessType, &t.AKA, &t.Address, &t.AccessID,
+        &t.ADASurface, &t.ADAFacilityName,
+    } {
+        *text = strings.ToLower(strings.TrimSpace(*text))
+    }
+    if t.HorseTrail == "" {
+        t.HorseTrail = HorseTrailNA
+    }
+    if t.ADAParking == "" {
+        t.ADAParking = ADAParkingNo
+    }
+}
+
+// Validate checks the trail against the constraints of the trails schema, reporting every invalid field
+func (t *Trail) Validate() error {
+    var fields []FieldError
+    invalid := func(field, format string, args ...interface{}) {
+        fields = append(fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+    }
+
+    if t.FID < 0 {
+        invalid("fid", "must not be negative")
+    }
+    if t.Name == "" {
+        invalid("name", "is required")
+    }
+    if !t.HorseTrail.Valid() {
+        invalid("horse_trail", "must be one of %s", joinHorseTrailValues())
+    }
+    if !t.ADAParking.Valid() {
+        invalid("ada_parking", "must be one of %s", joinADAParkingValues())
+    }
+    for field, count := range map[string]*int{
+        "dog_tube": t.DogTube, "trash_cans": t.TrashCans, "park_spaces": t.ParkSpaces,
+        "horse_trail_count": t.HorseTrailCount, "ada_parking_count": t.ADAParkingCount,
+    } {
+        if count != nil && *count < 0 {
+            invalid(field, "must not be negative")
+        }
+    }
+    if t.DateFrom != nil && t.DateTo != nil && t.DateTo.Before(*t.DateFrom) {
+        invalid("date_to", "must not be before date_from")
+    }
+
+    if (t.Latitude == nil) != (t.Longitude == nil) {
+        invalid("longitude", "must be set together with latitude")
+    }
+    for field, coordinate := range map[string]*float64{"latitude": t.Latitude, "longitude": t.Longitude} {
+        if limit := coordinateLimits[field]; coordinate != nil && (*coordinate < -limit || *coordinate > limit) {
+            invalid(field, "must be between -%g and %g", limit, limit)
+        }
+    }
+
+    if len(fields) == 0 {
+        return nil
+    }
+    // Report fields in column order, so messages are stable
+    sort.Slice(fields, func(i, j int) bool { return trailColumnIndex[fields[i].Field] < trailColumnIndex[fields[j].Field] })
+    return &ValidationError{Fields: fields}
+}
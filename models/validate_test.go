@@ -0,0 +1,58 @@
+package models
+
+import (
+    "testing"
+    "time"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestTrailNormalize(t *testing.T) {
+    trail := Trail{Name: "  Mesa Trail ", Difficulty: "Easy"}
+    trail.Normalize()
+
+    assert.Equal(t, "mesa trail", trail.Name)
+    assert.Equal(t, "easy", trail.Difficulty)
+    assert.Equal(t, HorseTrailNA, trail.HorseTrail)
+    assert.Equal(t, ADAParkingNo, trail.ADAParking)
+    assert.NoError(t, trail.Validate())
+}
+
+func TestTrailValidateCoordinates(t *testing.T) {
+    lat, lon := 40.01, -105.27
+    trail := Trail{FID: 1, Name: "mesa", HorseTrail: HorseTrailNA, ADAParking: ADAParkingNo, Latitude: &lat, Longitude: &lon}
+    assert.NoError(t, trail.Validate())
+
+    far := 200.0
+    trail = Trail{FID: 1, Name: "mesa", HorseTrail: HorseTrailNA, ADAParking: ADAParkingNo, Latitude: &lat, Longitude: &far}
+    var validationErr *ValidationError
+    require.ErrorAs(t, trail.Validate(), &validationErr)
+    assert.Equal(t, []FieldError{{Field: "longitude", Message: "must be between -180 and 180"}}, validationErr.Fields)
+
+    trail = Trail{FID: 1, Name: "mesa", HorseTrail: HorseTrailNA, ADAParking: ADAParkingNo, Latitude: &lat}
+    require.ErrorAs(t, trail.Validate(), &validationErr)
+    assert.Equal(t, []FieldError{{Field: "longitude", Message: "must be set together with latitude"}}, validationErr.Fields)
+}
+
+func TestTrailValidateReportsEveryField(t *testing.T) {
+    negative := -1
+    from := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+    to := from.AddDate(0, -1, 0)
+    trail := Trail{
+        FID: 1, HorseTrail: "Not Recommended", HorseTrailCount: &negative, ParkSpaces: &negative,
+        ADAParking: "Yes x 2", DateFrom: &from, DateTo: &to,
+    }
+
+    err := trail.Validate()
+    var validationErr *ValidationError
+    require.ErrorAs(t, err, &validationErr)
+    assert.Equal(t, []FieldError{
+        {Field: "name", Message: "is required"},
+        {Field: "horse_trail", Message: "must be one of possible, not_recommended, designated, not_allowed, pull_through, na"},
+        {Field: "horse_trail_count", Message: "must not be negative"},
+        {Field: "park_spaces", Message: "must not be negative"},
+        {Field: "ada_parking", Message: "must be one of yes, no, offsite"},
+        {Field: "date_to", Message: "must not be before date_from"},
+    }, validationErr.Fields)
+}
@@ -0,0 +1,14 @@
+package models
+
+import "time"
+
+// DatasetVersion records the trails left by a successful import or rollback. The store keeps a snapshot of
+// those trails, so the dataset can be queried as of the version or restored later.
+type DatasetVersion struct {
+    ID         int       `json:"id"`
+    Source     string    `json:"source"`                // Imported file name or path, or the rollback it records
+    SHA256     string    `json:"sha256"`                // Hex SHA-256 of the imported file; rollbacks keep the restored version's
+    RollbackOf int       `json:"rollback_of,omitempty"` // Version restored by a rollback, 0 for imports
+    TrailCount int       `json:"trail_count"`           // Number of trails in the snapshot
+    CreatedAt  time.Time `json:"created_at"`
+}
@@ -0,0 +1,168 @@
+package store
+
+import (
+    "bytes"
+    "encoding/base64"
+    "encoding/json"
+    "fmt"
+    "strings"
+    "time"
+    "trail-finder/models"
+)
+
+// Cursor marks where the next page of a listing starts. Listing cursors hold the sort key values and FID of
+// the last trail of the previous page, so pages stay consistent while trails are added or removed.
+// Search results are ranked, and distance sorts ordered by computed values, rather than sorted on stored values,
+// so their cursors hold a position instead. Search cursors also name the ranking of the store that issued them,
+// since a position only points to the same place in results ordered the same way.
+type Cursor struct {
+    Sort       []SortKey     // Sort keys of the listing the cursor belongs to
+    Values     []interface{} // Values of the sort keys for the last trail, in key order; nil for unset values
+    FID        int           // FID of the last trail
+    Offset     int           // Number of results already returned, for positional cursors
+    Positional bool          // Whether this is a positional cursor, of a search or distance sort
+    Ranking    string        // Search ranking of the store, for search cursors
+}
+
+// encodedCursor is the JSON form of a Cursor, before base64 encoding
+type encodedCursor struct {
+    Sort       string        `json:"s"`
+    Values     []interface{} `json:"v,omitempty"`
+    FID        int           `json:"f,omitempty"`
+    Offset     int           `json:"o,omitempty"`
+    Positional bool          `json:"q,omitempty"`
+    Ranking    string        `json:"r,omitempty"`
+}
+
+// CursorAfter returns the cursor for the page that follows the given trail in a listing sorted by keys
+func CursorAfter(trail *models.Trail, keys []SortKey) *Cursor {
+    values := make([]interface{}, len(keys))
+    for i, key := range keys {
+        values[i], _ = trail.FieldValue(key.Field)
+    }
+    return &Cursor{Sort: keys, Values: values, FID: trail.FID}
+}
+
+// PositionCursorAt returns the cursor for the search or distance sorted page that starts after offset results.
+// ranking is the SearchRanking of the store for searches, and empty for distance sorts.
+func PositionCursorAt(offset int, keys []SortKey, ranking string) *Cursor {
+    return &Cursor{Sort: keys, Offset: offset, Positional: true, Ranking: ranking}
+}
+
+// Encode returns the cursor as an opaque URL-safe string
+func (c *Cursor) Encode() string {
+    encoded := encodedCursor{Sort: FormatSort(c.Sort), FID: c.FID, Offset: c.Offset, Positional: c.Positional, Ranking: c.Ranking}
+    for _, value := range c.Values {
+        if t, ok := value.(time.Time); ok {
+            value = t.Format(time.RFC3339Nano)
+        }
+        encoded.Values = append(encoded.Values, value)
+    }
+
+    data, _ := json.Marshal(encoded)
+    return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor parses a cursor returned by Encode. The cursor must belong to a listing with the same sort keys,
+// and be positional exactly when the listing is a search or sorted by distance. ranking is the SearchRanking
+// of the store for searches, and empty otherwise; a search cursor issued by a store ranking differently is rejected.
+func DecodeCursor(raw string, keys []SortKey, positional bool, ranking string) (*Cursor, error) {
+    data, err := base64.RawURLEncoding.DecodeString(raw)
+    if err != nil {
+        return nil, ErrInvalidCursor
+    }
+    decoder := json.NewDecoder(bytes.NewReader(data))
+    decoder.UseNumber()
+    var encoded encodedCursor
+    if err := decoder.Decode(&encoded); err != nil {
+        return nil, ErrInvalidCursor
+    }
+    if encoded.Sort != FormatSort(keys) || encoded.Positional != positional {
+        return nil, fmt.Errorf("%w: it does not match the requested sort or search", ErrInvalidCursor)
+    }
+    if encoded.Ranking != ranking {
+        return nil, fmt.Errorf("%w: it was issued by a server ranking searches differently", ErrInvalidCursor)
+    }
+
+    cursor := &Cursor{Sort: keys, FID: encoded.FID, Offset: encoded.Offset, Positional: encoded.Positional, Ranking: encoded.Ranking}
+    if positional {
+        if encoded.Offset < 0 {
+            return nil, ErrInvalidCursor
+        }
+        return cursor, nil
+    }
+    if len(encoded.Values) != len(keys) {
+        return nil, ErrInvalidCursor
+    }
+    for i, key := range keys {
+        value, err := decodeCursorValue(key.Field, encoded.Values[i])
+        if err != nil {
+            return nil, ErrInvalidCursor
+        }
+        cursor.Values = append(cursor.Values, value)
+    }
+    return cursor, nil
+}
+
+// decodeCursorValue converts a JSON-decoded cursor value back into the typed value of a column
+func decodeCursorValue(field string, raw interface{}) (interface{}, error) {
+    if raw == nil {
+        return nil, nil
+    }
+
+    switch models.FieldKinds[field] {
+    case models.KindBool:
+        if b, ok := raw.(bool); ok {
+            return b, nil
+        }
+    case models.KindCount:
+        if n, ok := raw.(json.Number); ok {
+            v, err := n.Int64()
+            return int(v), err
+        }
+    case models.KindCoordinate:
+        if n, ok := raw.(json.Number); ok {
+            return n.Float64()
+        }
+    case models.KindDate:
+        if s, ok := raw.(string); ok {
+            return time.Parse(time.RFC3339Nano, s)
+        }
+    case models.KindHorseTrail:
+        if s, ok := raw.(string); ok {
+            return models.HorseTrail(s), nil
+        }
+    case models.KindADAParking:
+        if s, ok := raw.(string); ok {
+            return models.ADAParking(s), nil
+        }
+    default:
+        if s, ok := raw.(string); ok {
+            return s, nil
+        }
+    }
+    return nil, fmt.Errorf("unexpected %s value %v", field, raw)
+}
+
+// FormatSort renders sort keys in the form accepted by the sort query parameter, such as name,-park_spaces
+func FormatSort(keys []SortKey) string {
+    parts := make([]string, len(keys))
+    for i, key := range keys {
+        parts[i] = key.Field
+        if key.Desc {
+            parts[i] = "-" + key.Field
+        }
+    }
+    return strings.Join(parts, ",")
+}
+
+// afterCursor reports whether the trail comes after the cursor in the cursor's sort order
+func afterCursor(trail *models.Trail, c *Cursor) bool {
+    for i, key := range c.Sort {
+        value, _ := trail.FieldValue(key.Field)
+        if cmp := compareKeyValues(value, c.Values[i], key.Desc); cmp != 0 {
+            return cmp > 0
+        }
+    }
+    return trail.FID > c.FID
+}
@@ -0,0 +1,44 @@
+package store
+
+import (
+    "testing"
+    "time"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestCursorRoundTripsTypedValues(t *testing.T) {
+    from := time.Date(2020, 5, 1, 8, 30, 0, 0, time.UTC)
+    lat := 39.98
+    keys := []SortKey{{Field: "name"}, {Field: "date_from", Desc: true}, {Field: "fee"}, {Field: "dog_tube"}, {Field: "horse_trail"}, {Field: "latitude"}}
+    trail := models.Trail{FID: 9, Name: "Mesa Trail", DateFrom: &from, Fee: true, HorseTrail: models.HorseTrailDesignated, Latitude: &lat}
+
+    cursor, err := DecodeCursor(CursorAfter(&trail, keys).Encode(), keys, false, "")
+    require.NoError(t, err)
+    assert.Equal(t, 9, cursor.FID)
+    assert.Equal(t, []interface{}{"Mesa Trail", from, true, nil, models.HorseTrailDesignated, lat}, cursor.Values)
+}
+
+func TestDecodeCursorRejectsMismatchedListings(t *testing.T) {
+    keys := []SortKey{{Field: "name"}}
+    encoded := CursorAfter(&models.Trail{FID: 1, Name: "mesa"}, keys).Encode()
+
+    _, err := DecodeCursor(encoded, []SortKey{{Field: "name", Desc: true}}, false, "")
+    assert.ErrorIs(t, err, ErrInvalidCursor)
+    _, err = DecodeCursor(encoded, keys, true, "")
+    assert.ErrorIs(t, err, ErrInvalidCursor)
+    _, err = DecodeCursor("not a cursor", keys, false, "")
+    assert.ErrorIs(t, err, ErrInvalidCursor)
+
+    search, err := DecodeCursor(PositionCursorAt(20, keys, RankingWords).Encode(), keys, true, RankingWords)
+    require.NoError(t, err)
+    assert.Equal(t, 20, search.Offset)
+
+    // Search positions only hold for results ranked the same way
+    _, err = DecodeCursor(PositionCursorAt(20, keys, RankingWords).Encode(), keys, true, RankingFullText)
+    assert.ErrorIs(t, err, ErrInvalidCursor)
+    _, err = DecodeCursor(PositionCursorAt(20, keys, "").Encode(), keys, true, RankingWords)
+    assert.ErrorIs(t, err, ErrInvalidCursor)
+}
@@ -0,0 +1,49 @@
+package store
+
+import (
+    "fmt"
+    "trail-finder/models"
+)
+
+// DistanceField is the sort key that orders trails by their distance from the key's From point.
+// Trails without coordinates sort last.
+const DistanceField = "distance"
+
+// Near restricts a listing to the trails within RadiusKm of a point; trails without coordinates never match
+type Near struct {
+    models.Point
+    RadiusKm float64
+}
+
+// SortsByDistance reports whether a listing sorted by the keys is ordered by distance. Distances are computed
+// rather than stored, so such listings are paged with positional cursors, like searches.
+func SortsByDistance(keys []SortKey) bool {
+    for _, key := range keys {
+        if key.Field == DistanceField {
+            return true
+        }
+    }
+    return false
+}
+
+// distance is the SQL expression for a trail's distance in km from the point bound to lat and lon, NULL for
+// trails without coordinates. With PostGIS it is measured on the location column, on the same sphere as the
+// haversine formula of models.DistanceKm that is used otherwise.
+func (d dialect) distance(lat, lon string) string {
+    if d.geography {
+        return fmt.Sprintf("(ST_Distance(location, ST_MakePoint(%s, %s)::geography, false) / 1000)", lon, lat)
+    }
+    // Rounding can push the haversine term just past 1 for antipodal points, where ASIN is undefined
+    return fmt.Sprintf("(%v * 2 * ASIN(SQRT(%s(1, POWER(SIN(RADIANS(latitude - %s) / 2), 2) + "+
+        "COS(RADIANS(%s)) * COS(RADIANS(latitude)) * POWER(SIN(RADIANS(longitude - %s) / 2), 2)))))",
+        models.EarthRadiusKm, d.least, lat, lat, lon)
+}
+
+// within is the SQL condition that a trail is within radius km of the point bound to lat and lon.
+// With PostGIS it uses ST_DWithin, which can use the spatial index on the location column.
+func (d dialect) within(lat, lon, radius string) string {
+    if d.geography {
+        return fmt.Sprintf("ST_DWithin(location, ST_MakePoint(%s, %s)::geography, %s::float8 * 1000, false)", lon, lat, radius)
+    }
+    return fmt.Sprintf("%s <= %s", d.distance(lat, lon), radius)
+}
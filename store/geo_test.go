@@ -0,0 +1,67 @@
+package store
+
+import (
+    "context"
+    "testing"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// testGeoStore runs the near filter and distance sort behaviour every TrailStore implementation must share
+func testGeoStore(t *testing.T, s TrailStore) {
+    ctx := context.Background()
+    at := func(lat, lon float64) (*float64, *float64) { return &lat, &lon }
+    trails := []models.Trail{
+        {FID: 1, Name: "chautauqua", HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo},
+        {FID: 2, Name: "flagstaff", HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo},
+        {FID: 3, Name: "south mesa", HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo},
+        {FID: 4, Name: "unmapped", HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo},
+    }
+    trails[0].Latitude, trails[0].Longitude = at(39.9986, -105.2816)
+    trails[1].Latitude, trails[1].Longitude = at(39.9999, -105.2929)
+    trails[2].Latitude, trails[2].Longitude = at(39.9383, -105.2593)
+    require.NoError(t, s.ReplaceAll(ctx, trails))
+
+    downtown := models.Point{Latitude: 40.015, Longitude: -105.2705}
+    fids := func(opts ListOptions) []int {
+        trails, err := s.List(ctx, opts)
+        require.NoError(t, err)
+        result := []int{}
+        for _, trail := range trails {
+            result = append(result, trail.FID)
+        }
+        return result
+    }
+
+    // Near keeps the trails within the radius; trails without coordinates never match
+    near := Filter{Near: &Near{Point: downtown, RadiusKm: 5}}
+    assert.Equal(t, []int{1, 2}, fids(ListOptions{Filters: []Filter{near}}))
+    assert.Equal(t, []int{}, fids(ListOptions{Filters: []Filter{{Near: &Near{Point: models.Point{}, RadiusKm: 100}}}}))
+    count, err := s.Count(ctx, []Filter{near, {Field: "name", Value: "flagstaff"}})
+    require.NoError(t, err)
+    assert.Equal(t, 1, count)
+
+    // Coordinate facets are ordered by value, not by their text
+    facets, err := s.Facets(ctx, []string{"longitude"}, nil)
+    require.NoError(t, err)
+    assert.Equal(t, []FacetCount{
+        {Value: -105.2929, Count: 1}, {Value: -105.2816, Count: 1}, {Value: -105.2593, Count: 1}, {Value: nil, Count: 1},
+    }, facets["longitude"])
+
+    // Distance sorts put trails without coordinates last in either direction
+    byDistance := func(desc bool) []SortKey {
+        return []SortKey{{Field: DistanceField, Desc: desc, From: &downtown}}
+    }
+    assert.Equal(t, []int{1, 2, 3, 4}, fids(ListOptions{Sort: byDistance(false)}))
+    assert.Equal(t, []int{3, 2, 1, 4}, fids(ListOptions{Sort: byDistance(true)}))
+    assert.Equal(t, []int{2, 3}, fids(ListOptions{Sort: byDistance(false), Limit: 2, Offset: 1}))
+
+    _, err = s.List(ctx, ListOptions{Filters: []Filter{{Near: &Near{Point: downtown}}}})
+    assert.Error(t, err, "near filters need a radius")
+    _, err = s.List(ctx, ListOptions{Sort: []SortKey{{Field: DistanceField}}})
+    assert.Error(t, err, "distance sorts need a point")
+    _, err = s.List(ctx, ListOptions{Sort: byDistance(false), After: &Cursor{Sort: byDistance(false), Values: []interface{}{1.0}}})
+    assert.ErrorIs(t, err, ErrInvalidCursor)
+}
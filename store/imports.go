@@ -0,0 +1,154 @@
+package store
+
+import (
+    "context"
+    "encoding/json"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+    "trail-finder/models"
+)
+
+// ImportStore keeps the history of CSV import jobs
+type ImportStore interface {
+    // CreateImport records a new import job
+    CreateImport(ctx context.Context, job models.ImportJob) error
+    // UpdateImport saves the progress of an existing import job, or returns ErrNotFound
+    UpdateImport(ctx context.Context, job models.ImportJob) error
+    // GetImport returns the import job with the given ID, or ErrNotFound
+    GetImport(ctx context.Context, id string) (models.ImportJob, error)
+    // ListImports returns up to limit import jobs, newest first; 0 means no limit
+    ListImports(ctx context.Context, limit int) ([]models.ImportJob, error)
+    // HeartbeatImports records at as the heartbeat of the queued and running jobs of owner
+    HeartbeatImports(ctx context.Context, owner string, at time.Time) error
+    // FailStaleImports fails with reason the queued and running jobs of other owners whose last heartbeat,
+    // or creation when they have none, is before cutoff. It returns how many jobs it failed.
+    FailStaleImports(ctx context.Context, owner string, cutoff time.Time, reason string) (int, error)
+}
+
+// importColumns lists the imports table columns in the order used by importScanFields and importValues
+var importColumns = []string{
+    "id", "state", "source", "mode", "rows_read", "rows_imported", "rows_rejected", "rejected_rows",
+    "trails_inserted", "trails_updated", "trails_unchanged", "trails_deleted", "version_id", "error",
+    "owner", "created_at", "started_at", "finished_at", "heartbeat_at",
+}
+
+// importScan holds the scanned columns of an imports row until they are converted into a job
+type importScan struct {
+    job      models.ImportJob
+    rejected []byte
+}
+
+// importScanFields returns pointers to the scan targets in importColumns order, for use with rows.Scan
+func (s *importScan) importScanFields() []interface{} {
+    j := &s.job
+    return []interface{}{
+        &j.ID, &j.State, &j.Source, &j.Mode, &j.RowsRead, &j.RowsImported, &j.RowsRejected, &s.rejected,
+        &j.Inserted, &j.Updated, &j.Unchanged, &j.Deleted, &j.Version, &j.Error,
+        &j.Owner, &j.CreatedAt, &j.StartedAt, &j.FinishedAt, &j.HeartbeatAt,
+    }
+}
+
+// importJob converts the scanned row into a job, decoding its rejected rows
+func (s *importScan) importJob() (models.ImportJob, error) {
+    job := s.job
+    job.Rejected = []models.RejectedRow{}
+    if err := json.Unmarshal(s.rejected, &job.Rejected); err != nil {
+        return models.ImportJob{}, fmt.Errorf("invalid rejected rows of import %s: %w", job.ID, err)
+    }
+    return job, nil
+}
+
+// importValues returns the job fields in importColumns order, for use as query arguments.
+// Jobs without a mode are recorded as replace imports.
+func importValues(job models.ImportJob) ([]interface{}, error) {
+    mode := job.Mode
+    if mode == "" {
+        mode = models.ImportReplace
+    }
+    rejected := job.Rejected
+    if rejected == nil {
+        rejected = []models.RejectedRow{}
+    }
+    data, err := json.Marshal(rejected)
+    if err != nil {
+        return nil, fmt.Errorf("failed to encode rejected rows: %w", err)
+    }
+    return []interface{}{
+        job.ID, string(job.State), job.Source, string(mode), job.RowsRead, job.RowsImported, job.RowsRejected, string(data),
+        job.Inserted, job.Updated, job.Unchanged, job.Deleted, job.Version, job.Error,
+        job.Owner, job.CreatedAt, job.StartedAt, job.FinishedAt, job.HeartbeatAt,
+    }, nil
+}
+
+// insertImportQuery builds the INSERT of every imports column
+func (d dialect) insertImportQuery() string {
+    placeholders := make([]string, len(importColumns))
+    for i := range importColumns {
+        placeholders[i] = d.placeholder(i + 1)
+    }
+    return fmt.Sprintf("INSERT INTO imports (%s) VALUES (%s)", strings.Join(importColumns, ", "), strings.Join(placeholders, ", "))
+}
+
+// updateImportQuery builds the UPDATE of every column of the import identified by the first argument,
+// taking the arguments in importColumns order like the INSERT
+func (d dialect) updateImportQuery() string {
+    updates := make([]string, 0, len(importColumns)-1)
+    for i, column := range importColumns[1:] {
+        updates = append(updates, fmt.Sprintf("%s = %s", column, d.placeholder(i+2)))
+    }
+    return fmt.Sprintf("UPDATE imports SET %s WHERE id = %s", strings.Join(updates, ", "), d.placeholder(1))
+}
+
+// getImportQuery selects the import identified by the first argument
+func (d dialect) getImportQuery() string {
+    return fmt.Sprintf("SELECT %s FROM imports WHERE id = %s", strings.Join(importColumns, ", "), d.placeholder(1))
+}
+
+// listImportsQuery selects imports newest first, up to limit when it is positive
+func (d dialect) listImportsQuery(limit int) (string, []interface{}) {
+    query := fmt.Sprintf("SELECT %s FROM imports ORDER BY created_at DESC, id DESC", strings.Join(importColumns, ", "))
+    if limit > 0 {
+        return query + " LIMIT " + d.placeholder(1), []interface{}{limit}
+    }
+    return query, nil
+}
+
+// heartbeatImportsQuery updates the heartbeat of the unfinished jobs of an owner,
+// taking the heartbeat time and the owner as arguments
+func (d dialect) heartbeatImportsQuery() string {
+    return fmt.Sprintf("UPDATE imports SET heartbeat_at = %s WHERE owner = %s AND state IN ('queued', 'running')",
+        d.placeholder(1), d.placeholder(2))
+}
+
+// failStaleImportsQuery fails the unfinished jobs of other owners that stopped sending heartbeats,
+// taking the error, the finish time, the owner and the cutoff as arguments
+func (d dialect) failStaleImportsQuery() string {
+    return fmt.Sprintf("UPDATE imports SET state = 'failed', error = %s, finished_at = %s"+
+        " WHERE owner <> %s AND state IN ('queued', 'running') AND COALESCE(heartbeat_at, created_at) < %s",
+        d.placeholder(1), d.placeholder(2), d.placeholder(3), d.placeholder(4))
+}
+
+// sortImports orders jobs newest first, with ties broken by ID like listImportsQuery
+func sortImports(jobs []models.ImportJob) {
+    sort.Slice(jobs, func(i, j int) bool {
+        if !jobs[i].CreatedAt.Equal(jobs[j].CreatedAt) {
+            return jobs[i].CreatedAt.After(jobs[j].CreatedAt)
+        }
+        return jobs[i].ID > jobs[j].ID
+    })
+}
+
+// cloneImport copies a job so callers cannot modify stored rejected rows or timestamps through shared values.
+// Like importValues, it records jobs without a mode as replace imports.
+func cloneImport(job models.ImportJob) models.ImportJob {
+    if job.Mode == "" {
+        job.Mode = models.ImportReplace
+    }
+    job.Rejected = append([]models.RejectedRow{}, job.Rejected...)
+    job.StartedAt = cloneTime(job.StartedAt)
+    job.FinishedAt = cloneTime(job.FinishedAt)
+    job.HeartbeatAt = cloneTime(job.HeartbeatAt)
+    return job
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+    "context"
+    "testing"
+    "time"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// testImportStore runs the import history behaviour every TrailStore implementation must share
+func testImportStore(t *testing.T, s TrailStore) {
+    ctx := context.Background()
+    created := time.Now().UTC().Truncate(time.Millisecond)
+    suffix := created.Format("150405.000")
+
+    older := models.ImportJob{ID: "older-" + suffix, State: models.ImportSucceeded, Source: "a.csv", CreatedAt: created.Add(-time.Minute)}
+    job := models.ImportJob{ID: "job-" + suffix, State: models.ImportQueued, Source: "trails.csv", CreatedAt: created}
+    require.NoError(t, s.CreateImport(ctx, older))
+    require.NoError(t, s.CreateImport(ctx, job))
+
+    got, err := s.GetImport(ctx, job.ID)
+    require.NoError(t, err)
+    assert.Equal(t, models.ImportQueued, got.State)
+    assert.Equal(t, "trails.csv", got.Source)
+    assert.Empty(t, got.Rejected)
+    assert.Nil(t, got.StartedAt)
+
+    // Progress, rejected rows and timing are saved
+    started, finished := created.Add(time.Second), created.Add(2*time.Second)
+    job.State, job.StartedAt, job.FinishedAt = models.ImportSucceeded, &started, &finished
+    job.RowsRead, job.RowsImported, job.Version = 3, 2, 7
+    job.Reject(models.RejectedRow{Line: 3, Column: "FID", Value: "x", Reason: "is not an integer"})
+    require.NoError(t, s.UpdateImport(ctx, job))
+
+    got, err = s.GetImport(ctx, job.ID)
+    require.NoError(t, err)
+    assert.Equal(t, models.ImportSucceeded, got.State)
+    assert.Equal(t, 3, got.RowsRead)
+    assert.Equal(t, 2, got.RowsImported)
+    assert.Equal(t, 1, got.RowsRejected)
+    assert.Equal(t, 7, got.Version)
+    assert.Equal(t, []models.RejectedRow{{Line: 3, Column: "FID", Value: "x", Reason: "is not an integer"}}, got.Rejected)
+    require.NotNil(t, got.FinishedAt)
+    assert.True(t, finished.Equal(*got.FinishedAt))
+    assert.True(t, created.Equal(got.CreatedAt))
+
+    // Newest imports are listed first
+    jobs, err := s.ListImports(ctx, 2)
+    require.NoError(t, err)
+    require.Len(t, jobs, 2)
+    assert.Equal(t, job.ID, jobs[0].ID)
+    assert.Equal(t, older.ID, jobs[1].ID)
+
+    // Unfinished jobs of other owners fail once their heartbeat is older than the cutoff
+    beat := created.Add(-time.Hour)
+    mine := models.ImportJob{ID: "mine-" + suffix, State: models.ImportRunning, Owner: "a", CreatedAt: beat, HeartbeatAt: &beat}
+    theirs := models.ImportJob{ID: "theirs-" + suffix, State: models.ImportQueued, Owner: "b", CreatedAt: beat, HeartbeatAt: &beat}
+    require.NoError(t, s.CreateImport(ctx, mine))
+    require.NoError(t, s.CreateImport(ctx, theirs))
+    require.NoError(t, s.HeartbeatImports(ctx, "b", created))
+    got, err = s.GetImport(ctx, theirs.ID)
+    require.NoError(t, err)
+    require.NotNil(t, got.HeartbeatAt)
+    assert.True(t, created.Equal(*got.HeartbeatAt))
+    assert.Equal(t, "b", got.Owner)
+
+    failed, err := s.FailStaleImports(ctx, "a", created.Add(-time.Minute), "interrupted")
+    require.NoError(t, err)
+    assert.Equal(t, 0, failed, "jobs with a recent heartbeat and jobs of the caller are not stale")
+    failed, err = s.FailStaleImports(ctx, "c", created.Add(-time.Minute), "interrupted")
+    require.NoError(t, err)
+    assert.Equal(t, 1, failed)
+    got, err = s.GetImport(ctx, mine.ID)
+    require.NoError(t, err)
+    assert.Equal(t, models.ImportFailed, got.State)
+    assert.Equal(t, "interrupted", got.Error)
+    assert.NotNil(t, got.FinishedAt)
+
+    _, err = s.GetImport(ctx, "missing")
+    assert.ErrorIs(t, err, ErrNotFound)
+    assert.ErrorIs(t, s.UpdateImport(ctx, models.ImportJob{ID: "missing", State: models.ImportFailed, CreatedAt: created}), ErrNotFound)
+}
@@ -0,0 +1,695 @@
+package store
+
+import (
+    "cmp"
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+    "sync"
+    "time"
+    "trail-finder/models"
+)
+
+// MemoryStore is an in-memory TrailStore, used by tests and for running the API without a database
+type MemoryStore struct {
+    mu       sync.RWMutex
+    trails   map[int]models.Trail
+    imports  map[string]models.ImportJob
+    versions []memoryVersion // Ordered by ID, oldest first
+}
+
+// memoryVersion is a dataset version with the snapshot of its trails, ordered by FID
+type memoryVersion struct {
+    version models.DatasetVersion
+    trails  []models.Trail
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+    return &MemoryStore{trails: map[int]models.Trail{}, imports: map[string]models.ImportJob{}}
+}
+
+// List returns the matching trails in the requested order, with ties broken by FID
+func (s *MemoryStore) List(ctx context.Context, opts ListOptions) ([]models.Trail, error) {
+    if err := validateSort(opts.Sort, opts.After); err != nil {
+        return nil, err
+    }
+    matches, err := s.matching(opts.Filters)
+    if err != nil {
+        return nil, err
+    }
+    return pageTrails(matches, opts), nil
+}
+
+// Stream calls fn with each trail List returns; the in-memory trails are already held together
+func (s *MemoryStore) Stream(ctx context.Context, opts ListOptions, fn func(models.Trail) error) error {
+    trails, err := s.List(ctx, opts)
+    if err != nil {
+        return err
+    }
+    return streamSlice(trails, fn)
+}
+
+// streamSlice calls fn with each trail in order, stopping at the first error
+func streamSlice(trails []models.Trail, fn func(models.Trail) error) error {
+    for _, trail := range trails {
+        if err := fn(trail); err != nil {
+            return err
+        }
+    }
+    return nil
+}
+
+// pageTrails sorts the matching trails and cuts the page requested by the options
+func pageTrails(matches []models.Trail, opts ListOptions) []models.Trail {
+    sortTrails(matches, opts.Sort)
+    if opts.After != nil {
+        after := matches[:0]
+        for i := range matches {
+            if afterCursor(&matches[i], opts.After) {
+                after = append(after, matches[i])
+            }
+        }
+        matches = after
+    }
+
+    if opts.Offset >= len(matches) {
+        return []models.Trail{}
+    }
+    matches = matches[opts.Offset:]
+    if opts.Limit > 0 && opts.Limit < len(matches) {
+        matches = matches[:opts.Limit]
+    }
+    return matches
+}
+
+// Get returns the trail with the given FID
+func (s *MemoryStore) Get(ctx context.Context, fid int) (models.Trail, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    trail, ok := s.trails[fid]
+    if !ok {
+        return models.Trail{}, ErrNotFound
+    }
+    return cloneTrail(trail), nil
+}
+
+// Insert adds a new trail, or returns ErrConflict if its FID is taken
+func (s *MemoryStore) Insert(ctx context.Context, trail models.Trail) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, exists := s.trails[trail.FID]; exists {
+        return ErrConflict
+    }
+    s.trails[trail.FID] = cloneTrail(trail)
+    return nil
+}
+
+// Update replaces the trail with the same FID, or returns ErrNotFound
+func (s *MemoryStore) Update(ctx context.Context, trail models.Trail) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, exists := s.trails[trail.FID]; !exists {
+        return ErrNotFound
+    }
+    s.trails[trail.FID] = cloneTrail(trail)
+    return nil
+}
+
+// Modify replaces the trail with the given FID by the trail fn makes of it, holding the lock throughout
+func (s *MemoryStore) Modify(ctx context.Context, fid int, fn func(models.Trail) (models.Trail, error)) (models.Trail, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    existing, ok := s.trails[fid]
+    if !ok {
+        return models.Trail{}, ErrNotFound
+    }
+    trail, err := fn(cloneTrail(existing))
+    if err != nil {
+        return models.Trail{}, err
+    }
+    trail.FID = fid
+    s.trails[fid] = cloneTrail(trail)
+    return trail, nil
+}
+
+// Delete removes the trail with the given FID, or returns ErrNotFound
+func (s *MemoryStore) Delete(ctx context.Context, fid int) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, exists := s.trails[fid]; !exists {
+        return ErrNotFound
+    }
+    delete(s.trails, fid)
+    return nil
+}
+
+// Upsert inserts or replaces the trail with the same FID
+func (s *MemoryStore) Upsert(ctx context.Context, trail models.Trail) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.trails[trail.FID] = cloneTrail(trail)
+    return nil
+}
+
+// ReplaceAll replaces every stored trail with the given set
+func (s *MemoryStore) ReplaceAll(ctx context.Context, trails []models.Trail) error {
+    _, _, err := s.ReplaceAllFrom(ctx, SliceSource(trails), nil)
+    return err
+}
+
+// ReplaceAllFrom reads the source into memory and replaces every trail with it, snapshotting the result
+// under the same lock when a label is given
+func (s *MemoryStore) ReplaceAllFrom(ctx context.Context, src TrailSource, label VersionLabel) (int, models.DatasetVersion, error) {
+    replacement := map[int]models.Trail{}
+    for src.Next() {
+        trail := src.Trail()
+        if _, exists := replacement[trail.FID]; exists {
+            return 0, models.DatasetVersion{}, fmt.Errorf("duplicate fid %d", trail.FID)
+        }
+        replacement[trail.FID] = cloneTrail(trail)
+    }
+    if err := src.Err(); err != nil {
+        return 0, models.DatasetVersion{}, err
+    }
+
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    s.trails = replacement
+    return len(replacement), s.snapshotLabel(label), nil
+}
+
+// Merge applies the new and changed trails. Soft-deleted trails are simply dropped, since nothing can read them.
+func (s *MemoryStore) Merge(ctx context.Context, trails []models.Trail, softDelete bool, label VersionLabel) (models.MergeCounts, models.DatasetVersion, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    stored := make([]models.Trail, 0, len(s.trails))
+    for _, trail := range s.trails {
+        stored = append(stored, trail)
+    }
+    plan := planMerge(stored, trails, softDelete)
+    for _, trail := range plan.upserts {
+        s.trails[trail.FID] = cloneTrail(trail)
+    }
+    for _, fid := range plan.deletes {
+        delete(s.trails, fid)
+    }
+    return plan.counts, s.snapshotLabel(label), nil
+}
+
+// Search ranks the matching trails against the text
+func (s *MemoryStore) Search(ctx context.Context, text string, opts ListOptions) ([]SearchResult, error) {
+    if err := validateSort(opts.Sort, opts.After); err != nil {
+        return nil, err
+    }
+    matches, err := s.matching(opts.Filters)
+    if err != nil {
+        return nil, err
+    }
+    return searchTrails(matches, searchTerms(text), opts), nil
+}
+
+// SearchRanking returns RankingWords, as searches are ranked by searchTrails
+func (s *MemoryStore) SearchRanking() string {
+    return RankingWords
+}
+
+// Count returns the number of matching trails
+func (s *MemoryStore) Count(ctx context.Context, filters []Filter) (int, error) {
+    matches, err := s.matching(filters)
+    if err != nil {
+        return 0, err
+    }
+    return len(matches), nil
+}
+
+// Facets counts the distinct values of each field among the matching trails
+func (s *MemoryStore) Facets(ctx context.Context, fields []string, filters []Filter) (map[string][]FacetCount, error) {
+    if err := validateFields(fields...); err != nil {
+        return nil, err
+    }
+    matches, err := s.matching(filters)
+    if err != nil {
+        return nil, err
+    }
+
+    facets := make(map[string][]FacetCount, len(fields))
+    for _, field := range fields {
+        counts := map[interface{}]int{}
+        for i := range matches {
+            value, _ := matches[i].FieldValue(field)
+            counts[value]++
+        }
+
+        result := make([]FacetCount, 0, len(counts))
+        for value, count := range counts {
+            result = append(result, FacetCount{Value: value, Count: count})
+        }
+        sort.Slice(result, func(i, j int) bool { return lessValue(result[i].Value, result[j].Value) })
+        facets[field] = result
+    }
+    return facets, nil
+}
+
+// CreateImport records a new import job
+func (s *MemoryStore) CreateImport(ctx context.Context, job models.ImportJob) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, exists := s.imports[job.ID]; exists {
+        return fmt.Errorf("import %s already exists", job.ID)
+    }
+    s.imports[job.ID] = cloneImport(job)
+    return nil
+}
+
+// UpdateImport saves the progress of an existing import job, or returns ErrNotFound
+func (s *MemoryStore) UpdateImport(ctx context.Context, job models.ImportJob) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if _, exists := s.imports[job.ID]; !exists {
+        return ErrNotFound
+    }
+    s.imports[job.ID] = cloneImport(job)
+    return nil
+}
+
+// GetImport returns the import job with the given ID, or ErrNotFound
+func (s *MemoryStore) GetImport(ctx context.Context, id string) (models.ImportJob, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    job, ok := s.imports[id]
+    if !ok {
+        return models.ImportJob{}, ErrNotFound
+    }
+    return cloneImport(job), nil
+}
+
+// ListImports returns up to limit import jobs, newest first
+func (s *MemoryStore) ListImports(ctx context.Context, limit int) ([]models.ImportJob, error) {
+    s.mu.RLock()
+    jobs := make([]models.ImportJob, 0, len(s.imports))
+    for _, job := range s.imports {
+        jobs = append(jobs, cloneImport(job))
+    }
+    s.mu.RUnlock()
+
+    sortImports(jobs)
+    if limit > 0 && limit < len(jobs) {
+        jobs = jobs[:limit]
+    }
+    return jobs, nil
+}
+
+// HeartbeatImports records at as the heartbeat of the queued and running jobs of owner
+func (s *MemoryStore) HeartbeatImports(ctx context.Context, owner string, at time.Time) error {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    for id, job := range s.imports {
+        if job.Owner == owner && !job.State.Finished() {
+            job.HeartbeatAt = &at
+            s.imports[id] = cloneImport(job)
+        }
+    }
+    return nil
+}
+
+// FailStaleImports fails the unfinished jobs of other owners whose last heartbeat is before cutoff
+func (s *MemoryStore) FailStaleImports(ctx context.Context, owner string, cutoff time.Time, reason string) (int, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    failed, finished := 0, time.Now().UTC()
+    for id, job := range s.imports {
+        seen := job.CreatedAt
+        if job.HeartbeatAt != nil {
+            seen = *job.HeartbeatAt
+        }
+        if job.Owner != owner && !job.State.Finished() && seen.Before(cutoff) {
+            job.State, job.Error, job.FinishedAt = models.ImportFailed, reason, &finished
+            s.imports[id] = cloneImport(job)
+            failed++
+        }
+    }
+    return failed, nil
+}
+
+// CreateVersion snapshots the current trails as a new dataset version
+func (s *MemoryStore) CreateVersion(ctx context.Context, version models.DatasetVersion) (models.DatasetVersion, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    return s.snapshot(newVersion(version)), nil
+}
+
+// snapshotLabel records the trails as the version described by the label, if any. The caller must hold the write lock.
+func (s *MemoryStore) snapshotLabel(label VersionLabel) models.DatasetVersion {
+    if label == nil {
+        return models.DatasetVersion{}
+    }
+    return s.snapshot(newVersion(label()))
+}
+
+// snapshot records the version with a copy of the current trails. The caller must hold the write lock.
+func (s *MemoryStore) snapshot(version models.DatasetVersion) models.DatasetVersion {
+    trails := make([]models.Trail, 0, len(s.trails))
+    for _, trail := range s.trails {
+        trails = append(trails, cloneTrail(trail))
+    }
+    sort.Slice(trails, func(i, j int) bool { return trails[i].FID < trails[j].FID })
+
+    version.ID = len(s.versions) + 1
+    version.TrailCount = len(trails)
+    s.versions = append(s.versions, memoryVersion{version: version, trails: trails})
+    return version
+}
+
+// GetVersion returns the dataset version with the given ID, or ErrNotFound
+func (s *MemoryStore) GetVersion(ctx context.Context, id int) (models.DatasetVersion, error) {
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    if id < 1 || id > len(s.versions) {
+        return models.DatasetVersion{}, ErrNotFound
+    }
+    return s.versions[id-1].version, nil
+}
+
+// ListVersions returns up to limit dataset versions, newest first
+func (s *MemoryStore) ListVersions(ctx context.Context, limit int) ([]models.DatasetVersion, error) {
+    s.mu.RLock()
+    versions := make([]models.DatasetVersion, 0, len(s.versions))
+    for _, v := range s.versions {
+        versions = append(versions, v.version)
+    }
+    s.mu.RUnlock()
+
+    sortVersions(versions)
+    if limit > 0 && limit < len(versions) {
+        versions = versions[:limit]
+    }
+    return versions, nil
+}
+
+// ListVersionTrails filters, sorts and pages the snapshot of a dataset version like List
+func (s *MemoryStore) ListVersionTrails(ctx context.Context, id int, opts ListOptions) ([]models.Trail, int, error) {
+    if err := validateSort(opts.Sort, opts.After); err != nil {
+        return nil, 0, err
+    }
+    if err := validateFilters(opts.Filters); err != nil {
+        return nil, 0, err
+    }
+
+    s.mu.RLock()
+    if id < 1 || id > len(s.versions) {
+        s.mu.RUnlock()
+        return nil, 0, ErrNotFound
+    }
+    matches := []models.Trail{}
+    for _, trail := range s.versions[id-1].trails {
+        if matchesFilters(&trail, opts.Filters) {
+            matches = append(matches, cloneTrail(trail))
+        }
+    }
+    s.mu.RUnlock()
+
+    return pageTrails(matches, opts), len(matches), nil
+}
+
+// StreamVersionTrails calls fn with each trail ListVersionTrails returns
+func (s *MemoryStore) StreamVersionTrails(ctx context.Context, id int, opts ListOptions, fn func(models.Trail) error) error {
+    trails, _, err := s.ListVersionTrails(ctx, id, opts)
+    if err != nil {
+        return err
+    }
+    return streamSlice(trails, fn)
+}
+
+// Rollback replaces the trails with the snapshot of a version and records the result as a new version
+func (s *MemoryStore) Rollback(ctx context.Context, id int) (models.DatasetVersion, error) {
+    s.mu.Lock()
+    defer s.mu.Unlock()
+
+    if id < 1 || id > len(s.versions) {
+        return models.DatasetVersion{}, ErrNotFound
+    }
+    restored := s.versions[id-1]
+    s.trails = make(map[int]models.Trail, len(restored.trails))
+    for _, trail := range restored.trails {
+        s.trails[trail.FID] = cloneTrail(trail)
+    }
+    return s.snapshot(rollbackVersion(restored.version)), nil
+}
+
+// Ping always succeeds, since there is no database to reach
+func (s *MemoryStore) Ping(ctx context.Context) error {
+    return nil
+}
+
+// matching returns copies of the trails that match every filter, ordered by FID
+func (s *MemoryStore) matching(filters []Filter) ([]models.Trail, error) {
+    if err := validateFilters(filters); err != nil {
+        return nil, err
+    }
+
+    s.mu.RLock()
+    defer s.mu.RUnlock()
+
+    matches := []models.Trail{}
+    for _, trail := range s.trails {
+        if matchesFilters(&trail, filters) {
+            matches = append(matches, cloneTrail(trail))
+        }
+    }
+    sort.Slice(matches, func(i, j int) bool { return matches[i].FID < matches[j].FID })
+    return matches, nil
+}
+
+// matchesFilters reports whether the trail satisfies every filter
+func matchesFilters(trail *models.Trail, filters []Filter) bool {
+    for _, f := range filters {
+        if !matchesFilter(trail, f) {
+            return false
+        }
+    }
+    return true
+}
+
+// matchesFilter reports whether the trail satisfies a single filter or OR group
+func matchesFilter(trail *models.Trail, f Filter) bool {
+    if f.Any != nil {
+        for _, alternative := range f.Any {
+            if matchesFilter(trail, alternative) {
+                return true
+            }
+        }
+        return false
+    }
+    if f.Near != nil {
+        distance, ok := trail.DistanceFrom(f.Near.Point)
+        return ok && distance <= f.Near.RadiusKm
+    }
+
+    value, _ := trail.FieldValue(f.Field)
+    switch f.op() {
+    case OpEq:
+        return equalValue(value, f.Value)
+    case OpNe:
+        return !equalValue(value, f.Value)
+    case OpIn, OpNotIn:
+        found := false
+        for _, wanted := range f.Values {
+            if equalValue(value, wanted) {
+                found = true
+                break
+            }
+        }
+        return found == (f.op() == OpIn)
+    }
+
+    cmp, ok := compareValue(value, f.Value)
+    if !ok {
+        return false
+    }
+    switch f.op() {
+    case OpGt:
+        return cmp > 0
+    case OpGte:
+        return cmp >= 0
+    case OpLt:
+        return cmp < 0
+    default:
+        return cmp <= 0
+    }
+}
+
+// equalValue compares a stored value with a normalized filter value
+func equalValue(stored, wanted interface{}) bool {
+    if t, ok := stored.(time.Time); ok {
+        w, ok := wanted.(time.Time)
+        return ok && t.Equal(w)
+    }
+    return stored == wanted
+}
+
+// compareValue orders a stored value against a filter value, returning false when they cannot be compared,
+// as with unset values, which match no range
+func compareValue(stored, wanted interface{}) (int, bool) {
+    switch s := stored.(type) {
+    case int:
+        if w, ok := wanted.(int); ok {
+            return cmp.Compare(s, w), true
+        }
+    case float64:
+        if w, ok := wanted.(float64); ok {
+            return cmp.Compare(s, w), true
+        }
+    case time.Time:
+        if w, ok := wanted.(time.Time); ok {
+            return s.Compare(w), true
+        }
+    case string:
+        if w, ok := wanted.(string); ok {
+            return strings.Compare(s, w), true
+        }
+    case models.HorseTrail:
+        if w, ok := wanted.(models.HorseTrail); ok {
+            return strings.Compare(string(s), string(w)), true
+        }
+    case models.ADAParking:
+        if w, ok := wanted.(models.ADAParking); ok {
+            return strings.Compare(string(s), string(w)), true
+        }
+    case bool:
+        if w, ok := wanted.(bool); ok {
+            switch {
+            case s == w:
+                return 0, true
+            case w:
+                return -1, true
+            default:
+                return 1, true
+            }
+        }
+    }
+    return 0, false
+}
+
+// sortTrails orders trails by the sort keys, keeping the existing order between ties.
+// Unset values sort last in either direction, as they do with NULLS LAST in SQL.
+func sortTrails(trails []models.Trail, keys []SortKey) {
+    if len(keys) == 0 {
+        return
+    }
+    sort.SliceStable(trails, func(i, j int) bool { return compareByKeys(&trails[i], &trails[j], keys) < 0 })
+}
+
+// compareByKeys orders two trails by the sort keys, returning 0 when they tie on every key
+func compareByKeys(a, b *models.Trail, keys []SortKey) int {
+    for _, key := range keys {
+        if cmp := compareKeyValues(sortValue(a, key), sortValue(b, key), key.Desc); cmp != 0 {
+            return cmp
+        }
+    }
+    return 0
+}
+
+// sortValue returns the value a trail is ordered by for a sort key, nil when it is unset
+func sortValue(trail *models.Trail, key SortKey) interface{} {
+    if key.Field == DistanceField {
+        if distance, ok := trail.DistanceFrom(*key.From); ok {
+            return distance
+        }
+        return nil
+    }
+    value, _ := trail.FieldValue(key.Field)
+    return value
+}
+
+// compareKeyValues orders two values of a sort key, placing unset values last in either direction
+func compareKeyValues(a, b interface{}, desc bool) int {
+    switch {
+    case a == nil && b == nil:
+        return 0
+    case a == nil:
+        return 1
+    case b == nil:
+        return -1
+    }
+
+    cmp, _ := compareValue(a, b)
+    if desc {
+        return -cmp
+    }
+    return cmp
+}
+
+// lessValue orders facet values, placing unset values last
+func lessValue(a, b interface{}) bool {
+    if a == nil || b == nil {
+        return b == nil && a != nil
+    }
+    switch av := a.(type) {
+    case bool:
+        return !av && b.(bool)
+    case int:
+        return av < b.(int)
+    case float64:
+        return av < b.(float64)
+    case time.Time:
+        return av.Before(b.(time.Time))
+    default:
+        return fmt.Sprint(a) < fmt.Sprint(b)
+    }
+}
+
+// cloneTrail copies a trail so callers cannot modify stored optional values through shared pointers
+func cloneTrail(trail models.Trail) models.Trail {
+    trail.DogTube = cloneInt(trail.DogTube)
+    trail.TrashCans = cloneInt(trail.TrashCans)
+    trail.ParkSpaces = cloneInt(trail.ParkSpaces)
+    trail.HorseTrailCount = cloneInt(trail.HorseTrailCount)
+    trail.ADAParkingCount = cloneInt(trail.ADAParkingCount)
+    trail.DateFrom = cloneTime(trail.DateFrom)
+    trail.DateTo = cloneTime(trail.DateTo)
+    trail.Latitude = cloneFloat(trail.Latitude)
+    trail.Longitude = cloneFloat(trail.Longitude)
+    trail.Distance = nil // Only set on listings around a point
+    return trail
+}
+
+func cloneFloat(f *float64) *float64 {
+    if f == nil {
+        return nil
+    }
+    v := *f
+    return &v
+}
+
+func cloneInt(n *int) *int {
+    if n == nil {
+        return nil
+    }
+    v := *n
+    return &v
+}
+
+func cloneTime(t *time.Time) *time.Time {
+    if t == nil {
+        return nil
+    }
+    v := *t
+    return &v
+}
@@ -0,0 +1,285 @@
+package store
+
+import (
+    "context"
+    "errors"
+    "sync"
+    "testing"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// testTrails returns a small fixed data set for exercising a TrailStore
+func testTrails() []models.Trail {
+    two := 2
+    return []models.Trail{
+        {FID: 3, Name: "mesa", Restrooms: true, HorseTrail: models.HorseTrailDesignated, ADAParking: models.ADAParkingNo, DogTube: &two},
+        {FID: 1, Name: "chautauqua", Restrooms: true, HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo},
+        {FID: 2, Name: "flagstaff", Restrooms: false, HorseTrail: models.HorseTrailDesignated, ADAParking: models.ADAParkingNo},
+    }
+}
+
+// testTrailStore runs the behaviour every TrailStore implementation must share
+func testTrailStore(t *testing.T, s TrailStore) {
+    ctx := context.Background()
+    require.NoError(t, s.ReplaceAll(ctx, testTrails()))
+
+    // List orders by FID and applies filters, limit and offset
+    trails, err := s.List(ctx, ListOptions{})
+    require.NoError(t, err)
+    require.Len(t, trails, 3)
+    assert.Equal(t, []int{1, 2, 3}, []int{trails[0].FID, trails[1].FID, trails[2].FID})
+
+    trails, err = s.List(ctx, ListOptions{Filters: []Filter{{Field: "restrooms", Value: true}}, Limit: 1, Offset: 1})
+    require.NoError(t, err)
+    require.Len(t, trails, 1)
+    assert.Equal(t, 3, trails[0].FID)
+
+    _, err = s.List(ctx, ListOptions{Filters: []Filter{{Field: "nope", Value: true}}})
+    assert.Error(t, err, "unknown filter fields must be rejected")
+
+    // Operators, with unset counts differing from every value but matching no range
+    fids := func(filters ...Filter) []int {
+        trails, err := s.List(ctx, ListOptions{Filters: filters})
+        require.NoError(t, err)
+        result := []int{}
+        for _, trail := range trails {
+            result = append(result, trail.FID)
+        }
+        return result
+    }
+    assert.Equal(t, []int{1, 2}, fids(Filter{Field: "dog_tube", Op: OpNe, Value: 2}))
+    assert.Equal(t, []int{3}, fids(Filter{Field: "dog_tube", Op: OpGte, Value: 1}))
+    assert.Equal(t, []int{}, fids(Filter{Field: "dog_tube", Op: OpLt, Value: 2}))
+    assert.Equal(t, []int{1, 2}, fids(Filter{Field: "name", Op: OpNotIn, Values: []interface{}{"mesa"}}))
+    assert.Equal(t, []int{2, 3}, fids(
+        Filter{Field: "horse_trail", Op: OpIn, Values: []interface{}{models.HorseTrailDesignated, models.HorseTrailPossible}},
+    ))
+    assert.Equal(t, []int{2, 3}, fids(Filter{Any: []Filter{
+        {Field: "restrooms", Value: false},
+        {Field: "name", Value: "mesa"},
+    }}))
+    assert.Equal(t, []int{3}, fids(
+        Filter{Field: "restrooms", Value: true},
+        Filter{Any: []Filter{{Field: "dog_tube", Op: OpGt, Value: 1}, {Field: "name", Value: "flagstaff"}}},
+    ))
+
+    // Sort keys order the listing, unset values last, with ties broken by FID
+    sorted := func(keys ...SortKey) []int {
+        trails, err := s.List(ctx, ListOptions{Sort: keys})
+        require.NoError(t, err)
+        result := []int{}
+        for _, trail := range trails {
+            result = append(result, trail.FID)
+        }
+        return result
+    }
+    assert.Equal(t, []int{1, 2, 3}, sorted(SortKey{Field: "name"}))
+    assert.Equal(t, []int{3, 2, 1}, sorted(SortKey{Field: "name", Desc: true}))
+    assert.Equal(t, []int{3, 1, 2}, sorted(SortKey{Field: "dog_tube", Desc: true}))
+    assert.Equal(t, []int{2, 1, 3}, sorted(SortKey{Field: "restrooms"}))
+    assert.Equal(t, []int{3, 2, 1}, sorted(SortKey{Field: "horse_trail"}, SortKey{Field: "name", Desc: true}))
+
+    // Following cursors one trail at a time visits the same order as a single listing, including unset values
+    for _, keys := range [][]SortKey{nil, {{Field: "dog_tube", Desc: true}}, {{Field: "horse_trail"}, {Field: "dog_tube"}}} {
+        visited := []int{}
+        var after *Cursor
+        for {
+            page, err := s.List(ctx, ListOptions{Sort: keys, After: after, Limit: 1})
+            require.NoError(t, err)
+            if len(page) == 0 {
+                break
+            }
+            visited = append(visited, page[0].FID)
+            after, err = DecodeCursor(CursorAfter(&page[0], keys).Encode(), keys, false, "")
+            require.NoError(t, err)
+        }
+        assert.Equal(t, sorted(keys...), visited, "cursor pages for sort %q", FormatSort(keys))
+    }
+
+    // Stream visits the trails of a listing in the same order, and stops at the first error of its callback
+    for _, keys := range [][]SortKey{nil, {{Field: "dog_tube", Desc: true}}} {
+        streamed := []int{}
+        err := s.Stream(ctx, ListOptions{Sort: keys}, func(trail models.Trail) error {
+            streamed = append(streamed, trail.FID)
+            return nil
+        })
+        require.NoError(t, err)
+        assert.Equal(t, sorted(keys...), streamed, "stream for sort %q", FormatSort(keys))
+    }
+    stop := errors.New("stop")
+    calls := 0
+    err = s.Stream(ctx, ListOptions{}, func(models.Trail) error {
+        calls++
+        return stop
+    })
+    assert.Equal(t, stop, err)
+    assert.Equal(t, 1, calls)
+
+    _, err = s.List(ctx, ListOptions{Sort: []SortKey{{Field: "fid; DROP TABLE trails"}}})
+    assert.Error(t, err, "unknown sort fields must be rejected")
+
+    _, err = s.List(ctx, ListOptions{Filters: []Filter{{Field: "fee", Op: "like", Value: true}}})
+    assert.Error(t, err, "unknown operators must be rejected")
+    _, err = s.List(ctx, ListOptions{Filters: []Filter{{Any: []Filter{{Field: "nope", Value: true}}}}})
+    assert.Error(t, err, "unknown fields inside OR groups must be rejected")
+
+    // Search matches word prefixes and misspellings, and applies filters
+    results, err := s.Search(ctx, "Flagstaf", ListOptions{})
+    require.NoError(t, err)
+    require.Len(t, results, 1)
+    assert.Equal(t, 2, results[0].FID)
+    assert.Equal(t, map[string]string{"name": "<mark>flagstaff</mark>"}, results[0].Highlights)
+
+    results, err = s.Search(ctx, "chautauqa", ListOptions{})
+    require.NoError(t, err)
+    require.Len(t, results, 1)
+    assert.Equal(t, 1, results[0].FID)
+
+    results, err = s.Search(ctx, "mesa", ListOptions{Filters: []Filter{{Field: "restrooms", Value: false}}})
+    require.NoError(t, err)
+    assert.Empty(t, results)
+
+    // Get returns a single trail or ErrNotFound
+    trail, err := s.Get(ctx, 3)
+    require.NoError(t, err)
+    assert.Equal(t, "mesa", trail.Name)
+    require.NotNil(t, trail.DogTube)
+    assert.Equal(t, 2, *trail.DogTube)
+
+    _, err = s.Get(ctx, 99)
+    assert.ErrorIs(t, err, ErrNotFound)
+
+    // Upsert updates existing trails and inserts new ones
+    trail.Name = "mesa trail"
+    require.NoError(t, s.Upsert(ctx, trail))
+    require.NoError(t, s.Upsert(ctx, models.Trail{FID: 4, Name: "walker ranch", HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo}))
+
+    trail, err = s.Get(ctx, 3)
+    require.NoError(t, err)
+    assert.Equal(t, "mesa trail", trail.Name)
+
+    // Insert, Update and Delete report conflicts and missing trails
+    assert.ErrorIs(t, s.Insert(ctx, models.Trail{FID: 4, Name: "duplicate", HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo}), ErrConflict)
+    require.NoError(t, s.Insert(ctx, models.Trail{FID: 5, Name: "heil valley", HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo}))
+    assert.ErrorIs(t, s.Update(ctx, models.Trail{FID: 98, Name: "missing", HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo}), ErrNotFound)
+    require.NoError(t, s.Update(ctx, models.Trail{FID: 5, Name: "heil valley ranch", HorseTrail: models.HorseTrailPossible, ADAParking: models.ADAParkingNo}))
+
+    trail, err = s.Get(ctx, 5)
+    require.NoError(t, err)
+    assert.Equal(t, "heil valley ranch", trail.Name)
+    assert.Equal(t, models.HorseTrailPossible, trail.HorseTrail)
+
+    // Modify applies concurrent changes to a trail one after the other, and an error from fn changes nothing
+    var wg sync.WaitGroup
+    for i := 0; i < 10; i++ {
+        wg.Add(1)
+        go func() {
+            defer wg.Done()
+            _, err := s.Modify(ctx, 5, func(trail models.Trail) (models.Trail, error) {
+                spaces := 1
+                if trail.ParkSpaces != nil {
+                    spaces = *trail.ParkSpaces + 1
+                }
+                trail.ParkSpaces = &spaces
+                return trail, nil
+            })
+            assert.NoError(t, err)
+        }()
+    }
+    wg.Wait()
+    modifyErr := errors.New("rejected")
+    _, err = s.Modify(ctx, 5, func(trail models.Trail) (models.Trail, error) {
+        trail.Name = "changed"
+        return trail, modifyErr
+    })
+    assert.Equal(t, modifyErr, err)
+    _, err = s.Modify(ctx, 98, func(trail models.Trail) (models.Trail, error) { return trail, nil })
+    assert.ErrorIs(t, err, ErrNotFound)
+
+    trail, err = s.Get(ctx, 5)
+    require.NoError(t, err)
+    assert.Equal(t, "heil valley ranch", trail.Name)
+    require.NotNil(t, trail.ParkSpaces)
+    assert.Equal(t, 10, *trail.ParkSpaces)
+
+    require.NoError(t, s.Delete(ctx, 5))
+    assert.ErrorIs(t, s.Delete(ctx, 5), ErrNotFound)
+    _, err = s.Get(ctx, 5)
+    assert.ErrorIs(t, err, ErrNotFound)
+
+    // Count applies filters
+    count, err := s.Count(ctx, nil)
+    require.NoError(t, err)
+    assert.Equal(t, 4, count)
+
+    count, err = s.Count(ctx, []Filter{{Field: "horse_trail", Value: models.HorseTrailDesignated}})
+    require.NoError(t, err)
+    assert.Equal(t, 2, count)
+
+    // Facets count distinct values among the matching trails
+    facets, err := s.Facets(ctx, []string{"horse_trail", "dog_tube"}, []Filter{{Field: "restrooms", Value: true}})
+    require.NoError(t, err)
+    assert.Equal(t, []FacetCount{
+        {Value: models.HorseTrailDesignated, Count: 1},
+        {Value: models.HorseTrailNA, Count: 1},
+    }, facets["horse_trail"])
+    assert.Equal(t, []FacetCount{{Value: 2, Count: 1}, {Value: nil, Count: 1}}, facets["dog_tube"])
+
+    // ReplaceAll discards the previous set
+    require.NoError(t, s.ReplaceAll(ctx, testTrails()[:1]))
+    count, err = s.Count(ctx, nil)
+    require.NoError(t, err)
+    assert.Equal(t, 1, count)
+
+    // A source that fails part way leaves the stored trails unchanged
+    readErr := errors.New("read failed")
+    _, _, err = s.ReplaceAllFrom(ctx, &failingSource{TrailSource: SliceSource(testTrails()), err: readErr}, nil)
+    assert.Equal(t, readErr, err)
+    count, err = s.Count(ctx, nil)
+    require.NoError(t, err)
+    assert.Equal(t, 1, count)
+
+    testGeoStore(t, s)
+    testMergeStore(t, s)
+    testImportStore(t, s)
+    testVersionStore(t, s)
+}
+
+// failingSource yields the trails of its TrailSource, then fails with err
+type failingSource struct {
+    TrailSource
+    err error
+}
+
+func (f *failingSource) Err() error {
+    return f.err
+}
+
+func TestMemoryStore(t *testing.T) {
+    testTrailStore(t, NewMemoryStore())
+}
+
+func TestMemoryStoreReturnsCopies(t *testing.T) {
+    ctx := context.Background()
+    s := NewMemoryStore()
+    require.NoError(t, s.ReplaceAll(ctx, testTrails()))
+
+    trail, err := s.Get(ctx, 3)
+    require.NoError(t, err)
+    *trail.DogTube = 10
+
+    trail, err = s.Get(ctx, 3)
+    require.NoError(t, err)
+    assert.Equal(t, 2, *trail.DogTube, "modifying a returned trail must not change the store")
+}
+
+func TestMemoryStoreRejectsDuplicateFIDs(t *testing.T) {
+    s := NewMemoryStore()
+    trails := testTrails()
+    trails = append(trails, trails[0])
+
+    assert.Error(t, s.ReplaceAll(context.Background(), trails))
+}
@@ -0,0 +1,42 @@
+package store
+
+import (
+    "trail-finder/models"
+)
+
+// mergePlan is what Merge writes to bring the stored trails in line with an incoming set
+type mergePlan struct {
+    upserts []models.Trail // New trails and trails with changed columns
+    deletes []int          // FIDs of stored trails to soft-delete
+    counts  models.MergeCounts
+}
+
+// planMerge compares the stored trails with the incoming set. Stored trails missing from the set are only
+// planned for deletion with softDelete.
+func planMerge(stored, incoming []models.Trail, softDelete bool) mergePlan {
+    diff := models.DiffTrails(stored, incoming)
+    plan := mergePlan{counts: models.MergeCounts{
+        Inserted: len(diff.Added), Updated: len(diff.Changed), Unchanged: diff.Unchanged,
+    }}
+
+    write := make(map[int]bool, len(diff.Added)+len(diff.Changed))
+    for _, t := range diff.Added {
+        write[t.FID] = true
+    }
+    for _, t := range diff.Changed {
+        write[t.FID] = true
+    }
+    for _, trail := range incoming {
+        if write[trail.FID] {
+            plan.upserts = append(plan.upserts, trail)
+        }
+    }
+
+    if softDelete {
+        for _, t := range diff.Removed {
+            plan.deletes = append(plan.deletes, t.FID)
+        }
+        plan.counts.Deleted = len(plan.deletes)
+    }
+    return plan
+}
@@ -0,0 +1,60 @@
+package store
+
+import (
+    "context"
+    "testing"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// testMergeStore runs the upsert and soft-delete behaviour every TrailStore implementation must share
+func testMergeStore(t *testing.T, s TrailStore) {
+    ctx := context.Background()
+    require.NoError(t, s.ReplaceAll(ctx, testTrails()))
+
+    // New FIDs are inserted, changed trails updated and missing trails kept
+    incoming := testTrails()[:2]
+    incoming[0].Name = "mesa trail"
+    incoming = append(incoming, models.Trail{FID: 4, Name: "sawhill", HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo})
+    counts, _, err := s.Merge(ctx, incoming, false, nil)
+    require.NoError(t, err)
+    assert.Equal(t, models.MergeCounts{Inserted: 1, Updated: 1, Unchanged: 1}, counts)
+
+    trail, err := s.Get(ctx, 3)
+    require.NoError(t, err)
+    assert.Equal(t, "mesa trail", trail.Name)
+    count, err := s.Count(ctx, nil)
+    require.NoError(t, err)
+    assert.Equal(t, 4, count)
+
+    // With soft deletes, missing trails disappear from every query
+    counts, _, err = s.Merge(ctx, incoming, true, nil)
+    require.NoError(t, err)
+    assert.Equal(t, models.MergeCounts{Unchanged: 3, Deleted: 1}, counts)
+
+    _, err = s.Get(ctx, 2)
+    assert.ErrorIs(t, err, ErrNotFound)
+    trails, err := s.List(ctx, ListOptions{})
+    require.NoError(t, err)
+    assert.Len(t, trails, 3)
+    count, err = s.Count(ctx, []Filter{{Field: "horse_trail", Value: models.HorseTrailDesignated}})
+    require.NoError(t, err)
+    assert.Equal(t, 1, count)
+    facets, err := s.Facets(ctx, []string{"restrooms"}, nil)
+    require.NoError(t, err)
+    assert.Equal(t, []FacetCount{{Value: false, Count: 1}, {Value: true, Count: 2}}, facets["restrooms"])
+    results, err := s.Search(ctx, "flagstaff", ListOptions{})
+    require.NoError(t, err)
+    assert.Empty(t, results)
+    assert.ErrorIs(t, s.Update(ctx, testTrails()[2]), ErrNotFound)
+    assert.ErrorIs(t, s.Delete(ctx, 2), ErrNotFound)
+
+    // A soft-deleted FID can be inserted again
+    require.NoError(t, s.Insert(ctx, models.Trail{FID: 2, Name: "flagstaff summit", HorseTrail: models.HorseTrailNA, ADAParking: models.ADAParkingNo}))
+    trail, err = s.Get(ctx, 2)
+    require.NoError(t, err)
+    assert.Equal(t, "flagstaff summit", trail.Name)
+    assert.ErrorIs(t, s.Insert(ctx, trail), ErrConflict)
+}
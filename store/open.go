@@ -0,0 +1,35 @@
+package store
+
+import (
+    "context"
+    "trail-finder/db"
+)
+
+// Open connects to the database named by the connection string, applies pending migrations and returns
+// the matching store with a function that closes it. The backend is chosen by the scheme:
+// postgres:// (or postgresql://) for PostgreSQL and sqlite:// for SQLite.
+func Open(connString string) (TrailStore, func(), error) {
+    driver, err := db.DriverFor(connString)
+    if err != nil {
+        return nil, nil, err
+    }
+
+    switch driver {
+    case db.DriverSQLite:
+        sqlDB, err := db.OpenSQLite(connString)
+        if err != nil {
+            return nil, nil, err
+        }
+        return NewSQLiteStore(sqlDB), func() { sqlDB.Close() }, nil
+    default:
+        if err := db.InitDB(connString); err != nil {
+            return nil, nil, err
+        }
+        s := NewPostgresStore(db.Pool)
+        if _, err := s.DetectGeography(context.Background()); err != nil {
+            db.CloseDB()
+            return nil, nil, err
+        }
+        return s, db.CloseDB, nil
+    }
+}
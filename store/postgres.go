@@ -0,0 +1,814 @@
+package store
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "strings"
+    "time"
+    "trail-finder/db"
+    "trail-finder/models"
+
+    "github.com/jackc/pgx/v4"
+    "github.com/jackc/pgx/v4/pgxpool"
+)
+
+// PostgresStore is a TrailStore backed by the trails table in PostgreSQL
+type PostgresStore struct {
+    pool    *db.Store
+    dialect dialect // Dialect of the trail queries, measuring distances with PostGIS once DetectGeography finds it
+}
+
+// NewPostgresStore creates a store that runs queries on the given connection pool
+func NewPostgresStore(pool *db.Store) *PostgresStore {
+    return &PostgresStore{pool: pool, dialect: postgresDialect}
+}
+
+// DetectGeography checks whether the migrations added the PostGIS location column to the trails table, which
+// they only do when the extension is available. If so, near filters and distance sorts use it; otherwise
+// distances are computed from the latitude and longitude columns with the haversine formula.
+func (s *PostgresStore) DetectGeography(ctx context.Context) (bool, error) {
+    var found bool
+    err := s.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM information_schema.columns "+
+        "WHERE table_schema = current_schema() AND table_name = 'trails' AND column_name = 'location')").Scan(&found)
+    if err != nil {
+        return false, fmt.Errorf("failed to check for the location column: %w", err)
+    }
+    s.dialect = postgresDialect
+    if found {
+        s.dialect = postgisDialect
+    }
+    return found, nil
+}
+
+// List returns the matching trails in the requested order, with ties broken by FID
+func (s *PostgresStore) List(ctx context.Context, opts ListOptions) ([]models.Trail, error) {
+    trails := []models.Trail{}
+    err := s.Stream(ctx, opts, func(trail models.Trail) error {
+        trails = append(trails, trail)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return trails, nil
+}
+
+// Stream calls fn with each matching trail as it is read from the List query. The query holds a pooled
+// connection until every trail has been passed to fn.
+func (s *PostgresStore) Stream(ctx context.Context, opts ListOptions, fn func(models.Trail) error) error {
+    query, args, err := s.dialect.listTrailsQuery(opts)
+    if err != nil {
+        return err
+    }
+
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Release()
+
+    return streamPostgresTrails(ctx, conn, query, args, fn)
+}
+
+// streamPostgresTrails runs a query selecting TrailColumns on the connection and calls fn with each trail it returns
+func streamPostgresTrails(ctx context.Context, conn *pgxpool.Conn, query string, args []interface{}, fn func(models.Trail) error) error {
+    rows, err := conn.Query(ctx, query, args...)
+    if err != nil {
+        return fmt.Errorf("failed to query trails: %w", err)
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var trail models.Trail
+        if err := rows.Scan(trail.ScanFields()...); err != nil {
+            return fmt.Errorf("failed to scan trails: %w", err)
+        }
+        if err := fn(trail); err != nil {
+            return err
+        }
+    }
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("failed to read trails: %w", err)
+    }
+    return nil
+}
+
+// Get returns the trail with the given FID
+func (s *PostgresStore) Get(ctx context.Context, fid int) (models.Trail, error) {
+    query := postgresDialect.getTrailQuery()
+
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return models.Trail{}, err
+    }
+    defer conn.Release()
+
+    var trail models.Trail
+    err = conn.QueryRow(ctx, query, fid).Scan(trail.ScanFields()...)
+    if errors.Is(err, pgx.ErrNoRows) {
+        return models.Trail{}, ErrNotFound
+    }
+    if err != nil {
+        return models.Trail{}, fmt.Errorf("failed to get trail %d: %w", fid, err)
+    }
+    return trail, nil
+}
+
+// Insert adds a new trail, or returns ErrConflict if its FID is taken
+func (s *PostgresStore) Insert(ctx context.Context, trail models.Trail) error {
+    return s.execOne(ctx, postgresDialect.insertNewTrailQuery(), trail.Values(), ErrConflict, "insert trail", trail.FID)
+}
+
+// Update replaces every column of the trail with the same FID, or returns ErrNotFound
+func (s *PostgresStore) Update(ctx context.Context, trail models.Trail) error {
+    return s.execOne(ctx, postgresDialect.updateTrailQuery(), trail.Values(), ErrNotFound, "update trail", trail.FID)
+}
+
+// Modify replaces the trail with the given FID by the trail fn makes of it in a single transaction,
+// reading it with SELECT ... FOR UPDATE so concurrent changes wait for this one to commit
+func (s *PostgresStore) Modify(ctx context.Context, fid int, fn func(models.Trail) (models.Trail, error)) (models.Trail, error) {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return models.Trail{}, err
+    }
+    defer conn.Release()
+
+    tx, err := conn.Begin(ctx)
+    if err != nil {
+        return models.Trail{}, fmt.Errorf("could not start transaction: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    var existing models.Trail
+    err = tx.QueryRow(ctx, postgresDialect.getTrailQuery()+" FOR UPDATE", fid).Scan(existing.ScanFields()...)
+    if errors.Is(err, pgx.ErrNoRows) {
+        return models.Trail{}, ErrNotFound
+    }
+    if err != nil {
+        return models.Trail{}, fmt.Errorf("failed to get trail %d: %w", fid, err)
+    }
+
+    trail, err := fn(existing)
+    if err != nil {
+        return models.Trail{}, err
+    }
+    trail.FID = fid
+    if _, err := tx.Exec(ctx, postgresDialect.updateTrailQuery(), trail.Values()...); err != nil {
+        return models.Trail{}, fmt.Errorf("failed to update trail %d: %w", fid, err)
+    }
+    if err := tx.Commit(ctx); err != nil {
+        return models.Trail{}, fmt.Errorf("could not commit transaction: %w", err)
+    }
+    return trail, nil
+}
+
+// Delete removes the trail with the given FID, or returns ErrNotFound
+func (s *PostgresStore) Delete(ctx context.Context, fid int) error {
+    return s.execOne(ctx, postgresDialect.deleteTrailQuery(), []interface{}{fid}, ErrNotFound, "delete trail", fid)
+}
+
+// execOne runs a statement that changes a single trail, returning errNone when it changed no rows
+func (s *PostgresStore) execOne(ctx context.Context, query string, args []interface{}, errNone error, action string, fid int) error {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Release()
+
+    tag, err := conn.Exec(ctx, query, args...)
+    if err != nil {
+        return fmt.Errorf("failed to %s %d: %w", action, fid, err)
+    }
+    if tag.RowsAffected() == 0 {
+        return errNone
+    }
+    return nil
+}
+
+// Upsert inserts the trail, or updates every column of the existing trail with the same FID
+func (s *PostgresStore) Upsert(ctx context.Context, trail models.Trail) error {
+    query := postgresDialect.upsertTrailQuery()
+
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Release()
+
+    if _, err := conn.Exec(ctx, query, trail.Values()...); err != nil {
+        return fmt.Errorf("failed to upsert trail %d: %w", trail.FID, err)
+    }
+    return nil
+}
+
+// ReplaceAll deletes every trail and inserts the given set in a single transaction
+func (s *PostgresStore) ReplaceAll(ctx context.Context, trails []models.Trail) error {
+    _, _, err := s.ReplaceAllFrom(ctx, SliceSource(trails), nil)
+    return err
+}
+
+// ReplaceAllFrom streams the trails into a temporary staging table with the COPY protocol, then swaps them
+// in with one DELETE and one INSERT ... SELECT and snapshots them as the load's version, all in a single
+// transaction. Readers keep seeing the previous trails until the transaction commits, and memory use does
+// not grow with the number of trails. Like Merge, the swap locks the trails table against other writers, so
+// the snapshot holds exactly the loaded trails.
+func (s *PostgresStore) ReplaceAllFrom(ctx context.Context, src TrailSource, label VersionLabel) (int, models.DatasetVersion, error) {
+    // Hold one pooled connection for the whole load
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return 0, models.DatasetVersion{}, err
+    }
+    defer conn.Release()
+
+    // Start a transaction to ensure atomicity
+    tx, err := conn.Begin(ctx)
+    if err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("could not start transaction: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    if _, err := tx.Exec(ctx, "CREATE TEMPORARY TABLE trails_staging (LIKE trails INCLUDING DEFAULTS) ON COMMIT DROP"); err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("could not create staging table: %w", err)
+    }
+    copied, err := tx.CopyFrom(ctx, pgx.Identifier{"trails_staging"}, models.TrailColumns, &copySource{src: src})
+    if srcErr := src.Err(); srcErr != nil {
+        return 0, models.DatasetVersion{}, srcErr
+    }
+    if err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("failed to copy trails: %w", err)
+    }
+
+    // Swap the staged trails in
+    if _, err := tx.Exec(ctx, "LOCK TABLE trails IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("could not lock trails: %w", err)
+    }
+    if _, err := tx.Exec(ctx, "DELETE FROM trails"); err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("could not clear existing data: %w", err)
+    }
+    columns := strings.Join(models.TrailColumns, ", ")
+    if _, err := tx.Exec(ctx, fmt.Sprintf("INSERT INTO trails (%s) SELECT %s FROM trails_staging", columns, columns)); err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("failed to insert staged trails: %w", err)
+    }
+
+    version, err := postgresSnapshotLabel(ctx, tx, label)
+    if err != nil {
+        return 0, models.DatasetVersion{}, err
+    }
+    if err := tx.Commit(ctx); err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("could not commit transaction: %w", err)
+    }
+    return int(copied), version, nil
+}
+
+// copySource adapts a TrailSource to pgx.CopyFromSource, sending each trail's values in TrailColumns order
+type copySource struct {
+    src TrailSource
+}
+
+func (c *copySource) Next() bool {
+    return c.src.Next()
+}
+
+func (c *copySource) Values() ([]interface{}, error) {
+    trail := c.src.Trail()
+    return trail.Values(), nil
+}
+
+func (c *copySource) Err() error {
+    return c.src.Err()
+}
+
+// Merge upserts the new and changed trails, soft-deletes missing ones and records the version in a single
+// transaction. The trails table is locked against other writers while the stored trails are compared with
+// the set and snapshotted.
+func (s *PostgresStore) Merge(ctx context.Context, trails []models.Trail, softDelete bool, label VersionLabel) (models.MergeCounts, models.DatasetVersion, error) {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, err
+    }
+    defer conn.Release()
+
+    tx, err := conn.Begin(ctx)
+    if err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("could not start transaction: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    if _, err := tx.Exec(ctx, "LOCK TABLE trails IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("could not lock trails: %w", err)
+    }
+    query, args, err := postgresDialect.listTrailsQuery(ListOptions{})
+    if err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, err
+    }
+    rows, err := tx.Query(ctx, query, args...)
+    if err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("failed to query trails: %w", err)
+    }
+    stored := []models.Trail{}
+    for rows.Next() {
+        var trail models.Trail
+        if err := rows.Scan(trail.ScanFields()...); err != nil {
+            rows.Close()
+            return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("failed to scan trails: %w", err)
+        }
+        stored = append(stored, trail)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("failed to read trails: %w", err)
+    }
+
+    plan := planMerge(stored, trails, softDelete)
+    for _, trail := range plan.upserts {
+        if _, err := tx.Exec(ctx, postgresDialect.upsertTrailQuery(), trail.Values()...); err != nil {
+            return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("failed to upsert trail %d: %w", trail.FID, err)
+        }
+    }
+    deletedAt := time.Now().UTC()
+    for _, fid := range plan.deletes {
+        if _, err := tx.Exec(ctx, postgresDialect.softDeleteTrailQuery(), fid, deletedAt); err != nil {
+            return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("failed to delete trail %d: %w", fid, err)
+        }
+    }
+
+    version, err := postgresSnapshotLabel(ctx, tx, label)
+    if err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, err
+    }
+    if err := tx.Commit(ctx); err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("could not commit transaction: %w", err)
+    }
+    return plan.counts, version, nil
+}
+
+// Search ranks the matching trails with the search_vector full-text index, which matches word prefixes,
+// and pg_trgm word similarity, which tolerates misspellings
+func (s *PostgresStore) Search(ctx context.Context, text string, opts ListOptions) ([]SearchResult, error) {
+    terms := searchTerms(text)
+    if len(terms) == 0 {
+        return []SearchResult{}, nil
+    }
+    query, args, err := s.dialect.postgresSearchQuery(terms, opts)
+    if err != nil {
+        return nil, err
+    }
+
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Release()
+
+    rows, err := conn.Query(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to search trails: %w", err)
+    }
+    defer rows.Close()
+
+    results := []SearchResult{}
+    for rows.Next() {
+        var result SearchResult
+        if err := rows.Scan(append(result.ScanFields(), &result.Rank)...); err != nil {
+            return nil, fmt.Errorf("failed to scan trails: %w", err)
+        }
+        result.Highlights = highlightTrail(&result.Trail, terms)
+        results = append(results, result)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read trails: %w", err)
+    }
+    return results, nil
+}
+
+// SearchRanking returns RankingFullText. Ranks combine ts_rank with trigram similarity, so results are ordered
+// differently from the stores ranking searches in the application.
+func (s *PostgresStore) SearchRanking() string {
+    return RankingFullText
+}
+
+// postgresSearchQuery builds the ranked SELECT for Search. A trail matches when every term is a word prefix
+// in the search_vector, or when the whole text is similar enough to a word sequence of a searched field.
+func (d dialect) postgresSearchQuery(terms []string, opts ListOptions) (string, []interface{}, error) {
+    where, args, err := d.buildWhere(opts.Filters)
+    if err != nil {
+        return "", nil, err
+    }
+
+    args = append(args, prefixTSQuery(terms))
+    tsQuery := fmt.Sprintf("to_tsquery('simple', %s)", d.placeholder(len(args)))
+    args = append(args, strings.Join(terms, " "))
+    text := d.placeholder(len(args))
+    args = append(args, fuzzyThreshold)
+    threshold := d.placeholder(len(args))
+
+    match := fmt.Sprintf("(search_vector @@ %s OR word_similarity(%s, name) >= %s OR word_similarity(%s, aka) >= %s OR word_similarity(%s, address) >= %s)",
+        tsQuery, text, threshold, text, threshold, text, threshold)
+    where = appendCondition(where, match)
+    rank := fmt.Sprintf("(ts_rank(search_vector, %s) + GREATEST(word_similarity(%s, name), 0.4 * word_similarity(%s, aka), 0.2 * word_similarity(%s, address)))::float8",
+        tsQuery, text, text, text)
+
+    if err := validateSort(opts.Sort, opts.After); err != nil {
+        return "", nil, err
+    }
+
+    query := fmt.Sprintf("SELECT %s, %s AS rank FROM trails%s ORDER BY %s",
+        strings.Join(models.TrailColumns, ", "), rank, where, d.orderBy(opts.Sort, &args, "rank DESC"))
+    query, args = d.paginate(query, args, opts)
+    return query, args, nil
+}
+
+// Count returns the number of matching trails
+func (s *PostgresStore) Count(ctx context.Context, filters []Filter) (int, error) {
+    where, args, err := s.dialect.buildWhere(filters)
+    if err != nil {
+        return 0, err
+    }
+
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return 0, err
+    }
+    defer conn.Release()
+
+    var count int
+    if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM trails"+where, args...).Scan(&count); err != nil {
+        return 0, fmt.Errorf("failed to count trails: %w", err)
+    }
+    return count, nil
+}
+
+// Facets counts the distinct values of each field among the matching trails
+func (s *PostgresStore) Facets(ctx context.Context, fields []string, filters []Filter) (map[string][]FacetCount, error) {
+    if err := validateFields(fields...); err != nil {
+        return nil, err
+    }
+    where, args, err := s.dialect.buildWhere(filters)
+    if err != nil {
+        return nil, err
+    }
+
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Release()
+
+    facets := make(map[string][]FacetCount, len(fields))
+    for _, field := range fields {
+        rows, err := conn.Query(ctx, facetQuery(field, where), args...)
+        if err != nil {
+            return nil, fmt.Errorf("failed to count %s values: %w", field, err)
+        }
+
+        counts := []FacetCount{}
+        for rows.Next() {
+            var value interface{}
+            var count int
+            if err := rows.Scan(&value, &count); err != nil {
+                rows.Close()
+                return nil, fmt.Errorf("failed to scan %s values: %w", field, err)
+            }
+            counts = append(counts, FacetCount{Value: normalizeScanned(field, value), Count: count})
+        }
+        rows.Close()
+        if err := rows.Err(); err != nil {
+            return nil, fmt.Errorf("failed to read %s values: %w", field, err)
+        }
+        facets[field] = counts
+    }
+    return facets, nil
+}
+
+// CreateImport records a new import job
+func (s *PostgresStore) CreateImport(ctx context.Context, job models.ImportJob) error {
+    values, err := importValues(job)
+    if err != nil {
+        return err
+    }
+
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Release()
+
+    if _, err := conn.Exec(ctx, postgresDialect.insertImportQuery(), values...); err != nil {
+        return fmt.Errorf("failed to create import %s: %w", job.ID, err)
+    }
+    return nil
+}
+
+// UpdateImport saves the progress of an existing import job, or returns ErrNotFound
+func (s *PostgresStore) UpdateImport(ctx context.Context, job models.ImportJob) error {
+    values, err := importValues(job)
+    if err != nil {
+        return err
+    }
+
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Release()
+
+    tag, err := conn.Exec(ctx, postgresDialect.updateImportQuery(), values...)
+    if err != nil {
+        return fmt.Errorf("failed to update import %s: %w", job.ID, err)
+    }
+    if tag.RowsAffected() == 0 {
+        return ErrNotFound
+    }
+    return nil
+}
+
+// GetImport returns the import job with the given ID, or ErrNotFound
+func (s *PostgresStore) GetImport(ctx context.Context, id string) (models.ImportJob, error) {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return models.ImportJob{}, err
+    }
+    defer conn.Release()
+
+    var scan importScan
+    err = conn.QueryRow(ctx, postgresDialect.getImportQuery(), id).Scan(scan.importScanFields()...)
+    if errors.Is(err, pgx.ErrNoRows) {
+        return models.ImportJob{}, ErrNotFound
+    }
+    if err != nil {
+        return models.ImportJob{}, fmt.Errorf("failed to get import %s: %w", id, err)
+    }
+    return scan.importJob()
+}
+
+// ListImports returns up to limit import jobs, newest first
+func (s *PostgresStore) ListImports(ctx context.Context, limit int) ([]models.ImportJob, error) {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Release()
+
+    query, args := postgresDialect.listImportsQuery(limit)
+    rows, err := conn.Query(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query imports: %w", err)
+    }
+    defer rows.Close()
+
+    jobs := []models.ImportJob{}
+    for rows.Next() {
+        var scan importScan
+        if err := rows.Scan(scan.importScanFields()...); err != nil {
+            return nil, fmt.Errorf("failed to scan imports: %w", err)
+        }
+        job, err := scan.importJob()
+        if err != nil {
+            return nil, err
+        }
+        jobs = append(jobs, job)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read imports: %w", err)
+    }
+    return jobs, nil
+}
+
+// HeartbeatImports records at as the heartbeat of the queued and running jobs of owner
+func (s *PostgresStore) HeartbeatImports(ctx context.Context, owner string, at time.Time) error {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Release()
+
+    if _, err := conn.Exec(ctx, postgresDialect.heartbeatImportsQuery(), at, owner); err != nil {
+        return fmt.Errorf("failed to record import heartbeat: %w", err)
+    }
+    return nil
+}
+
+// FailStaleImports fails the unfinished jobs of other owners whose last heartbeat is before cutoff
+func (s *PostgresStore) FailStaleImports(ctx context.Context, owner string, cutoff time.Time, reason string) (int, error) {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return 0, err
+    }
+    defer conn.Release()
+
+    tag, err := conn.Exec(ctx, postgresDialect.failStaleImportsQuery(), reason, time.Now().UTC(), owner, cutoff)
+    if err != nil {
+        return 0, fmt.Errorf("failed to fail stale imports: %w", err)
+    }
+    return int(tag.RowsAffected()), nil
+}
+
+// Ping checks that the database is reachable
+func (s *PostgresStore) Ping(ctx context.Context) error {
+    return s.pool.Ping(ctx)
+}
+
+// Stats reports the connection pool usage
+func (s *PostgresStore) Stats() map[string]int32 {
+    return s.pool.Stats()
+}
+
+// CreateVersion snapshots the current trails as a new dataset version in a single transaction
+func (s *PostgresStore) CreateVersion(ctx context.Context, version models.DatasetVersion) (models.DatasetVersion, error) {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return models.DatasetVersion{}, err
+    }
+    defer conn.Release()
+
+    tx, err := conn.Begin(ctx)
+    if err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not start transaction: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    version, err = postgresSnapshot(ctx, tx, newVersion(version))
+    if err != nil {
+        return models.DatasetVersion{}, err
+    }
+    if err := tx.Commit(ctx); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not commit transaction: %w", err)
+    }
+    return version, nil
+}
+
+// postgresSnapshotLabel snapshots the trails as the version described by the label, if any
+func postgresSnapshotLabel(ctx context.Context, tx pgx.Tx, label VersionLabel) (models.DatasetVersion, error) {
+    if label == nil {
+        return models.DatasetVersion{}, nil
+    }
+    return postgresSnapshot(ctx, tx, newVersion(label()))
+}
+
+// postgresSnapshot records the version and copies the live trails into its snapshot
+func postgresSnapshot(ctx context.Context, tx pgx.Tx, version models.DatasetVersion) (models.DatasetVersion, error) {
+    err := tx.QueryRow(ctx, postgresDialect.insertVersionQuery(),
+        version.Source, version.SHA256, version.RollbackOf, version.CreatedAt).Scan(&version.ID)
+    if err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("failed to create version: %w", err)
+    }
+    tag, err := tx.Exec(ctx, postgresDialect.snapshotTrailsQuery(), version.ID)
+    if err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("failed to snapshot trails: %w", err)
+    }
+    version.TrailCount = int(tag.RowsAffected())
+    if _, err := tx.Exec(ctx, postgresDialect.setTrailCountQuery(), version.ID, version.TrailCount); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("failed to update version %d: %w", version.ID, err)
+    }
+    return version, nil
+}
+
+// GetVersion returns the dataset version with the given ID, or ErrNotFound
+func (s *PostgresStore) GetVersion(ctx context.Context, id int) (models.DatasetVersion, error) {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return models.DatasetVersion{}, err
+    }
+    defer conn.Release()
+
+    return scanPostgresVersion(conn.QueryRow(ctx, postgresDialect.getVersionQuery(), id), id)
+}
+
+// scanPostgresVersion reads the version selected by getVersionQuery, or returns ErrNotFound
+func scanPostgresVersion(row pgx.Row, id int) (models.DatasetVersion, error) {
+    var version models.DatasetVersion
+    err := row.Scan(versionScanFields(&version)...)
+    if errors.Is(err, pgx.ErrNoRows) {
+        return models.DatasetVersion{}, ErrNotFound
+    }
+    if err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("failed to get version %d: %w", id, err)
+    }
+    return version, nil
+}
+
+// ListVersions returns up to limit dataset versions, newest first
+func (s *PostgresStore) ListVersions(ctx context.Context, limit int) ([]models.DatasetVersion, error) {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return nil, err
+    }
+    defer conn.Release()
+
+    query, args := postgresDialect.listVersionsQuery(limit)
+    rows, err := conn.Query(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query versions: %w", err)
+    }
+    defer rows.Close()
+
+    versions := []models.DatasetVersion{}
+    for rows.Next() {
+        var version models.DatasetVersion
+        if err := rows.Scan(versionScanFields(&version)...); err != nil {
+            return nil, fmt.Errorf("failed to scan versions: %w", err)
+        }
+        versions = append(versions, version)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read versions: %w", err)
+    }
+    return versions, nil
+}
+
+// ListVersionTrails runs the List and Count queries against the snapshot of a dataset version.
+// Snapshots have no location column, so their distances always use the haversine formula.
+func (s *PostgresStore) ListVersionTrails(ctx context.Context, id int, opts ListOptions) ([]models.Trail, int, error) {
+    query, args, err := postgresDialect.listFromQuery(versionTrails(id), opts)
+    if err != nil {
+        return nil, 0, err
+    }
+    where, countArgs, err := postgresDialect.buildWhere(opts.Filters)
+    if err != nil {
+        return nil, 0, err
+    }
+
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return nil, 0, err
+    }
+    defer conn.Release()
+
+    if _, err := scanPostgresVersion(conn.QueryRow(ctx, postgresDialect.getVersionQuery(), id), id); err != nil {
+        return nil, 0, err
+    }
+
+    trails := []models.Trail{}
+    err = streamPostgresTrails(ctx, conn, query, args, func(trail models.Trail) error {
+        trails = append(trails, trail)
+        return nil
+    })
+    if err != nil {
+        return nil, 0, err
+    }
+
+    var total int
+    if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM "+versionTrails(id)+where, countArgs...).Scan(&total); err != nil {
+        return nil, 0, fmt.Errorf("failed to count trails of version %d: %w", id, err)
+    }
+    return trails, total, nil
+}
+
+// StreamVersionTrails runs the List query against the snapshot of a dataset version, calling fn with each trail
+func (s *PostgresStore) StreamVersionTrails(ctx context.Context, id int, opts ListOptions, fn func(models.Trail) error) error {
+    query, args, err := postgresDialect.listFromQuery(versionTrails(id), opts)
+    if err != nil {
+        return err
+    }
+
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return err
+    }
+    defer conn.Release()
+
+    if _, err := scanPostgresVersion(conn.QueryRow(ctx, postgresDialect.getVersionQuery(), id), id); err != nil {
+        return err
+    }
+    return streamPostgresTrails(ctx, conn, query, args, fn)
+}
+
+// Rollback replaces the trails with the snapshot of a version and records the result, in a single transaction.
+// Like Merge, it locks the trails table against other writers.
+func (s *PostgresStore) Rollback(ctx context.Context, id int) (models.DatasetVersion, error) {
+    conn, err := s.pool.Acquire(ctx)
+    if err != nil {
+        return models.DatasetVersion{}, err
+    }
+    defer conn.Release()
+
+    tx, err := conn.Begin(ctx)
+    if err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not start transaction: %w", err)
+    }
+    defer tx.Rollback(ctx)
+
+    if _, err := tx.Exec(ctx, "LOCK TABLE trails IN SHARE ROW EXCLUSIVE MODE"); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not lock trails: %w", err)
+    }
+    restored, err := scanPostgresVersion(tx.QueryRow(ctx, postgresDialect.getVersionQuery(), id), id)
+    if err != nil {
+        return models.DatasetVersion{}, err
+    }
+    if _, err := tx.Exec(ctx, "DELETE FROM trails"); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not clear existing data: %w", err)
+    }
+    if _, err := tx.Exec(ctx, postgresDialect.restoreTrailsQuery(), id); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("failed to restore version %d: %w", id, err)
+    }
+    version, err := postgresSnapshot(ctx, tx, rollbackVersion(restored))
+    if err != nil {
+        return models.DatasetVersion{}, err
+    }
+
+    if err := tx.Commit(ctx); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not commit transaction: %w", err)
+    }
+    return version, nil
+}
@@ -0,0 +1,199 @@
+package store
+
+import (
+    "regexp"
+    "sort"
+    "strings"
+    "trail-finder/models"
+    "unicode/utf8"
+)
+
+// SearchResult is a trail matched by a text search, with its relevance and the matching fields highlighted
+type SearchResult struct {
+    models.Trail
+    Rank       float64           `json:"rank"`       // Higher ranks match better; only comparable within one search
+    Highlights map[string]string `json:"highlights"` // Searched fields that matched, with matching words wrapped in <mark> tags
+}
+
+// Highlight markers wrapped around matching words
+const (
+    HighlightStart = "<mark>"
+    HighlightEnd   = "</mark>"
+)
+
+// Search rankings, as returned by SearchRanking. Stores with the same ranking order the same trails the same way;
+// the ranks and result order of stores with different rankings differ.
+const (
+    RankingFullText = "fulltext" // ts_rank of the PostgreSQL search_vector plus pg_trgm word similarity
+    RankingWords    = "words"    // Word matching in the application, by searchTrails
+)
+
+// fuzzyThreshold is the trigram similarity above which a misspelled word still matches
+const fuzzyThreshold = 0.5
+
+// searchFields are the searched columns, weighted like the A, B and C weights of the PostgreSQL search_vector
+var searchFields = []struct {
+    name   string
+    weight float64
+}{
+    {"name", 1.0},
+    {"aka", 0.4},
+    {"address", 0.2},
+}
+
+// wordPattern splits text into the words that are searched and highlighted
+var wordPattern = regexp.MustCompile(`[\p{L}\p{N}]+`)
+
+// searchTerms splits a search query into lowercased words
+func searchTerms(query string) []string {
+    return wordPattern.FindAllString(strings.ToLower(query), -1)
+}
+
+// prefixTSQuery builds a to_tsquery expression requiring every term as a word prefix.
+// Terms only contain letters and digits, so they cannot inject tsquery operators.
+func prefixTSQuery(terms []string) string {
+    parts := make([]string, len(terms))
+    for i, term := range terms {
+        parts[i] = term + ":*"
+    }
+    return strings.Join(parts, " & ")
+}
+
+// searchTrails ranks trails against the search terms and returns the requested page of matches, ordered by
+// the sort keys, then best first, then by FID. It is the search used by the stores without full-text indexes,
+// and matches words the same way: exactly, by prefix, or by trigram similarity for misspellings.
+func searchTrails(trails []models.Trail, terms []string, opts ListOptions) []SearchResult {
+    results := []SearchResult{}
+    for _, trail := range trails {
+        if rank, ok := rankTrail(&trail, terms); ok {
+            results = append(results, SearchResult{Trail: trail, Rank: rank, Highlights: highlightTrail(&trail, terms)})
+        }
+    }
+    sort.SliceStable(results, func(i, j int) bool {
+        if cmp := compareByKeys(&results[i].Trail, &results[j].Trail, opts.Sort); cmp != 0 {
+            return cmp < 0
+        }
+        if results[i].Rank != results[j].Rank {
+            return results[i].Rank > results[j].Rank
+        }
+        return results[i].FID < results[j].FID
+    })
+
+    if opts.Offset >= len(results) {
+        return []SearchResult{}
+    }
+    results = results[opts.Offset:]
+    if opts.Limit > 0 && opts.Limit < len(results) {
+        results = results[:opts.Limit]
+    }
+    return results
+}
+
+// rankTrail scores a trail against the search terms. Every term must match a word in one of the searched fields.
+func rankTrail(trail *models.Trail, terms []string) (float64, bool) {
+    if len(terms) == 0 {
+        return 0, false
+    }
+
+    rank := 0.0
+    matched := make([]bool, len(terms))
+    for _, field := range searchFields {
+        value, _ := trail.FieldValue(field.name)
+        words := searchTerms(value.(string))
+        for i, term := range terms {
+            best := 0.0
+            for _, word := range words {
+                if score := wordScore(term, word); score > best {
+                    best = score
+                }
+            }
+            if best > 0 {
+                matched[i] = true
+                rank += field.weight * best / float64(len(terms))
+            }
+        }
+    }
+
+    for _, ok := range matched {
+        if !ok {
+            return 0, false
+        }
+    }
+    return rank, true
+}
+
+// highlightTrail wraps the words of each searched field that match a term, leaving out fields without matches
+func highlightTrail(trail *models.Trail, terms []string) map[string]string {
+    highlights := map[string]string{}
+    for _, field := range searchFields {
+        value, _ := trail.FieldValue(field.name)
+        if text, ok := highlight(value.(string), terms); ok {
+            highlights[field.name] = text
+        }
+    }
+    return highlights
+}
+
+// highlight wraps the words of text that match any term, reporting whether any did
+func highlight(text string, terms []string) (string, bool) {
+    var b strings.Builder
+    last, found := 0, false
+    for _, loc := range wordPattern.FindAllStringIndex(text, -1) {
+        word := strings.ToLower(text[loc[0]:loc[1]])
+        for _, term := range terms {
+            if wordScore(term, word) > 0 {
+                b.WriteString(text[last:loc[0]])
+                b.WriteString(HighlightStart + text[loc[0]:loc[1]] + HighlightEnd)
+                last, found = loc[1], true
+                break
+            }
+        }
+    }
+    b.WriteString(text[last:])
+    return b.String(), found
+}
+
+// wordScore scores how well a word matches a search term: 1 for the same word, slightly less for a prefix,
+// the trigram similarity for a close misspelling, and 0 otherwise
+func wordScore(term, word string) float64 {
+    switch {
+    case term == word:
+        return 1
+    case strings.HasPrefix(word, term):
+        return 0.9
+    }
+    if similarity := trigramSimilarity(term, word); similarity >= fuzzyThreshold {
+        return similarity
+    }
+    return 0
+}
+
+// trigramSimilarity compares two words the way pg_trgm's similarity does: the share of their padded
+// three-character sequences they have in common
+func trigramSimilarity(a, b string) float64 {
+    ta, tb := trigrams(a), trigrams(b)
+    if len(ta) == 0 || len(tb) == 0 {
+        return 0
+    }
+
+    shared := 0
+    for t := range ta {
+        if tb[t] {
+            shared++
+        }
+    }
+    return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+// trigrams returns the set of three-character sequences of a word padded with two leading spaces and one trailing space
+func trigrams(word string) map[string]bool {
+    if utf8.RuneCountInString(word) == 0 {
+        return nil
+    }
+    runes := []rune("  " + word + " ")
+    set := make(map[string]bool, len(runes)-2)
+    for i := 0; i+3 <= len(runes); i++ {
+        set[string(runes[i:i+3])] = true
+    }
+    return set
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+    "testing"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestSearchTrailsRanksNamesAboveOtherFields(t *testing.T) {
+    trails := []models.Trail{
+        {FID: 1, Name: "betasso preserve", Address: "flagstaff road"},
+        {FID: 2, Name: "flagstaff summit west", AKA: "summit"},
+        {FID: 3, Name: "walker ranch", AKA: "flagstaff trailhead"},
+        {FID: 4, Name: "mesa trail"},
+    }
+
+    results := searchTrails(trails, searchTerms("Flagstaff"), ListOptions{})
+    require.Len(t, results, 3)
+    assert.Equal(t, []int{2, 3, 1}, []int{results[0].FID, results[1].FID, results[2].FID})
+    assert.Equal(t, map[string]string{"aka": "<mark>flagstaff</mark> trailhead"}, results[1].Highlights)
+    assert.Equal(t, map[string]string{"address": "<mark>flagstaff</mark> road"}, results[2].Highlights)
+
+    // Every term must match, and pages are taken after ranking
+    results = searchTrails(trails, searchTerms("flagstaff summit"), ListOptions{})
+    require.Len(t, results, 1)
+    assert.Equal(t, "<mark>flagstaff</mark> <mark>summit</mark> west", results[0].Highlights["name"])
+
+    results = searchTrails(trails, searchTerms("flagstaff"), ListOptions{Limit: 1, Offset: 1})
+    require.Len(t, results, 1)
+    assert.Equal(t, 3, results[0].FID)
+
+    // Sort keys take precedence over the rank
+    results = searchTrails(trails, searchTerms("flagstaff"), ListOptions{Sort: []SortKey{{Field: "name"}}})
+    require.Len(t, results, 3)
+    assert.Equal(t, []int{1, 2, 3}, []int{results[0].FID, results[1].FID, results[2].FID})
+}
+
+func TestSearchTrailsIgnoresPunctuationOnlyQueries(t *testing.T) {
+    assert.Empty(t, searchTrails([]models.Trail{{FID: 1, Name: "mesa"}}, searchTerms(" -- "), ListOptions{}))
+}
+
+func TestTrigramSimilarity(t *testing.T) {
+    assert.Equal(t, 1.0, trigramSimilarity("mesa", "mesa"))
+    assert.GreaterOrEqual(t, trigramSimilarity("chautauqa", "chautauqua"), fuzzyThreshold)
+    assert.Less(t, trigramSimilarity("mesa", "walker"), fuzzyThreshold)
+}
+
+func TestPrefixTSQuery(t *testing.T) {
+    assert.Equal(t, "flag:* & summit:*", prefixTSQuery(searchTerms("Flag-summit")))
+}
@@ -0,0 +1,41 @@
+package store
+
+import (
+    "trail-finder/models"
+)
+
+// TrailSource streams trails into ReplaceAllFrom without holding the whole set in memory.
+// Next advances to the next trail, returning false once the trails are exhausted or reading them failed;
+// Err then reports the failure.
+type TrailSource interface {
+    Next() bool
+    Trail() models.Trail
+    Err() error
+}
+
+// sliceSource is a TrailSource over trails already in memory
+type sliceSource struct {
+    trails []models.Trail
+    next   int
+}
+
+// SliceSource returns a TrailSource that yields the given trails in order
+func SliceSource(trails []models.Trail) TrailSource {
+    return &sliceSource{trails: trails}
+}
+
+func (s *sliceSource) Next() bool {
+    if s.next >= len(s.trails) {
+        return false
+    }
+    s.next++
+    return true
+}
+
+func (s *sliceSource) Trail() models.Trail {
+    return s.trails[s.next-1]
+}
+
+func (s *sliceSource) Err() error {
+    return nil
+}
@@ -0,0 +1,301 @@
+package store
+
+import (
+    "fmt"
+    "strings"
+    "trail-finder/models"
+)
+
+// dialect holds the SQL differences between the database backends
+type dialect struct {
+    placeholder func(n int) string // Bind parameter for the nth query argument, starting at 1
+    noLimit     string             // LIMIT expression that returns every row, for an OFFSET without a limit
+    textCollate string             // Collation that sorts text by byte value, like the in-memory store
+    least       string             // Function returning the smallest of its arguments
+    geography   bool               // Whether distances are measured on the PostGIS location column
+}
+
+// postgresDialect numbers parameters as $1, $2, ...
+var postgresDialect = dialect{
+    placeholder: func(n int) string { return fmt.Sprintf("$%d", n) },
+    noLimit:     "ALL",
+    textCollate: ` COLLATE "C"`,
+    least:       "LEAST",
+}
+
+// postgisDialect is the PostgreSQL dialect for databases where the location column was added with PostGIS
+var postgisDialect = func() dialect {
+    d := postgresDialect
+    d.geography = true
+    return d
+}()
+
+// sqliteDialect numbers parameters as ?1, ?2, ...; SQLite only accepts OFFSET after a LIMIT
+var sqliteDialect = dialect{
+    placeholder: func(n int) string { return fmt.Sprintf("?%d", n) },
+    noLimit:     "-1",
+    textCollate: "",
+    least:       "MIN",
+}
+
+// listTrailsQuery builds the SELECT for List, ordered by FID so pages are stable
+func (d dialect) listTrailsQuery(opts ListOptions) (string, []interface{}, error) {
+    return d.listFromQuery("trails", opts)
+}
+
+// listFromQuery builds the SELECT for List from a table expression with the columns of the trails table
+func (d dialect) listFromQuery(from string, opts ListOptions) (string, []interface{}, error) {
+    where, args, err := d.buildWhere(opts.Filters)
+    if err != nil {
+        return "", nil, err
+    }
+
+    if err := validateSort(opts.Sort, opts.After); err != nil {
+        return "", nil, err
+    }
+    if opts.After != nil {
+        where = appendCondition(where, d.keysetCondition(opts.After, &args))
+    }
+
+    query := fmt.Sprintf("SELECT %s FROM %s%s ORDER BY %s", strings.Join(models.TrailColumns, ", "), from, where, d.orderBy(opts.Sort, &args))
+    query, args = d.paginate(query, args, opts)
+    return query, args, nil
+}
+
+// keysetCondition compiles "comes after the cursor" for the order produced by orderBy: the first sort key that
+// differs from the cursor's value decides, then the FID. Unset values sort last, so only unset values follow them.
+func (d dialect) keysetCondition(c *Cursor, args *[]interface{}) string {
+    bind := func(value interface{}) string {
+        *args = append(*args, sqlValue(value))
+        return d.placeholder(len(*args))
+    }
+
+    var alternatives, equal []string
+    for i, key := range c.Sort {
+        if c.Values[i] == nil {
+            equal = append(equal, key.Field+" IS NULL")
+            continue
+        }
+
+        op := ">"
+        if key.Desc {
+            op = "<"
+        }
+        after := fmt.Sprintf("(%s %s %s OR %s IS NULL)", d.sortColumn(key.Field), op, bind(c.Values[i]), key.Field)
+        alternatives = append(alternatives, strings.Join(append(equal[:len(equal):len(equal)], after), " AND "))
+        equal = append(equal, fmt.Sprintf("%s = %s", key.Field, bind(c.Values[i])))
+    }
+    alternatives = append(alternatives, strings.Join(append(equal, "fid > "+bind(c.FID)), " AND "))
+    return "(" + strings.Join(alternatives, " OR ") + ")"
+}
+
+// appendCondition adds a condition to a WHERE clause built by buildWhere, which may be empty
+func appendCondition(where, condition string) string {
+    if where == "" {
+        return " WHERE " + condition
+    }
+    return where + " AND " + condition
+}
+
+// sortColumn returns the column expression used to order a field, comparing text by byte value
+func (d dialect) sortColumn(field string) string {
+    switch models.FieldKinds[field] {
+    case models.KindText, models.KindHorseTrail, models.KindADAParking:
+        return field + d.textCollate
+    }
+    return field
+}
+
+// orderBy compiles validated sort keys into an ORDER BY list ending with the FID tiebreak, appending the
+// points of distance keys to args. Extra terms, such as a search rank, are placed between the keys and the tiebreak.
+func (d dialect) orderBy(keys []SortKey, args *[]interface{}, extra ...string) string {
+    bind := func(value interface{}) string {
+        *args = append(*args, value)
+        return d.placeholder(len(*args))
+    }
+
+    terms := make([]string, 0, len(keys)+len(extra)+1)
+    for _, key := range keys {
+        column := d.sortColumn(key.Field)
+        if key.Field == DistanceField {
+            column = d.distance(bind(key.From.Latitude), bind(key.From.Longitude))
+        }
+        direction := "ASC"
+        if key.Desc {
+            direction = "DESC"
+        }
+        terms = append(terms, fmt.Sprintf("%s %s NULLS LAST", column, direction))
+    }
+    terms = append(terms, extra...)
+    return strings.Join(append(terms, "fid"), ", ")
+}
+
+// paginate appends the LIMIT and OFFSET of the options to a query
+func (d dialect) paginate(query string, args []interface{}, opts ListOptions) (string, []interface{}) {
+    if opts.Limit > 0 {
+        args = append(args, opts.Limit)
+        query += " LIMIT " + d.placeholder(len(args))
+    } else if opts.Offset > 0 {
+        query += " LIMIT " + d.noLimit
+    }
+    if opts.Offset > 0 {
+        args = append(args, opts.Offset)
+        query += " OFFSET " + d.placeholder(len(args))
+    }
+    return query, args
+}
+
+// liveCondition excludes soft-deleted trails, which no query returns
+const liveCondition = "deleted_at IS NULL"
+
+// buildWhere compiles the filters into a parameterized WHERE clause that also excludes soft-deleted trails.
+// Column names are checked against the known columns and operators come from a fixed set,
+// so only values are taken from the caller, and those are always passed as bind parameters.
+func (d dialect) buildWhere(filters []Filter) (string, []interface{}, error) {
+    if err := validateFilters(filters); err != nil {
+        return "", nil, err
+    }
+    if len(filters) == 0 {
+        return " WHERE " + liveCondition, nil, nil
+    }
+
+    var args []interface{}
+    condition := d.joinConditions(filters, " AND ", &args)
+    return " WHERE " + liveCondition + " AND " + condition, args, nil
+}
+
+// joinConditions compiles each filter and joins the conditions with the given operator
+func (d dialect) joinConditions(filters []Filter, join string, args *[]interface{}) string {
+    conditions := make([]string, len(filters))
+    for i, f := range filters {
+        conditions[i] = d.condition(f, args)
+    }
+    return strings.Join(conditions, join)
+}
+
+// condition compiles a single validated filter, appending its values to args
+func (d dialect) condition(f Filter, args *[]interface{}) string {
+    bind := func(value interface{}) string {
+        *args = append(*args, sqlValue(value))
+        return d.placeholder(len(*args))
+    }
+
+    if f.Any != nil {
+        return "(" + d.joinConditions(f.Any, " OR ", args) + ")"
+    }
+    if f.Near != nil {
+        return d.within(bind(f.Near.Latitude), bind(f.Near.Longitude), bind(f.Near.RadiusKm))
+    }
+
+    switch f.op() {
+    case OpIn, OpNotIn:
+        placeholders := make([]string, len(f.Values))
+        for i, value := range f.Values {
+            placeholders[i] = bind(value)
+        }
+        if f.op() == OpNotIn {
+            return fmt.Sprintf("(%s NOT IN (%s) OR %s IS NULL)", f.Field, strings.Join(placeholders, ", "), f.Field)
+        }
+        return fmt.Sprintf("%s IN (%s)", f.Field, strings.Join(placeholders, ", "))
+    case OpNe:
+        // Unset values differ from every value, as they do in the in-memory store
+        return fmt.Sprintf("(%s <> %s OR %s IS NULL)", f.Field, bind(f.Value), f.Field)
+    default:
+        return fmt.Sprintf("%s %s %s", f.Field, sqlOperators[f.op()], bind(f.Value))
+    }
+}
+
+// insertTrailQuery builds the INSERT statement for a full trail record
+func (d dialect) insertTrailQuery() string {
+    placeholders := make([]string, len(models.TrailColumns))
+    for i := range placeholders {
+        placeholders[i] = d.placeholder(i + 1)
+    }
+    return fmt.Sprintf("INSERT INTO trails (%s) VALUES (%s)",
+        strings.Join(models.TrailColumns, ", "), strings.Join(placeholders, ", "))
+}
+
+// upsertTrailQuery builds an INSERT that updates every column of an existing trail with the same FID,
+// reviving it if it was soft-deleted
+func (d dialect) upsertTrailQuery() string {
+    return d.insertTrailQuery() + " ON CONFLICT (fid) DO UPDATE SET " + excludedUpdates()
+}
+
+// insertNewTrailQuery builds an INSERT that skips the trail when a live trail has its FID, so no row is
+// affected. A soft-deleted trail with the same FID is replaced.
+func (d dialect) insertNewTrailQuery() string {
+    return d.insertTrailQuery() + " ON CONFLICT (fid) DO UPDATE SET " + excludedUpdates() + " WHERE trails.deleted_at IS NOT NULL"
+}
+
+// excludedUpdates sets every trail column but the FID from the conflicting INSERT, and clears deleted_at
+func excludedUpdates() string {
+    updates := make([]string, 0, len(models.TrailColumns))
+    for _, column := range models.TrailColumns[1:] {
+        updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", column, column))
+    }
+    return strings.Join(append(updates, "deleted_at = NULL"), ", ")
+}
+
+// updateTrailQuery builds the UPDATE of every column of the live trail identified by the first argument,
+// taking the arguments in TrailColumns order like the INSERT
+func (d dialect) updateTrailQuery() string {
+    updates := make([]string, 0, len(models.TrailColumns)-1)
+    for i, column := range models.TrailColumns[1:] {
+        updates = append(updates, fmt.Sprintf("%s = %s", column, d.placeholder(i+2)))
+    }
+    return fmt.Sprintf("UPDATE trails SET %s WHERE fid = %s AND %s", strings.Join(updates, ", "), d.placeholder(1), liveCondition)
+}
+
+// getTrailQuery selects the live trail identified by the first argument
+func (d dialect) getTrailQuery() string {
+    return fmt.Sprintf("SELECT %s FROM trails WHERE fid = %s AND %s", strings.Join(models.TrailColumns, ", "), d.placeholder(1), liveCondition)
+}
+
+// deleteTrailQuery removes the live trail identified by the first argument
+func (d dialect) deleteTrailQuery() string {
+    return fmt.Sprintf("DELETE FROM trails WHERE fid = %s AND %s", d.placeholder(1), liveCondition)
+}
+
+// softDeleteTrailQuery marks the live trail identified by the first argument deleted at the time in the second
+func (d dialect) softDeleteTrailQuery() string {
+    return fmt.Sprintf("UPDATE trails SET deleted_at = %s WHERE fid = %s AND %s", d.placeholder(2), d.placeholder(1), liveCondition)
+}
+
+// facetQuery builds the GROUP BY query that counts the values of one field.
+// field must have been validated against the known columns, since it is interpolated.
+func facetQuery(field, where string) string {
+    return fmt.Sprintf("SELECT %s, COUNT(*) FROM trails%s GROUP BY %s ORDER BY %s NULLS LAST", field, where, field, field)
+}
+
+// sqlValue converts typed model values into values the database driver can encode
+func sqlValue(value interface{}) interface{} {
+    switch v := value.(type) {
+    case models.HorseTrail:
+        return string(v)
+    case models.ADAParking:
+        return string(v)
+    }
+    return value
+}
+
+// normalizeScanned converts a value scanned from the database into the form used by the models
+func normalizeScanned(field string, value interface{}) interface{} {
+    switch v := value.(type) {
+    case int32:
+        return int(v)
+    case int64:
+        // SQLite stores booleans as 0 and 1
+        if models.FieldKinds[field] == models.KindBool {
+            return v != 0
+        }
+        return int(v)
+    case string:
+        switch models.FieldKinds[field] {
+        case models.KindHorseTrail:
+            return models.HorseTrail(v)
+        case models.KindADAParking:
+            return models.ADAParking(v)
+        }
+    }
+    return value
+}
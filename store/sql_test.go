@@ -0,0 +1,37 @@
+package store
+
+import (
+    "testing"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestBuildWhereParameterizesEveryValue(t *testing.T) {
+    where, args, err := postgresDialect.buildWhere([]Filter{
+        {Field: "difficulty", Op: OpIn, Values: []interface{}{"easy", "moderate"}},
+        {Field: "fee", Op: OpNe, Value: true},
+        {Any: []Filter{
+            {Field: "bike_trail", Value: true},
+            {Field: "horse_trail", Value: models.HorseTrailDesignated},
+        }},
+        {Field: "park_spaces", Op: OpGte, Value: 10},
+    })
+    require.NoError(t, err)
+    assert.Equal(t, " WHERE deleted_at IS NULL AND difficulty IN ($1, $2) AND (fee <> $3 OR fee IS NULL)"+
+        " AND (bike_trail = $4 OR horse_trail = $5) AND park_spaces >= $6", where)
+    assert.Equal(t, []interface{}{"easy", "moderate", true, true, "designated", 10}, args)
+}
+
+func TestBuildWhereRejectsUnsafeInput(t *testing.T) {
+    for _, filters := range [][]Filter{
+        {{Field: "fee; DROP TABLE trails", Value: true}},
+        {{Field: "fee", Op: "= 1 OR 1", Value: true}},
+        {{Field: "difficulty", Op: OpIn}},
+        {{Any: []Filter{}}},
+    } {
+        _, _, err := sqliteDialect.buildWhere(filters)
+        assert.Error(t, err)
+    }
+}
@@ -0,0 +1,584 @@
+package store
+
+import (
+    "context"
+    "database/sql"
+    "errors"
+    "fmt"
+    "time"
+    "trail-finder/models"
+)
+
+// SQLiteStore is a TrailStore backed by the trails table in a SQLite database,
+// for running trail-finder as a single binary without PostgreSQL
+type SQLiteStore struct {
+    db *sql.DB
+}
+
+// NewSQLiteStore creates a store that runs queries on a database opened with db.OpenSQLite
+func NewSQLiteStore(sqlDB *sql.DB) *SQLiteStore {
+    return &SQLiteStore{db: sqlDB}
+}
+
+// List returns the matching trails in the requested order, with ties broken by FID
+func (s *SQLiteStore) List(ctx context.Context, opts ListOptions) ([]models.Trail, error) {
+    trails := []models.Trail{}
+    err := s.Stream(ctx, opts, func(trail models.Trail) error {
+        trails = append(trails, trail)
+        return nil
+    })
+    if err != nil {
+        return nil, err
+    }
+    return trails, nil
+}
+
+// Stream calls fn with each matching trail as it is read from the List query
+func (s *SQLiteStore) Stream(ctx context.Context, opts ListOptions, fn func(models.Trail) error) error {
+    query, args, err := sqliteDialect.listTrailsQuery(opts)
+    if err != nil {
+        return err
+    }
+    return s.streamTrails(ctx, query, args, fn)
+}
+
+// streamTrails runs a query selecting TrailColumns and calls fn with each trail it returns
+func (s *SQLiteStore) streamTrails(ctx context.Context, query string, args []interface{}, fn func(models.Trail) error) error {
+    rows, err := s.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return fmt.Errorf("failed to query trails: %w", err)
+    }
+    defer rows.Close()
+
+    for rows.Next() {
+        var trail models.Trail
+        if err := rows.Scan(trail.ScanFields()...); err != nil {
+            return fmt.Errorf("failed to scan trails: %w", err)
+        }
+        if err := fn(trail); err != nil {
+            return err
+        }
+    }
+    if err := rows.Err(); err != nil {
+        return fmt.Errorf("failed to read trails: %w", err)
+    }
+    return nil
+}
+
+// Get returns the trail with the given FID
+func (s *SQLiteStore) Get(ctx context.Context, fid int) (models.Trail, error) {
+    query := sqliteDialect.getTrailQuery()
+
+    var trail models.Trail
+    err := s.db.QueryRowContext(ctx, query, fid).Scan(trail.ScanFields()...)
+    if errors.Is(err, sql.ErrNoRows) {
+        return models.Trail{}, ErrNotFound
+    }
+    if err != nil {
+        return models.Trail{}, fmt.Errorf("failed to get trail %d: %w", fid, err)
+    }
+    return trail, nil
+}
+
+// Insert adds a new trail, or returns ErrConflict if its FID is taken
+func (s *SQLiteStore) Insert(ctx context.Context, trail models.Trail) error {
+    result, err := s.db.ExecContext(ctx, sqliteDialect.insertNewTrailQuery(), trail.Values()...)
+    if err != nil {
+        return fmt.Errorf("failed to insert trail %d: %w", trail.FID, err)
+    }
+    return requireAffected(result, ErrConflict)
+}
+
+// Update replaces every column of the trail with the same FID, or returns ErrNotFound
+func (s *SQLiteStore) Update(ctx context.Context, trail models.Trail) error {
+    result, err := s.db.ExecContext(ctx, sqliteDialect.updateTrailQuery(), trail.Values()...)
+    if err != nil {
+        return fmt.Errorf("failed to update trail %d: %w", trail.FID, err)
+    }
+    return requireAffected(result, ErrNotFound)
+}
+
+// Modify replaces the trail with the given FID by the trail fn makes of it in a single transaction. The handle
+// has one connection, so the transaction also keeps the server's other requests from changing the trail.
+func (s *SQLiteStore) Modify(ctx context.Context, fid int, fn func(models.Trail) (models.Trail, error)) (models.Trail, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return models.Trail{}, fmt.Errorf("could not start transaction: %w", err)
+    }
+    defer tx.Rollback()
+
+    var existing models.Trail
+    err = tx.QueryRowContext(ctx, sqliteDialect.getTrailQuery(), fid).Scan(existing.ScanFields()...)
+    if errors.Is(err, sql.ErrNoRows) {
+        return models.Trail{}, ErrNotFound
+    }
+    if err != nil {
+        return models.Trail{}, fmt.Errorf("failed to get trail %d: %w", fid, err)
+    }
+
+    trail, err := fn(existing)
+    if err != nil {
+        return models.Trail{}, err
+    }
+    trail.FID = fid
+    if _, err := tx.ExecContext(ctx, sqliteDialect.updateTrailQuery(), trail.Values()...); err != nil {
+        return models.Trail{}, fmt.Errorf("failed to update trail %d: %w", fid, err)
+    }
+    if err := tx.Commit(); err != nil {
+        return models.Trail{}, fmt.Errorf("could not commit transaction: %w", err)
+    }
+    return trail, nil
+}
+
+// Delete removes the trail with the given FID, or returns ErrNotFound
+func (s *SQLiteStore) Delete(ctx context.Context, fid int) error {
+    result, err := s.db.ExecContext(ctx, sqliteDialect.deleteTrailQuery(), fid)
+    if err != nil {
+        return fmt.Errorf("failed to delete trail %d: %w", fid, err)
+    }
+    return requireAffected(result, ErrNotFound)
+}
+
+// CreateImport records a new import job
+func (s *SQLiteStore) CreateImport(ctx context.Context, job models.ImportJob) error {
+    values, err := importValues(job)
+    if err != nil {
+        return err
+    }
+    if _, err := s.db.ExecContext(ctx, sqliteDialect.insertImportQuery(), values...); err != nil {
+        return fmt.Errorf("failed to create import %s: %w", job.ID, err)
+    }
+    return nil
+}
+
+// UpdateImport saves the progress of an existing import job, or returns ErrNotFound
+func (s *SQLiteStore) UpdateImport(ctx context.Context, job models.ImportJob) error {
+    values, err := importValues(job)
+    if err != nil {
+        return err
+    }
+    result, err := s.db.ExecContext(ctx, sqliteDialect.updateImportQuery(), values...)
+    if err != nil {
+        return fmt.Errorf("failed to update import %s: %w", job.ID, err)
+    }
+    return requireAffected(result, ErrNotFound)
+}
+
+// GetImport returns the import job with the given ID, or ErrNotFound
+func (s *SQLiteStore) GetImport(ctx context.Context, id string) (models.ImportJob, error) {
+    var scan importScan
+    err := s.db.QueryRowContext(ctx, sqliteDialect.getImportQuery(), id).Scan(scan.importScanFields()...)
+    if errors.Is(err, sql.ErrNoRows) {
+        return models.ImportJob{}, ErrNotFound
+    }
+    if err != nil {
+        return models.ImportJob{}, fmt.Errorf("failed to get import %s: %w", id, err)
+    }
+    return scan.importJob()
+}
+
+// ListImports returns up to limit import jobs, newest first
+func (s *SQLiteStore) ListImports(ctx context.Context, limit int) ([]models.ImportJob, error) {
+    query, args := sqliteDialect.listImportsQuery(limit)
+    rows, err := s.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query imports: %w", err)
+    }
+    defer rows.Close()
+
+    jobs := []models.ImportJob{}
+    for rows.Next() {
+        var scan importScan
+        if err := rows.Scan(scan.importScanFields()...); err != nil {
+            return nil, fmt.Errorf("failed to scan imports: %w", err)
+        }
+        job, err := scan.importJob()
+        if err != nil {
+            return nil, err
+        }
+        jobs = append(jobs, job)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read imports: %w", err)
+    }
+    return jobs, nil
+}
+
+// HeartbeatImports records at as the heartbeat of the queued and running jobs of owner
+func (s *SQLiteStore) HeartbeatImports(ctx context.Context, owner string, at time.Time) error {
+    if _, err := s.db.ExecContext(ctx, sqliteDialect.heartbeatImportsQuery(), at, owner); err != nil {
+        return fmt.Errorf("failed to record import heartbeat: %w", err)
+    }
+    return nil
+}
+
+// FailStaleImports fails the unfinished jobs of other owners whose last heartbeat is before cutoff
+func (s *SQLiteStore) FailStaleImports(ctx context.Context, owner string, cutoff time.Time, reason string) (int, error) {
+    result, err := s.db.ExecContext(ctx, sqliteDialect.failStaleImportsQuery(), reason, time.Now().UTC(), owner, cutoff)
+    if err != nil {
+        return 0, fmt.Errorf("failed to fail stale imports: %w", err)
+    }
+    n, err := result.RowsAffected()
+    if err != nil {
+        return 0, fmt.Errorf("failed to read affected rows: %w", err)
+    }
+    return int(n), nil
+}
+
+// requireAffected returns errNone when a statement changed no rows
+func requireAffected(result sql.Result, errNone error) error {
+    n, err := result.RowsAffected()
+    if err != nil {
+        return fmt.Errorf("failed to read affected rows: %w", err)
+    }
+    if n == 0 {
+        return errNone
+    }
+    return nil
+}
+
+// Upsert inserts the trail, or updates every column of the existing trail with the same FID
+func (s *SQLiteStore) Upsert(ctx context.Context, trail models.Trail) error {
+    if _, err := s.db.ExecContext(ctx, sqliteDialect.upsertTrailQuery(), trail.Values()...); err != nil {
+        return fmt.Errorf("failed to upsert trail %d: %w", trail.FID, err)
+    }
+    return nil
+}
+
+// ReplaceAll deletes every trail and inserts the given set in a single transaction
+func (s *SQLiteStore) ReplaceAll(ctx context.Context, trails []models.Trail) error {
+    _, _, err := s.ReplaceAllFrom(ctx, SliceSource(trails), nil)
+    return err
+}
+
+// ReplaceAllFrom deletes every trail and inserts the streamed trails with a prepared statement, in a single
+// transaction that also records the version. SQLite runs in process, so a statement per trail costs no network
+// round trip.
+func (s *SQLiteStore) ReplaceAllFrom(ctx context.Context, src TrailSource, label VersionLabel) (int, models.DatasetVersion, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("could not start transaction: %w", err)
+    }
+    defer tx.Rollback()
+
+    // Clear the existing data in the trails table
+    if _, err := tx.ExecContext(ctx, "DELETE FROM trails"); err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("could not clear existing data: %w", err)
+    }
+
+    insert, err := tx.PrepareContext(ctx, sqliteDialect.insertTrailQuery())
+    if err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("could not prepare insert: %w", err)
+    }
+    defer insert.Close()
+
+    count := 0
+    for src.Next() {
+        trail := src.Trail()
+        if _, err := insert.ExecContext(ctx, trail.Values()...); err != nil {
+            return 0, models.DatasetVersion{}, fmt.Errorf("failed to insert trail %d: %w", trail.FID, err)
+        }
+        count++
+    }
+    if err := src.Err(); err != nil {
+        return 0, models.DatasetVersion{}, err
+    }
+
+    version, err := sqliteSnapshotLabel(ctx, tx, label)
+    if err != nil {
+        return 0, models.DatasetVersion{}, err
+    }
+    if err := tx.Commit(); err != nil {
+        return 0, models.DatasetVersion{}, fmt.Errorf("could not commit transaction: %w", err)
+    }
+    return count, version, nil
+}
+
+// Merge upserts the new and changed trails, soft-deletes missing ones and records the version in a single transaction
+func (s *SQLiteStore) Merge(ctx context.Context, trails []models.Trail, softDelete bool, label VersionLabel) (models.MergeCounts, models.DatasetVersion, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("could not start transaction: %w", err)
+    }
+    defer tx.Rollback()
+
+    query, args, err := sqliteDialect.listTrailsQuery(ListOptions{})
+    if err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, err
+    }
+    rows, err := tx.QueryContext(ctx, query, args...)
+    if err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("failed to query trails: %w", err)
+    }
+    stored := []models.Trail{}
+    for rows.Next() {
+        var trail models.Trail
+        if err := rows.Scan(trail.ScanFields()...); err != nil {
+            rows.Close()
+            return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("failed to scan trails: %w", err)
+        }
+        stored = append(stored, trail)
+    }
+    rows.Close()
+    if err := rows.Err(); err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("failed to read trails: %w", err)
+    }
+
+    plan := planMerge(stored, trails, softDelete)
+    for _, trail := range plan.upserts {
+        if _, err := tx.ExecContext(ctx, sqliteDialect.upsertTrailQuery(), trail.Values()...); err != nil {
+            return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("failed to upsert trail %d: %w", trail.FID, err)
+        }
+    }
+    deletedAt := time.Now().UTC()
+    for _, fid := range plan.deletes {
+        if _, err := tx.ExecContext(ctx, sqliteDialect.softDeleteTrailQuery(), fid, deletedAt); err != nil {
+            return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("failed to delete trail %d: %w", fid, err)
+        }
+    }
+
+    version, err := sqliteSnapshotLabel(ctx, tx, label)
+    if err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, err
+    }
+    if err := tx.Commit(); err != nil {
+        return models.MergeCounts{}, models.DatasetVersion{}, fmt.Errorf("could not commit transaction: %w", err)
+    }
+    return plan.counts, version, nil
+}
+
+// Search ranks the matching trails against the text. SQLite has no trigram matching, so the trails
+// selected by the filters are ranked with the same word matching as the in-memory store.
+func (s *SQLiteStore) Search(ctx context.Context, text string, opts ListOptions) ([]SearchResult, error) {
+    trails, err := s.List(ctx, ListOptions{Filters: opts.Filters})
+    if err != nil {
+        return nil, err
+    }
+    if err := validateSort(opts.Sort, opts.After); err != nil {
+        return nil, err
+    }
+    return searchTrails(trails, searchTerms(text), opts), nil
+}
+
+// SearchRanking returns RankingWords, as searches are ranked by searchTrails like in the in-memory store
+func (s *SQLiteStore) SearchRanking() string {
+    return RankingWords
+}
+
+// Count returns the number of matching trails
+func (s *SQLiteStore) Count(ctx context.Context, filters []Filter) (int, error) {
+    where, args, err := sqliteDialect.buildWhere(filters)
+    if err != nil {
+        return 0, err
+    }
+
+    var count int
+    if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM trails"+where, args...).Scan(&count); err != nil {
+        return 0, fmt.Errorf("failed to count trails: %w", err)
+    }
+    return count, nil
+}
+
+// Facets counts the distinct values of each field among the matching trails
+func (s *SQLiteStore) Facets(ctx context.Context, fields []string, filters []Filter) (map[string][]FacetCount, error) {
+    if err := validateFields(fields...); err != nil {
+        return nil, err
+    }
+    where, args, err := sqliteDialect.buildWhere(filters)
+    if err != nil {
+        return nil, err
+    }
+
+    facets := make(map[string][]FacetCount, len(fields))
+    for _, field := range fields {
+        counts, err := s.facet(ctx, field, where, args)
+        if err != nil {
+            return nil, err
+        }
+        facets[field] = counts
+    }
+    return facets, nil
+}
+
+// facet counts the values of a single field
+func (s *SQLiteStore) facet(ctx context.Context, field, where string, args []interface{}) ([]FacetCount, error) {
+    rows, err := s.db.QueryContext(ctx, facetQuery(field, where), args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to count %s values: %w", field, err)
+    }
+    defer rows.Close()
+
+    counts := []FacetCount{}
+    for rows.Next() {
+        var value interface{}
+        var count int
+        if err := rows.Scan(&value, &count); err != nil {
+            return nil, fmt.Errorf("failed to scan %s values: %w", field, err)
+        }
+        counts = append(counts, FacetCount{Value: normalizeScanned(field, value), Count: count})
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read %s values: %w", field, err)
+    }
+    return counts, nil
+}
+
+// Ping checks that the database is reachable
+func (s *SQLiteStore) Ping(ctx context.Context) error {
+    if err := s.db.PingContext(ctx); err != nil {
+        return fmt.Errorf("database ping failed: %w", err)
+    }
+    return nil
+}
+
+// CreateVersion snapshots the current trails as a new dataset version in a single transaction
+func (s *SQLiteStore) CreateVersion(ctx context.Context, version models.DatasetVersion) (models.DatasetVersion, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not start transaction: %w", err)
+    }
+    defer tx.Rollback()
+
+    version, err = sqliteSnapshot(ctx, tx, newVersion(version))
+    if err != nil {
+        return models.DatasetVersion{}, err
+    }
+    if err := tx.Commit(); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not commit transaction: %w", err)
+    }
+    return version, nil
+}
+
+// sqliteSnapshotLabel snapshots the trails as the version described by the label, if any
+func sqliteSnapshotLabel(ctx context.Context, tx *sql.Tx, label VersionLabel) (models.DatasetVersion, error) {
+    if label == nil {
+        return models.DatasetVersion{}, nil
+    }
+    return sqliteSnapshot(ctx, tx, newVersion(label()))
+}
+
+// sqliteSnapshot records the version and copies the live trails into its snapshot
+func sqliteSnapshot(ctx context.Context, tx *sql.Tx, version models.DatasetVersion) (models.DatasetVersion, error) {
+    err := tx.QueryRowContext(ctx, sqliteDialect.insertVersionQuery(),
+        version.Source, version.SHA256, version.RollbackOf, version.CreatedAt).Scan(&version.ID)
+    if err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("failed to create version: %w", err)
+    }
+    result, err := tx.ExecContext(ctx, sqliteDialect.snapshotTrailsQuery(), version.ID)
+    if err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("failed to snapshot trails: %w", err)
+    }
+    count, err := result.RowsAffected()
+    if err != nil {
+        return models.DatasetVersion{}, err
+    }
+    version.TrailCount = int(count)
+    if _, err := tx.ExecContext(ctx, sqliteDialect.setTrailCountQuery(), version.ID, version.TrailCount); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("failed to update version %d: %w", version.ID, err)
+    }
+    return version, nil
+}
+
+// GetVersion returns the dataset version with the given ID, or ErrNotFound
+func (s *SQLiteStore) GetVersion(ctx context.Context, id int) (models.DatasetVersion, error) {
+    return scanSQLiteVersion(s.db.QueryRowContext(ctx, sqliteDialect.getVersionQuery(), id), id)
+}
+
+// scanSQLiteVersion reads the version selected by getVersionQuery, or returns ErrNotFound
+func scanSQLiteVersion(row *sql.Row, id int) (models.DatasetVersion, error) {
+    var version models.DatasetVersion
+    err := row.Scan(versionScanFields(&version)...)
+    if errors.Is(err, sql.ErrNoRows) {
+        return models.DatasetVersion{}, ErrNotFound
+    }
+    if err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("failed to get version %d: %w", id, err)
+    }
+    return version, nil
+}
+
+// ListVersions returns up to limit dataset versions, newest first
+func (s *SQLiteStore) ListVersions(ctx context.Context, limit int) ([]models.DatasetVersion, error) {
+    query, args := sqliteDialect.listVersionsQuery(limit)
+    rows, err := s.db.QueryContext(ctx, query, args...)
+    if err != nil {
+        return nil, fmt.Errorf("failed to query versions: %w", err)
+    }
+    defer rows.Close()
+
+    versions := []models.DatasetVersion{}
+    for rows.Next() {
+        var version models.DatasetVersion
+        if err := rows.Scan(versionScanFields(&version)...); err != nil {
+            return nil, fmt.Errorf("failed to scan versions: %w", err)
+        }
+        versions = append(versions, version)
+    }
+    if err := rows.Err(); err != nil {
+        return nil, fmt.Errorf("failed to read versions: %w", err)
+    }
+    return versions, nil
+}
+
+// ListVersionTrails runs the List and Count queries against the snapshot of a dataset version
+func (s *SQLiteStore) ListVersionTrails(ctx context.Context, id int, opts ListOptions) ([]models.Trail, int, error) {
+    where, countArgs, err := sqliteDialect.buildWhere(opts.Filters)
+    if err != nil {
+        return nil, 0, err
+    }
+    trails := []models.Trail{}
+    err = s.StreamVersionTrails(ctx, id, opts, func(trail models.Trail) error {
+        trails = append(trails, trail)
+        return nil
+    })
+    if err != nil {
+        return nil, 0, err
+    }
+
+    var total int
+    if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+versionTrails(id)+where, countArgs...).Scan(&total); err != nil {
+        return nil, 0, fmt.Errorf("failed to count trails of version %d: %w", id, err)
+    }
+    return trails, total, nil
+}
+
+// StreamVersionTrails runs the List query against the snapshot of a dataset version, calling fn with each trail
+func (s *SQLiteStore) StreamVersionTrails(ctx context.Context, id int, opts ListOptions, fn func(models.Trail) error) error {
+    if _, err := s.GetVersion(ctx, id); err != nil {
+        return err
+    }
+    query, args, err := sqliteDialect.listFromQuery(versionTrails(id), opts)
+    if err != nil {
+        return err
+    }
+    return s.streamTrails(ctx, query, args, fn)
+}
+
+// Rollback replaces the trails with the snapshot of a version and records the result, in a single transaction
+func (s *SQLiteStore) Rollback(ctx context.Context, id int) (models.DatasetVersion, error) {
+    tx, err := s.db.BeginTx(ctx, nil)
+    if err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not start transaction: %w", err)
+    }
+    defer tx.Rollback()
+
+    restored, err := scanSQLiteVersion(tx.QueryRowContext(ctx, sqliteDialect.getVersionQuery(), id), id)
+    if err != nil {
+        return models.DatasetVersion{}, err
+    }
+    if _, err := tx.ExecContext(ctx, "DELETE FROM trails"); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not clear existing data: %w", err)
+    }
+    if _, err := tx.ExecContext(ctx, sqliteDialect.restoreTrailsQuery(), id); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("failed to restore version %d: %w", id, err)
+    }
+    version, err := sqliteSnapshot(ctx, tx, rollbackVersion(restored))
+    if err != nil {
+        return models.DatasetVersion{}, err
+    }
+
+    if err := tx.Commit(); err != nil {
+        return models.DatasetVersion{}, fmt.Errorf("could not commit transaction: %w", err)
+    }
+    return version, nil
+}
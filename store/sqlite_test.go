@@ -0,0 +1,100 @@
+package store
+
+import (
+    "context"
+    "path/filepath"
+    "testing"
+    "time"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+func TestSQLiteStore(t *testing.T) {
+    s, closeStore, err := Open("sqlite://:memory:")
+    require.NoError(t, err)
+    defer closeStore()
+
+    testTrailStore(t, s)
+}
+
+func TestSQLiteStoreRoundTripsTypedValues(t *testing.T) {
+    ctx := context.Background()
+    s, closeStore, err := Open("sqlite://" + filepath.Join(t.TempDir(), "trails.db"))
+    require.NoError(t, err)
+    defer closeStore()
+
+    spaces, horseTrails := 12, 2
+    lat, lon := 40.0, -105.3
+    from := time.Date(2020, 5, 1, 0, 0, 0, 0, time.UTC)
+    want := models.Trail{
+        FID: 7, Name: "betasso", Fee: true, ADAFacility: true, HorseTrail: models.HorseTrailDesignated,
+        HorseTrailCount: &horseTrails, ADAParking: models.ADAParkingOffsite,
+        ParkSpaces: &spaces, DateFrom: &from, Latitude: &lat, Longitude: &lon,
+    }
+    require.NoError(t, s.Upsert(ctx, want))
+
+    got, err := s.Get(ctx, 7)
+    require.NoError(t, err)
+    assert.True(t, got.Fee)
+    assert.True(t, got.ADAFacility)
+    assert.False(t, got.Restrooms)
+    assert.Equal(t, models.HorseTrailDesignated, got.HorseTrail)
+    assert.Equal(t, &horseTrails, got.HorseTrailCount)
+    assert.Equal(t, models.ADAParkingOffsite, got.ADAParking)
+    assert.Nil(t, got.ADAParkingCount)
+    require.NotNil(t, got.ParkSpaces)
+    assert.Equal(t, 12, *got.ParkSpaces)
+    assert.Nil(t, got.DogTube)
+    require.NotNil(t, got.DateFrom)
+    assert.True(t, from.Equal(*got.DateFrom))
+    assert.Nil(t, got.DateTo)
+    assert.Equal(t, &lat, got.Latitude)
+    assert.Equal(t, &lon, got.Longitude)
+
+    // Filters on booleans and dates use the same normalized values as the other stores
+    count, err := s.Count(ctx, []Filter{{Field: "fee", Value: true}, {Field: "date_from", Value: from}})
+    require.NoError(t, err)
+    assert.Equal(t, 1, count)
+
+    facets, err := s.Facets(ctx, []string{"fee"}, nil)
+    require.NoError(t, err)
+    assert.Equal(t, []FacetCount{{Value: true, Count: 1}}, facets["fee"])
+}
+
+func TestSQLiteStoreKeepsDataAcrossOpens(t *testing.T) {
+    ctx := context.Background()
+    dsn := "sqlite://" + filepath.Join(t.TempDir(), "trails.db")
+
+    s, closeStore, err := Open(dsn)
+    require.NoError(t, err)
+    require.NoError(t, s.ReplaceAll(ctx, testTrails()))
+    closeStore()
+
+    // Reopening runs the migrations again without changing the existing data
+    s, closeStore, err = Open(dsn)
+    require.NoError(t, err)
+    defer closeStore()
+
+    count, err := s.Count(ctx, nil)
+    require.NoError(t, err)
+    assert.Equal(t, 3, count)
+}
+
+// BenchmarkSQLiteReplaceAll measures streaming a replace load into SQLite, for comparison with PostgreSQL
+func BenchmarkSQLiteReplaceAll(b *testing.B) {
+    s, closeStore, err := Open("sqlite://" + filepath.Join(b.TempDir(), "trails.db"))
+    if err != nil {
+        b.Fatal(err)
+    }
+    defer closeStore()
+
+    trails := benchmarkTrails(10000)
+    b.ResetTimer()
+    for i := 0; i < b.N; i++ {
+        if _, _, err := s.ReplaceAllFrom(context.Background(), SliceSource(trails), nil); err != nil {
+            b.Fatal(err)
+        }
+    }
+}
@@ -0,0 +1,202 @@
+// Package store defines the TrailStore repository used by the handlers and CLI,
+// with PostgreSQL, SQLite and in-memory implementations.
+package store
+
+import (
+    "context"
+    "errors"
+    "fmt"
+    "trail-finder/models"
+)
+
+// ErrNotFound is returned when a trail with the requested FID does not exist
+var ErrNotFound = errors.New("trail not found")
+
+// ErrConflict is returned when inserting a trail whose FID is already taken
+var ErrConflict = errors.New("trail already exists")
+
+// ErrInvalidCursor is returned when a pagination cursor cannot be decoded or belongs to a different listing
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Op is the comparison a Filter applies between a column and its value
+type Op string
+
+// Filter operators. Range operators compare counts and dates; unset values never match them.
+const (
+    OpEq    Op = "eq"  // Column equals Value
+    OpNe    Op = "ne"  // Column differs from Value, or is unset
+    OpIn    Op = "in"  // Column equals one of Values
+    OpNotIn Op = "nin" // Column equals none of Values, or is unset
+    OpGt    Op = "gt"  // Column is greater than Value
+    OpGte   Op = "gte" // Column is greater than or equal to Value
+    OpLt    Op = "lt"  // Column is less than Value
+    OpLte   Op = "lte" // Column is less than or equal to Value
+)
+
+// sqlOperators maps the single-value operators to their SQL comparison
+var sqlOperators = map[Op]string{
+    OpEq:  "=",
+    OpNe:  "<>",
+    OpGt:  ">",
+    OpGte: ">=",
+    OpLt:  "<",
+    OpLte: "<=",
+}
+
+// Filter restricts a listing to trails whose Field compares to Value (or Values, for OpIn and OpNotIn)
+// with Op, which defaults to OpEq. A filter with Any set is an OR group: it matches when at least one
+// of the filters in Any matches, and its other fields are ignored. Likewise a filter with Near set matches
+// the trails within its radius of its point.
+// Values must already be normalized with models.NormalizeValue.
+type Filter struct {
+    Field  string
+    Op     Op
+    Value  interface{}
+    Values []interface{}
+    Any    []Filter
+    Near   *Near
+}
+
+// SortKey orders a listing by a column, or by the distance from From with DistanceField.
+// Unset values sort last in either direction.
+type SortKey struct {
+    Field string
+    Desc  bool
+    From  *models.Point // Point distances are measured from, for DistanceField
+}
+
+// ListOptions controls which trails List returns
+type ListOptions struct {
+    Filters []Filter  // All filters must match
+    Sort    []SortKey // Applied in order, with ties broken by FID
+    After   *Cursor   // Only return trails after this listing cursor, which must use the same Sort
+    Limit   int      // Maximum number of trails to return; 0 means no limit
+    Offset  int      // Number of matching trails to skip
+}
+
+// FacetCount is the number of trails that have a given value in a column
+type FacetCount struct {
+    Value interface{} `json:"value"`
+    Count int         `json:"count"`
+}
+
+// TrailStore is the repository for trail records
+type TrailStore interface {
+    // List returns the trails matching the options
+    List(ctx context.Context, opts ListOptions) ([]models.Trail, error)
+    // Stream calls fn with each trail matching the options, in List order, as it is read from the database,
+    // without holding the trails in memory together. It stops at the first error fn returns and returns it.
+    Stream(ctx context.Context, opts ListOptions, fn func(models.Trail) error) error
+    // Get returns the trail with the given FID, or ErrNotFound
+    Get(ctx context.Context, fid int) (models.Trail, error)
+    // Insert adds a new trail, or returns ErrConflict if its FID is taken
+    Insert(ctx context.Context, trail models.Trail) error
+    // Update replaces the existing trail with the same FID, or returns ErrNotFound
+    Update(ctx context.Context, trail models.Trail) error
+    // Modify replaces the trail with the given FID by the trail fn makes of it, keeping the FID, and returns the
+    // saved trail, or ErrNotFound. The trail is locked from the read to the write, so concurrent changes to it
+    // apply one after the other instead of overwriting each other. An error from fn leaves the trail unchanged.
+    Modify(ctx context.Context, fid int, fn func(models.Trail) (models.Trail, error)) (models.Trail, error)
+    // Upsert inserts the trail, or replaces the existing trail with the same FID
+    Upsert(ctx context.Context, trail models.Trail) error
+    // Delete removes the trail with the given FID, or returns ErrNotFound
+    Delete(ctx context.Context, fid int) error
+    // ReplaceAll atomically replaces every stored trail with the given set
+    ReplaceAll(ctx context.Context, trails []models.Trail) error
+    // ReplaceAllFrom atomically replaces every stored trail with the trails streamed from the source, returning
+    // how many were stored. If the source fails, the stored trails are left unchanged and its error is returned.
+    // With a label, the new trails are recorded as a dataset version in the same transaction, which is returned.
+    ReplaceAllFrom(ctx context.Context, src TrailSource, label VersionLabel) (int, models.DatasetVersion, error)
+    // Merge atomically inserts the trails with new FIDs and updates the stored trails whose columns changed,
+    // leaving the others untouched. With softDelete, stored trails missing from the set are soft-deleted:
+    // hidden from every query until a later insert or merge brings their FID back. With a label, the merged
+    // trails are recorded as a dataset version in the same transaction, which is returned.
+    Merge(ctx context.Context, trails []models.Trail, softDelete bool, label VersionLabel) (models.MergeCounts, models.DatasetVersion, error)
+    // Search returns the trails matching the filters whose name, AKA or address match the text, best match first
+    Search(ctx context.Context, text string, opts ListOptions) ([]SearchResult, error)
+    // SearchRanking names how Search ranks results, RankingFullText or RankingWords. Positional search cursors
+    // record it, so a cursor is only accepted by a store ranking searches the same way.
+    SearchRanking() string
+    // Count returns the number of trails matching the filters
+    Count(ctx context.Context, filters []Filter) (int, error)
+    // Facets returns, for each field, the distinct values among the matching trails and how many trails have each
+    Facets(ctx context.Context, fields []string, filters []Filter) (map[string][]FacetCount, error)
+    // Ping checks that the underlying database is reachable
+    Ping(ctx context.Context) error
+
+    ImportStore
+    VersionStore
+}
+
+// validateFields checks that every field name is a known trails column
+func validateFields(fields ...string) error {
+    for _, field := range fields {
+        if _, ok := models.FieldKinds[field]; !ok {
+            return fmt.Errorf("unknown field %q", field)
+        }
+    }
+    return nil
+}
+
+// validateFilters checks that the filters only use known columns and operators, including inside OR groups
+func validateFilters(filters []Filter) error {
+    for _, f := range filters {
+        if f.Any != nil {
+            if len(f.Any) == 0 {
+                return fmt.Errorf("empty OR group")
+            }
+            if err := validateFilters(f.Any); err != nil {
+                return err
+            }
+            continue
+        }
+        if f.Near != nil {
+            if !(f.Near.RadiusKm > 0) {
+                return fmt.Errorf("near filter needs a positive radius")
+            }
+            continue
+        }
+        if err := validateFields(f.Field); err != nil {
+            return err
+        }
+        switch f.op() {
+        case OpIn, OpNotIn:
+            if len(f.Values) == 0 {
+                return fmt.Errorf("%s filter on %s needs at least one value", f.op(), f.Field)
+            }
+        default:
+            if _, ok := sqlOperators[f.op()]; !ok {
+                return fmt.Errorf("unknown filter operator %q", f.Op)
+            }
+        }
+    }
+    return nil
+}
+
+// validateSort checks that every sort key is a known trails column or a distance from a point, and that a
+// cursor matches the sort keys. Distance sorts have no listing cursors, as distances are not stored.
+func validateSort(keys []SortKey, after *Cursor) error {
+    for _, key := range keys {
+        if key.Field == DistanceField {
+            if key.From == nil {
+                return fmt.Errorf("sorting by %s needs a point to measure from", DistanceField)
+            }
+            continue
+        }
+        if err := validateFields(key.Field); err != nil {
+            return err
+        }
+    }
+    if after != nil && (after.Positional || SortsByDistance(keys) || FormatSort(after.Sort) != FormatSort(keys) || len(after.Values) != len(keys)) {
+        return ErrInvalidCursor
+    }
+    return nil
+}
+
+// op returns the filter's operator, defaulting to equality
+func (f Filter) op() Op {
+    if f.Op == "" {
+        return OpEq
+    }
+    return f.Op
+}
@@ -0,0 +1,112 @@
+package store
+
+import (
+    "context"
+    "fmt"
+    "sort"
+    "strings"
+    "time"
+    "trail-finder/models"
+)
+
+// VersionLabel describes the dataset version recorded by a load. It is called once the source of the load has
+// been read to the end, so the version can identify the whole file, as its SHA-256 does. A nil label records
+// no version.
+type VersionLabel func() models.DatasetVersion
+
+// VersionStore keeps a snapshot of the trails for every dataset version, so earlier datasets can be listed,
+// queried and restored
+type VersionStore interface {
+    // CreateVersion snapshots the current trails as a new dataset version, returning it with its ID, trail count
+    // and, unless already set, creation time filled in
+    CreateVersion(ctx context.Context, version models.DatasetVersion) (models.DatasetVersion, error)
+    // GetVersion returns the dataset version with the given ID, or ErrNotFound
+    GetVersion(ctx context.Context, id int) (models.DatasetVersion, error)
+    // ListVersions returns up to limit dataset versions, newest first; 0 means no limit
+    ListVersions(ctx context.Context, limit int) ([]models.DatasetVersion, error)
+    // ListVersionTrails returns the trails of a dataset version matching the options, with the number of trails
+    // matching the filters across every page, or ErrNotFound if the version does not exist
+    ListVersionTrails(ctx context.Context, id int, opts ListOptions) ([]models.Trail, int, error)
+    // StreamVersionTrails calls fn with each trail of a dataset version matching the options, like Stream,
+    // or returns ErrNotFound if the version does not exist
+    StreamVersionTrails(ctx context.Context, id int, opts ListOptions, fn func(models.Trail) error) error
+    // Rollback atomically replaces the current trails with the snapshot of a dataset version, or returns
+    // ErrNotFound. The restored trails are recorded as a new version, which is returned. Versions are only
+    // recorded by loads and rollbacks, so single-trail edits made since the last of them are discarded.
+    Rollback(ctx context.Context, id int) (models.DatasetVersion, error)
+}
+
+// versionColumns lists the dataset_versions columns in the order used by versionScanFields
+var versionColumns = []string{"id", "source", "sha256", "rollback_of", "trail_count", "created_at"}
+
+// versionScanFields returns pointers to the version fields in versionColumns order, for use with rows.Scan
+func versionScanFields(v *models.DatasetVersion) []interface{} {
+    return []interface{}{&v.ID, &v.Source, &v.SHA256, &v.RollbackOf, &v.TrailCount, &v.CreatedAt}
+}
+
+// newVersion stamps a version about to be recorded with the current time, unless it already has one
+func newVersion(version models.DatasetVersion) models.DatasetVersion {
+    if version.CreatedAt.IsZero() {
+        version.CreatedAt = time.Now().UTC()
+    }
+    return version
+}
+
+// rollbackVersion describes the version recorded by restoring the given one
+func rollbackVersion(restored models.DatasetVersion) models.DatasetVersion {
+    return newVersion(models.DatasetVersion{
+        Source:     fmt.Sprintf("rollback to version %d", restored.ID),
+        SHA256:     restored.SHA256,
+        RollbackOf: restored.ID,
+    })
+}
+
+// insertVersionQuery builds the INSERT of a version with no trails yet, returning its ID
+func (d dialect) insertVersionQuery() string {
+    return fmt.Sprintf("INSERT INTO dataset_versions (source, sha256, rollback_of, trail_count, created_at) VALUES (%s, %s, %s, 0, %s) RETURNING id",
+        d.placeholder(1), d.placeholder(2), d.placeholder(3), d.placeholder(4))
+}
+
+// snapshotTrailsQuery copies the live trails into the snapshot of the version identified by the first argument
+func (d dialect) snapshotTrailsQuery() string {
+    columns := strings.Join(models.TrailColumns, ", ")
+    return fmt.Sprintf("INSERT INTO trail_versions (version_id, %s) SELECT %s, %s FROM trails WHERE %s",
+        columns, d.placeholder(1), columns, liveCondition)
+}
+
+// setTrailCountQuery records the number of trails in the snapshot of the version identified by the first argument
+func (d dialect) setTrailCountQuery() string {
+    return fmt.Sprintf("UPDATE dataset_versions SET trail_count = %s WHERE id = %s", d.placeholder(2), d.placeholder(1))
+}
+
+// restoreTrailsQuery inserts the snapshot of the version identified by the first argument into the emptied trails table
+func (d dialect) restoreTrailsQuery() string {
+    columns := strings.Join(models.TrailColumns, ", ")
+    return fmt.Sprintf("INSERT INTO trails (%s) SELECT %s FROM trail_versions WHERE version_id = %s", columns, columns, d.placeholder(1))
+}
+
+// getVersionQuery selects the version identified by the first argument
+func (d dialect) getVersionQuery() string {
+    return fmt.Sprintf("SELECT %s FROM dataset_versions WHERE id = %s", strings.Join(versionColumns, ", "), d.placeholder(1))
+}
+
+// listVersionsQuery selects versions newest first, up to limit when it is positive
+func (d dialect) listVersionsQuery(limit int) (string, []interface{}) {
+    query := fmt.Sprintf("SELECT %s FROM dataset_versions ORDER BY id DESC", strings.Join(versionColumns, ", "))
+    if limit > 0 {
+        return query + " LIMIT " + d.placeholder(1), []interface{}{limit}
+    }
+    return query, nil
+}
+
+// versionTrails is a table expression that reads the snapshot of a version as if it were the trails table,
+// so the List and Count queries run unchanged against it. Snapshots only hold live trails.
+func versionTrails(id int) string {
+    return fmt.Sprintf("(SELECT %s, NULL AS deleted_at FROM trail_versions WHERE version_id = %d) AS trails",
+        strings.Join(models.TrailColumns, ", "), id)
+}
+
+// sortVersions orders versions newest first, like listVersionsQuery
+func sortVersions(versions []models.DatasetVersion) {
+    sort.Slice(versions, func(i, j int) bool { return versions[i].ID > versions[j].ID })
+}
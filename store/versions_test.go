@@ -0,0 +1,115 @@
+package store
+
+import (
+    "context"
+    "testing"
+    "trail-finder/models"
+
+    "github.com/stretchr/testify/assert"
+    "github.com/stretchr/testify/require"
+)
+
+// testVersionStore runs the snapshot and rollback behaviour every TrailStore implementation must share
+func testVersionStore(t *testing.T, s TrailStore) {
+    ctx := context.Background()
+    require.NoError(t, s.ReplaceAll(ctx, testTrails()))
+
+    first, err := s.CreateVersion(ctx, models.DatasetVersion{Source: "first.csv", SHA256: "abc"})
+    require.NoError(t, err)
+    assert.NotZero(t, first.ID)
+    assert.Equal(t, 3, first.TrailCount)
+    assert.False(t, first.CreatedAt.IsZero())
+
+    // Soft-deleted trails are left out of snapshots
+    incoming := testTrails()[:2]
+    incoming[0].Name = "mesa trail"
+    _, second, err := s.Merge(ctx, incoming, true, func() models.DatasetVersion {
+        return models.DatasetVersion{Source: "second.csv", SHA256: "def"}
+    })
+    require.NoError(t, err)
+    assert.Greater(t, second.ID, first.ID)
+    assert.Equal(t, 2, second.TrailCount)
+    assert.Equal(t, "second.csv", second.Source)
+
+    got, err := s.GetVersion(ctx, first.ID)
+    require.NoError(t, err)
+    assert.Equal(t, "first.csv", got.Source)
+    assert.Equal(t, "abc", got.SHA256)
+    _, err = s.GetVersion(ctx, second.ID+100)
+    assert.ErrorIs(t, err, ErrNotFound)
+
+    versions, err := s.ListVersions(ctx, 2)
+    require.NoError(t, err)
+    require.Len(t, versions, 2)
+    assert.Equal(t, []int{second.ID, first.ID}, []int{versions[0].ID, versions[1].ID})
+
+    // The snapshot of a version is queried like the trails table
+    trails, total, err := s.ListVersionTrails(ctx, first.ID, ListOptions{
+        Filters: []Filter{{Field: "restrooms", Value: true}},
+        Sort:    []SortKey{{Field: "name", Desc: true}},
+        Limit:   1,
+    })
+    require.NoError(t, err)
+    assert.Equal(t, 2, total)
+    require.Len(t, trails, 1)
+    assert.Equal(t, "mesa", trails[0].Name)
+    assert.Equal(t, 2, *trails[0].DogTube)
+    _, _, err = s.ListVersionTrails(ctx, second.ID+100, ListOptions{})
+    assert.ErrorIs(t, err, ErrNotFound)
+
+    streamed := []string{}
+    err = s.StreamVersionTrails(ctx, first.ID, ListOptions{Sort: []SortKey{{Field: "name"}}}, func(trail models.Trail) error {
+        streamed = append(streamed, trail.Name)
+        return nil
+    })
+    require.NoError(t, err)
+    assert.Equal(t, []string{"chautauqua", "flagstaff", "mesa"}, streamed)
+    err = s.StreamVersionTrails(ctx, second.ID+100, ListOptions{}, func(models.Trail) error { return nil })
+    assert.ErrorIs(t, err, ErrNotFound)
+
+    // Replacing loads record their version in the same transaction, labelled once the source is read; a
+    // failed source records nothing
+    versions, err = s.ListVersions(ctx, 0)
+    require.NoError(t, err)
+    before := len(versions)
+    _, _, err = s.ReplaceAllFrom(ctx, &failingSource{TrailSource: SliceSource(testTrails()), err: assert.AnError}, func() models.DatasetVersion {
+        return models.DatasetVersion{Source: "failed.csv"}
+    })
+    assert.Equal(t, assert.AnError, err)
+    versions, err = s.ListVersions(ctx, 0)
+    require.NoError(t, err)
+    assert.Len(t, versions, before)
+
+    src := SliceSource(testTrails()[:1])
+    count, loaded, err := s.ReplaceAllFrom(ctx, src, func() models.DatasetVersion {
+        assert.False(t, src.Next(), "the label is only read once the source is exhausted")
+        return models.DatasetVersion{Source: "third.csv", SHA256: "ghi"}
+    })
+    require.NoError(t, err)
+    assert.Equal(t, 1, count)
+    assert.Greater(t, loaded.ID, second.ID)
+    assert.Equal(t, 1, loaded.TrailCount)
+    assert.Equal(t, "ghi", loaded.SHA256)
+    require.NoError(t, s.ReplaceAll(ctx, testTrails()))
+
+    // Rolling back restores the snapshot and records it as a new version
+    rollback, err := s.Rollback(ctx, first.ID)
+    require.NoError(t, err)
+    assert.Greater(t, rollback.ID, second.ID)
+    assert.Equal(t, first.ID, rollback.RollbackOf)
+    assert.Equal(t, "abc", rollback.SHA256)
+    assert.Equal(t, 3, rollback.TrailCount)
+
+    trail, err := s.Get(ctx, 2)
+    require.NoError(t, err)
+    assert.Equal(t, "flagstaff", trail.Name)
+    trail, err = s.Get(ctx, 3)
+    require.NoError(t, err)
+    assert.Equal(t, "mesa", trail.Name)
+
+    _, err = s.Rollback(ctx, rollback.ID+100)
+    assert.ErrorIs(t, err, ErrNotFound)
+    count, err = s.Count(ctx, nil)
+    require.NoError(t, err)
+    assert.Equal(t, 3, count, "a failed rollback must leave the trails unchanged")
+}